@@ -0,0 +1,132 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crazyuploader/rclone_exporter/internal/rclone"
+	"github.com/rs/zerolog/log"
+)
+
+// requireAdminToken wraps a handler so it is only reachable with a valid bearer token
+func requireAdminToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			log.Warn().
+				Str("client", r.RemoteAddr).
+				Str("path", r.URL.Path).
+				Msg("Rejected unauthorized admin request")
+			w.Header().Set("WWW-Authenticate", `Bearer realm="rclone_exporter"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// cacheClearHandler clears the remote type cache, or a single remote's entry when ?remote= is given
+func cacheClearHandler(rcloneClient rclone.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		resp := map[string]interface{}{
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+		}
+
+		if remote := strings.TrimSpace(r.URL.Query().Get("remote")); remote != "" {
+			cleared := 0
+			if rcloneClient.InvalidateCache(remote) {
+				cleared = 1
+			}
+			resp["remote"] = remote
+			resp["cleared"] = cleared
+		} else {
+			resp["cleared"] = rcloneClient.ClearCache()
+		}
+
+		log.Info().
+			Str("client", r.RemoteAddr).
+			Interface("cleared", resp["cleared"]).
+			Msg("Remote type cache cleared via admin endpoint")
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			log.Error().Err(err).Msg("Failed to encode cache clear response")
+		}
+	}
+}
+
+// rcloneConfigHandler serves the remotes from rclone config dump with secrets masked
+func rcloneConfigHandler(rcloneClient rclone.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		configs, err := rcloneClient.GetRedactedConfig()
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to read rclone config")
+			http.Error(w, "Failed to read rclone config", http.StatusInternalServerError)
+			return
+		}
+
+		log.Info().
+			Str("client", r.RemoteAddr).
+			Int("remotes", len(configs)).
+			Msg("Redacted rclone config served via admin endpoint")
+
+		resp := map[string]interface{}{
+			"remotes":   configs,
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			log.Error().Err(err).Msg("Failed to encode rclone config response")
+		}
+	}
+}
+
+// maintenanceHandler reports maintenance mode on GET and sets it on POST with ?enabled=true|false
+func maintenanceHandler(enabled func() bool, setEnabled func(bool)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			value, err := strconv.ParseBool(strings.TrimSpace(r.URL.Query().Get("enabled")))
+			if err != nil {
+				http.Error(w, "Invalid enabled parameter: must be true or false", http.StatusBadRequest)
+				return
+			}
+			setEnabled(value)
+
+			log.Info().
+				Str("client", r.RemoteAddr).
+				Bool("enabled", value).
+				Msg("Maintenance mode set via admin endpoint")
+		default:
+			w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		resp := map[string]interface{}{
+			"maintenance": enabled(),
+			"timestamp":   time.Now().UTC().Format(time.RFC3339),
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			log.Error().Err(err).Msg("Failed to encode maintenance response")
+		}
+	}
+}
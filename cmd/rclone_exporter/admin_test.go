@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crazyuploader/rclone_exporter/internal/rclone"
+)
+
+func TestRcloneConfigHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rclone")
+	script := `#!/bin/sh
+echo '{"s3":{"type":"s3","region":"eu-west-1","secret_access_key":"hunter2"},"box":{"type":"sftp","pass":"hunter3","host":"example.com"}}'
+`
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	handler := requireAdminToken("token", rcloneConfigHandler(rclone.NewRcloneClientWithConfig(path, 5*time.Second)))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/rclone-config", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("unauthenticated status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/rclone-config", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if strings.Contains(rec.Body.String(), "hunter") {
+		t.Fatalf("response leaks a secret: %s", rec.Body)
+	}
+
+	var resp struct {
+		Remotes map[string]map[string]string `json:"remotes"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Remotes["s3"]["region"]; got != "eu-west-1" {
+		t.Errorf("s3 region = %q, want eu-west-1", got)
+	}
+	if got := resp.Remotes["box"]["pass"]; got != "***" {
+		t.Errorf("box pass = %q, want it redacted", got)
+	}
+}
+
+func TestMaintenanceHandler(t *testing.T) {
+	var maintenance bool
+	handler := maintenanceHandler(func() bool { return maintenance }, func(v bool) { maintenance = v })
+
+	request := func(method, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		return rec
+	}
+
+	if rec := request(http.MethodPost, "/admin/maintenance?enabled=true"); rec.Code != http.StatusOK || !maintenance {
+		t.Fatalf("enable: status = %d, maintenance = %v, want 200 and true", rec.Code, maintenance)
+	}
+	if rec := request(http.MethodGet, "/admin/maintenance"); !strings.Contains(rec.Body.String(), `"maintenance":true`) {
+		t.Errorf("GET body = %s, want maintenance true", rec.Body)
+	}
+	if rec := request(http.MethodPost, "/admin/maintenance?enabled=maybe"); rec.Code != http.StatusBadRequest || !maintenance {
+		t.Errorf("invalid value: status = %d, maintenance = %v, want 400 and unchanged", rec.Code, maintenance)
+	}
+	if rec := request(http.MethodDelete, "/admin/maintenance"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+	if request(http.MethodPost, "/admin/maintenance?enabled=false"); maintenance {
+		t.Error("maintenance still enabled after enabled=false")
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+)
+
+// binaryAliasRegex limits the aliases selectable with the binary probe parameter
+var binaryAliasRegex = regexp.MustCompile(`^[a-zA-Z0-9_\-\.]+$`)
+
+// parseBinaries parses "alias=path" pairs from --rclone.binaries
+func parseBinaries(pairs []string) (map[string]string, error) {
+	binaries := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		alias, path, ok := strings.Cut(pair, "=")
+		alias, path = strings.TrimSpace(alias), strings.TrimSpace(path)
+		if !ok || alias == "" || path == "" {
+			return nil, fmt.Errorf("rclone binary %q must be in alias=path form", pair)
+		}
+		if !binaryAliasRegex.MatchString(alias) {
+			return nil, fmt.Errorf("invalid rclone binary alias %q", alias)
+		}
+		if _, dup := binaries[alias]; dup {
+			return nil, fmt.Errorf("duplicate rclone binary alias %q", alias)
+		}
+		binaries[alias] = path
+	}
+	return binaries, nil
+}
+
+// binaryStatCollector exports the modification time and size of the rclone binary.
+// The binary is stat-ed on every collection, so a replaced binary shows up on the
+// next scrape.
+type binaryStatCollector struct {
+	path  string
+	mtime *prometheus.Desc
+	size  *prometheus.Desc
+}
+
+// newBinaryStatCollector creates a collector for the binary at the resolved path
+func newBinaryStatCollector(path string) *binaryStatCollector {
+	labels := []string{"path"}
+	return &binaryStatCollector{
+		path: path,
+		mtime: prometheus.NewDesc(
+			"rclone_exporter_binary_mtime_seconds",
+			"Modification time of the rclone binary since unix epoch in seconds",
+			labels, nil,
+		),
+		size: prometheus.NewDesc(
+			"rclone_exporter_binary_size_bytes",
+			"Size of the rclone binary in bytes",
+			labels, nil,
+		),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *binaryStatCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.mtime
+	ch <- c.size
+}
+
+// Collect implements prometheus.Collector. A binary that cannot be stat-ed
+// reports no series, so its absence can be alerted on.
+func (c *binaryStatCollector) Collect(ch chan<- prometheus.Metric) {
+	info, err := os.Stat(c.path)
+	if err != nil {
+		log.Warn().Err(err).Str("path", c.path).Msg("Failed to stat rclone binary")
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.mtime, prometheus.GaugeValue, float64(info.ModTime().UnixNano())/1e9, c.path)
+	ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(info.Size()), c.path)
+}
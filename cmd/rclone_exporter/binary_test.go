@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestBinaryStatCollector(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rclone")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Unix(1700000000, 0)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(newBinaryStatCollector(path))
+
+	want := fmt.Sprintf(`
+# HELP rclone_exporter_binary_mtime_seconds Modification time of the rclone binary since unix epoch in seconds
+# TYPE rclone_exporter_binary_mtime_seconds gauge
+rclone_exporter_binary_mtime_seconds{path=%q} 1.7e+09
+# HELP rclone_exporter_binary_size_bytes Size of the rclone binary in bytes
+# TYPE rclone_exporter_binary_size_bytes gauge
+rclone_exporter_binary_size_bytes{path=%q} 10
+`, path, path)
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+
+	// A replaced binary is picked up on the next collection
+	if err := os.WriteFile(path, []byte("#!/bin/sh\nexit 0\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() == "rclone_exporter_binary_size_bytes" {
+			if got := family.GetMetric()[0].GetGauge().GetValue(); got != 17 {
+				t.Errorf("size after replace = %v, want 17", got)
+			}
+		}
+	}
+
+	// A missing binary reports no series
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := testutil.CollectAndCount(newBinaryStatCollector(path)); got != 0 {
+		t.Errorf("series for missing binary = %d, want 0", got)
+	}
+}
+
+func TestParseBinaries(t *testing.T) {
+	got, err := parseBinaries([]string{"stable=/usr/bin/rclone", " beta = /opt/rclone-beta/rclone "})
+	if err != nil {
+		t.Fatalf("parseBinaries() error = %v", err)
+	}
+	if got["stable"] != "/usr/bin/rclone" || got["beta"] != "/opt/rclone-beta/rclone" || len(got) != 2 {
+		t.Errorf("parseBinaries() = %v", got)
+	}
+
+	for _, pairs := range [][]string{
+		{"/usr/bin/rclone"},
+		{"=/usr/bin/rclone"},
+		{"beta="},
+		{"be ta=/usr/bin/rclone"},
+		{"beta=/a", "beta=/b"},
+	} {
+		if _, err := parseBinaries(pairs); err == nil {
+			t.Errorf("parseBinaries(%q) error = nil, want error", pairs)
+		}
+	}
+}
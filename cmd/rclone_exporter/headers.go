@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// parseResponseHeaders parses "Name: value" pairs from --web.response-headers
+func parseResponseHeaders(pairs []string) (http.Header, error) {
+	headers := make(http.Header, len(pairs))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, ":")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("response header %q must be in Name: value form", pair)
+		}
+
+		if !httpguts.ValidHeaderFieldName(name) {
+			return nil, fmt.Errorf("invalid response header name %q", name)
+		}
+		if !httpguts.ValidHeaderFieldValue(value) {
+			return nil, fmt.Errorf("invalid value for response header %q", name)
+		}
+
+		headers.Add(name, value)
+	}
+
+	return headers, nil
+}
+
+// withResponseHeaders sets the configured headers on every response before the
+// handler runs, so handlers can still override them
+func withResponseHeaders(headers http.Header, next http.Handler) http.Handler {
+	if len(headers) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, values := range headers {
+			w.Header()[name] = append([]string(nil), values...)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseResponseHeaders(t *testing.T) {
+	headers, err := parseResponseHeaders([]string{"Cache-Control: no-store", "x-content-type-options:nosniff"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := headers.Get("Cache-Control"); got != "no-store" {
+		t.Errorf("Cache-Control = %q, want %q", got, "no-store")
+	}
+	if got := headers.Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Errorf("X-Content-Type-Options = %q, want %q", got, "nosniff")
+	}
+
+	for _, bad := range []string{"no-colon", ": value", "Bad Name: value", "X-Bad: line\nbreak"} {
+		if _, err := parseResponseHeaders([]string{bad}); err == nil {
+			t.Errorf("parseResponseHeaders(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestWithResponseHeaders(t *testing.T) {
+	headers := http.Header{"Cache-Control": {"no-store"}, "X-Frame-Options": {"DENY"}}
+	handler := withResponseHeaders(headers, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Handlers may still override a configured header
+		w.Header().Set("X-Frame-Options", "SAMEORIGIN")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/probe", nil))
+	if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("Cache-Control = %q, want %q", got, "no-store")
+	}
+	if got := rec.Header().Get("X-Frame-Options"); got != "SAMEORIGIN" {
+		t.Errorf("X-Frame-Options = %q, want the handler's value", got)
+	}
+	if headers.Get("X-Frame-Options") != "DENY" {
+		t.Error("handler modified the configured headers")
+	}
+}
@@ -3,20 +3,25 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"net/http"
 	"os"
+	"os/exec"
 	"os/signal"
 	"runtime"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/crazyuploader/rclone_exporter/internal/config"
 	"github.com/crazyuploader/rclone_exporter/internal/exporter"
 	"github.com/crazyuploader/rclone_exporter/internal/logging"
 	"github.com/crazyuploader/rclone_exporter/internal/rclone"
+	"github.com/crazyuploader/rclone_exporter/internal/syncstats"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog/log"
 	cli "github.com/urfave/cli/v3"
 )
@@ -37,8 +42,25 @@ const (
 	DefaultProbePath       = "/probe"
 	DefaultRclonePath      = "rclone"
 	DefaultHealthPath      = "/health"
+	DefaultReadyPath       = "/ready"
 	DefaultRemotesPath     = "/remotes"
 	DefaultConfigPath      = "/config"
+	DefaultReachablePath   = "/reachable"
+	DefaultCacheClearPath  = "/admin/cache/clear"
+	DefaultVersionPath     = "/version"
+	DefaultSyncPath        = "/sync"
+
+	DefaultRcloneConfigPath = "/rclone-config"
+	DefaultMaintenancePath  = "/admin/maintenance"
+	DefaultSnapshotPath     = "/admin/snapshot"
+	DefaultSnapshotDiffPath = "/admin/snapshot/diff"
+
+	DefaultAlertFailureThreshold = 3
+
+	DefaultSelfTestTimeout = 15 * time.Second
+
+	DefaultScrapeInterval = 5 * time.Minute
+	DefaultOutputInterval = time.Minute
 )
 
 // ConfigResponse represents the runtime configuration exposed via /config endpoint
@@ -46,8 +68,11 @@ type ConfigResponse struct {
 	BuildInfo    BuildInfo       `json:"build_info"`
 	ServerConfig ServerConfig    `json:"server_config"`
 	RcloneConfig RcloneConfig    `json:"rclone_config"`
-	RuntimeInfo  RuntimeInfo     `json:"runtime_info"`
+	RuntimeInfo  *RuntimeInfo    `json:"runtime_info,omitempty"`
 	Endpoints    EndpointsConfig `json:"endpoints"`
+
+	// Flags holds the effective value of every flag, only filled in by --print-config
+	Flags map[string]interface{} `json:"flags,omitempty"`
 }
 
 type BuildInfo struct {
@@ -58,17 +83,27 @@ type BuildInfo struct {
 }
 
 type ServerConfig struct {
-	ListenAddress   string `json:"listen_address"`
-	ShutdownTimeout string `json:"shutdown_timeout"`
-	ReadTimeout     string `json:"read_timeout"`
-	WriteTimeout    string `json:"write_timeout"`
-	IdleTimeout     string `json:"idle_timeout"`
+	ListenAddress   string   `json:"listen_address"` // First listen address, kept for backwards compatibility
+	ListenAddresses []string `json:"listen_addresses"`
+	ShutdownTimeout string   `json:"shutdown_timeout"`
+	DrainPeriod     string   `json:"drain_period"`
+	ReadTimeout     string   `json:"read_timeout"`
+	WriteTimeout    string   `json:"write_timeout"`
+	IdleTimeout     string   `json:"idle_timeout"`
 }
 
 type RcloneConfig struct {
-	BinaryPath string `json:"binary_path"`
-	Timeout    string `json:"timeout"`
-	Version    string `json:"version,omitempty"`
+	BinaryPath         string `json:"binary_path"`
+	Timeout            string `json:"timeout"`
+	SlowProbeThreshold string `json:"slow_probe_threshold"`
+	CacheTTL           string `json:"cache_ttl"`
+	MaxResultAge       string `json:"max_result_age"`
+	ConfigPassCommand  string `json:"config_pass_command,omitempty"`
+	Version            string `json:"version,omitempty"`
+
+	ScrapeMode     string `json:"scrape_mode"`
+	ScrapeInterval string `json:"scrape_interval,omitempty"`
+	ScrapeJitter   string `json:"scrape_jitter,omitempty"`
 }
 
 type RuntimeInfo struct {
@@ -79,24 +114,28 @@ type RuntimeInfo struct {
 }
 
 type EndpointsConfig struct {
-	MetricsPath string `json:"metrics_path"`
-	ProbePath   string `json:"probe_path"`
-	HealthPath  string `json:"health_path"`
-	RemotesPath string `json:"remotes_path"`
-	ConfigPath  string `json:"config_path"`
+	MetricsPath   string `json:"metrics_path"`
+	ProbePath     string `json:"probe_path"`
+	HealthPath    string `json:"health_path"`
+	RemotesPath   string `json:"remotes_path"`
+	ConfigPath    string `json:"config_path"`
+	ReachablePath string `json:"reachable_path"`
+	VersionPath   string `json:"version_path"`
 }
 
 type LandingPageData struct {
-	Version     string
-	Commit      string
-	BuildDate   string
-	GoVersion   string
-	Uptime      string
-	MetricsPath string
-	ProbePath   string
-	HealthPath  string
-	RemotesPath string
-	ConfigPath  string
+	Version       string
+	Commit        string
+	BuildDate     string
+	GoVersion     string
+	Uptime        string
+	MetricsPath   string
+	ProbePath     string
+	HealthPath    string
+	RemotesPath   string
+	ConfigPath    string
+	ReachablePath string
+	VersionPath   string
 }
 
 var startTime = time.Now()
@@ -183,7 +222,9 @@ const landingPageTemplate = `<!DOCTYPE html>
         <ul>
             <li><a href="{{.MetricsPath}}">{{.MetricsPath}}</a> — metrics</li>
             <li><a href="{{.ProbePath}}">{{.ProbePath}}</a> — probe remote</li>
+            <li><a href="{{.ReachablePath}}">{{.ReachablePath}}</a> — check remote reachability</li>
             <li><a href="{{.HealthPath}}">{{.HealthPath}}</a> — health check</li>
+            <li><a href="{{.VersionPath}}">{{.VersionPath}}</a> — exporter and rclone versions</li>
             <li><a href="{{.RemotesPath}}">{{.RemotesPath}}</a> — list remotes</li>
             <li><a href="{{.ConfigPath}}">{{.ConfigPath}}</a> — exporter config</li>
         </ul>
@@ -191,6 +232,7 @@ const landingPageTemplate = `<!DOCTYPE html>
         <p>Probe a specific remote:</p>
         <p><code>{{.ProbePath}}?remote=&lt;remote_name&gt;</code></p>
         <p>Example: <code>{{.ProbePath}}?remote=myremote:</code></p>
+        <p>Optional parameters: <code>depth=&lt;1-20&gt;</code> limits traversal depth, <code>command=dirs</code> counts directories instead of computing size, <code>command=about</code> reports quota and free space.</p>
         <footer>
             Built with Go • Build Date: {{.BuildDate}}
         </footer>
@@ -199,7 +241,7 @@ const landingPageTemplate = `<!DOCTYPE html>
 `
 
 // createBuildInfoMetric creates and registers the build info metric
-func createBuildInfoMetric(registry *prometheus.Registry) {
+func createBuildInfoMetric(registry prometheus.Registerer) {
 	buildInfo := prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: "rclone_exporter",
@@ -214,6 +256,49 @@ func createBuildInfoMetric(registry *prometheus.Registry) {
 	registry.MustRegister(buildInfo)
 }
 
+// newTypeDetectionHistogram creates the histogram of remote type detection durations,
+// observed by the rclone client and registered once the exporter exists
+func newTypeDetectionHistogram() prometheus.Histogram {
+	return prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: "rclone_exporter",
+			Name:      "type_detection_duration_seconds",
+			Help:      "Duration of remote type detection in seconds, near zero for cache hits",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 8),
+		},
+	)
+}
+
+// createStartTimeMetric creates and registers the exporter start time metric
+func createStartTimeMetric(registry prometheus.Registerer) {
+	startTimeSeconds := prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "rclone_exporter",
+			Name:      "start_time_seconds",
+			Help:      "Start time of the rclone exporter since unix epoch in seconds",
+		},
+	)
+
+	startTimeSeconds.Set(float64(startTime.UnixNano()) / 1e9)
+
+	registry.MustRegister(startTimeSeconds)
+}
+
+// createUptimeMetric creates and registers the exporter uptime metric, computed on
+// every scrape so dashboards need no time() arithmetic
+func createUptimeMetric(registry prometheus.Registerer) {
+	uptimeSeconds := prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace: "rclone_exporter",
+			Name:      "uptime_seconds",
+			Help:      "Time since the rclone exporter started in seconds",
+		},
+		func() float64 { return time.Since(startTime).Seconds() },
+	)
+
+	registry.MustRegister(uptimeSeconds)
+}
+
 // landingPageHandler serves an HTML landing page
 func landingPageHandler(cmd *cli.Command) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -231,16 +316,18 @@ func landingPageHandler(cmd *cli.Command) http.HandlerFunc {
 		}
 
 		data := LandingPageData{
-			Version:     version,
-			Commit:      commit,
-			BuildDate:   buildDate,
-			GoVersion:   goVersion,
-			Uptime:      time.Since(startTime).Round(time.Second).String(),
-			MetricsPath: cmd.String("web.telemetry-path"),
-			ProbePath:   cmd.String("web.probe-path"),
-			HealthPath:  cmd.String("web.health-path"),
-			RemotesPath: cmd.String("web.remotes-path"),
-			ConfigPath:  cmd.String("web.config-path"),
+			Version:       version,
+			Commit:        commit,
+			BuildDate:     buildDate,
+			GoVersion:     goVersion,
+			Uptime:        time.Since(startTime).Round(time.Second).String(),
+			MetricsPath:   cmd.String("web.telemetry-path"),
+			ProbePath:     cmd.String("web.probe-path"),
+			HealthPath:    cmd.String("web.health-path"),
+			RemotesPath:   cmd.String("web.remotes-path"),
+			ConfigPath:    cmd.String("web.config-path"),
+			ReachablePath: cmd.String("web.reachable-path"),
+			VersionPath:   cmd.String("web.version-path"),
 		}
 
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -251,19 +338,186 @@ func landingPageHandler(cmd *cli.Command) http.HandlerFunc {
 	}
 }
 
-// healthHandler provides a simple health check endpoint with build info
-func healthHandler(w http.ResponseWriter, r *http.Request) {
-	resp := map[string]string{
-		"status":     "OK",
-		"version":    version,
-		"commit":     commit,
-		"build_date": buildDate,
-		"go_version": goVersion,
-		"uptime":     time.Since(startTime).Round(time.Second).String(),
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(resp)
+// healthHandler provides a simple health check endpoint with build info.
+// It answers 503 once draining reports that the exporter is shutting down.
+func healthHandler(draining func() bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, code := "OK", http.StatusOK
+		if draining() {
+			status, code = "DRAINING", http.StatusServiceUnavailable
+		}
+
+		resp := map[string]string{
+			"status":     status,
+			"version":    version,
+			"commit":     commit,
+			"build_date": buildDate,
+			"go_version": goVersion,
+			"uptime":     time.Since(startTime).Round(time.Second).String(),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// readyHandler answers 200 while the exporter can run live probes, and 503 while it
+// shuts down or is in maintenance mode
+func readyHandler(draining, maintenance func() bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, code := "READY", http.StatusOK
+		switch {
+		case draining():
+			status, code = "DRAINING", http.StatusServiceUnavailable
+		case maintenance():
+			status, code = "MAINTENANCE", http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(map[string]string{"status": status})
+	}
+}
+
+// versionHandler reports the exporter build and the rclone version as JSON
+func versionHandler(rcloneClient rclone.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := map[string]string{
+			"version":    version,
+			"commit":     commit,
+			"build_date": buildDate,
+			"go_version": goVersion,
+		}
+
+		// Best effort, the exporter version is still useful without it
+		if rcloneVersion, err := rcloneClient.GetVersion(); err == nil {
+			resp["rclone_version"] = rcloneVersion
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// redactedConfigValue replaces sensitive values in a redacted /config response
+const redactedConfigValue = "***"
+
+// redactConfigResponse masks filesystem paths and listen addresses and drops memory stats,
+// leaving only build and runtime information that is safe to share
+func redactConfigResponse(config ConfigResponse) ConfigResponse {
+	config.ServerConfig.ListenAddress = redactedConfigValue
+	config.ServerConfig.ListenAddresses = []string{redactedConfigValue}
+	config.RcloneConfig.BinaryPath = redactedConfigValue
+	if config.RuntimeInfo != nil {
+		runtimeInfo := *config.RuntimeInfo
+		runtimeInfo.GoMemStats = ""
+		config.RuntimeInfo = &runtimeInfo
+	}
+	return config
+}
+
+// redactedIfSet masks a secret value, keeping only whether it is configured
+func redactedIfSet(value string) string {
+	if value == "" {
+		return ""
+	}
+	return redactedConfigValue
+}
+
+// secretFlags are masked by effectiveFlags, keeping only whether they are set
+var secretFlags = map[string]bool{
+	"web.admin-token":            true,
+	"rclone.config-pass-command": true,
+	"alert.webhook-url":          true,
+	"sync.rc-pass":               true,
+}
+
+// effectiveFlags returns the resolved value of every flag, after defaults and
+// environment overrides, with secrets masked
+func effectiveFlags(cmd *cli.Command) map[string]interface{} {
+	flags := make(map[string]interface{})
+	for _, flag := range cmd.Flags {
+		name := flag.Names()[0]
+		switch name {
+		case "help", "version", "print-config":
+			continue
+		}
+
+		value := cmd.Value(name)
+		switch v := value.(type) {
+		case time.Duration:
+			value = v.String()
+		case string:
+			if secretFlags[name] {
+				value = redactedIfSet(v)
+			}
+		}
+		flags[name] = value
+	}
+	return flags
+}
+
+// printConfig writes the effective configuration as indented JSON, in the shape of the
+// config endpoint plus the value of every flag
+func printConfig(w io.Writer, cmd *cli.Command) error {
+	config := newConfigResponse(cmd, "")
+	config.Flags = effectiveFlags(cmd)
+	if cmd.Bool("web.config-redact") {
+		config = redactConfigResponse(config)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(config)
+}
+
+// newConfigResponse builds the configuration exposed via /config from the flags,
+// without the runtime information of a running server
+func newConfigResponse(cmd *cli.Command, rcloneVersion string) ConfigResponse {
+	config := ConfigResponse{
+		BuildInfo: BuildInfo{
+			Version:   version,
+			Commit:    commit,
+			BuildDate: buildDate,
+			GoVersion: goVersion,
+		},
+		ServerConfig: ServerConfig{
+			ListenAddress:   firstOrEmpty(listenAddresses(cmd)),
+			ListenAddresses: listenAddresses(cmd),
+			ShutdownTimeout: cmd.Duration("server.shutdown-timeout").String(),
+			DrainPeriod:     cmd.Duration("server.drain-period").String(),
+			ReadTimeout:     "15s",
+			WriteTimeout:    "15s",
+			IdleTimeout:     "60s",
+		},
+		RcloneConfig: RcloneConfig{
+			BinaryPath:         cmd.String("rclone.path"),
+			Timeout:            cmd.Duration("rclone.timeout").String(),
+			SlowProbeThreshold: slowProbeThreshold(cmd).String(),
+			CacheTTL:           cmd.Duration("rclone.cache-ttl").String(),
+			MaxResultAge:       cmd.Duration("rclone.max-result-age").String(),
+			ConfigPassCommand:  redactedIfSet(cmd.String("rclone.config-pass-command")),
+			Version:            rcloneVersion,
+
+			ScrapeMode: cmd.String("scrape.mode"),
+		},
+		Endpoints: EndpointsConfig{
+			MetricsPath:   cmd.String("web.telemetry-path"),
+			ProbePath:     cmd.String("web.probe-path"),
+			HealthPath:    cmd.String("web.health-path"),
+			RemotesPath:   cmd.String("web.remotes-path"),
+			ConfigPath:    cmd.String("web.config-path"),
+			ReachablePath: cmd.String("web.reachable-path"),
+			VersionPath:   cmd.String("web.version-path"),
+		},
+	}
+
+	if config.RcloneConfig.ScrapeMode == exporter.ScrapeModePush {
+		config.RcloneConfig.ScrapeInterval = cmd.Duration("scrape.interval").String()
+		config.RcloneConfig.ScrapeJitter = cmd.Duration("scrape.jitter").String()
+	}
+
+	return config
 }
 
 // configHandler exposes the runtime configuration of the exporter
@@ -275,38 +529,16 @@ func configHandler(cmd *cli.Command, rcloneClient rclone.Client) http.HandlerFun
 		var m runtime.MemStats
 		runtime.ReadMemStats(&m)
 
-		config := ConfigResponse{
-			BuildInfo: BuildInfo{
-				Version:   version,
-				Commit:    commit,
-				BuildDate: buildDate,
-				GoVersion: goVersion,
-			},
-			ServerConfig: ServerConfig{
-				ListenAddress:   cmd.String("web.listen-address"),
-				ShutdownTimeout: cmd.Duration("server.shutdown-timeout").String(),
-				ReadTimeout:     "15s",
-				WriteTimeout:    "15s",
-				IdleTimeout:     "60s",
-			},
-			RcloneConfig: RcloneConfig{
-				BinaryPath: cmd.String("rclone.path"),
-				Timeout:    cmd.Duration("rclone.timeout").String(),
-				Version:    rcloneVersion,
-			},
-			RuntimeInfo: RuntimeInfo{
-				Uptime:        time.Since(startTime).Round(time.Second).String(),
-				NumGoroutines: runtime.NumGoroutine(),
-				NumCPU:        runtime.NumCPU(),
-				GoMemStats:    fmt.Sprintf("Alloc=%dMB TotalAlloc=%dMB Sys=%dMB", m.Alloc/1024/1024, m.TotalAlloc/1024/1024, m.Sys/1024/1024),
-			},
-			Endpoints: EndpointsConfig{
-				MetricsPath: cmd.String("web.telemetry-path"),
-				ProbePath:   cmd.String("web.probe-path"),
-				HealthPath:  cmd.String("web.health-path"),
-				RemotesPath: cmd.String("web.remotes-path"),
-				ConfigPath:  cmd.String("web.config-path"),
-			},
+		config := newConfigResponse(cmd, rcloneVersion)
+		config.RuntimeInfo = &RuntimeInfo{
+			Uptime:        time.Since(startTime).Round(time.Second).String(),
+			NumGoroutines: runtime.NumGoroutine(),
+			NumCPU:        runtime.NumCPU(),
+			GoMemStats:    fmt.Sprintf("Alloc=%dMB TotalAlloc=%dMB Sys=%dMB", m.Alloc/1024/1024, m.TotalAlloc/1024/1024, m.Sys/1024/1024),
+		}
+
+		if cmd.Bool("web.config-redact") {
+			config = redactConfigResponse(config)
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -318,82 +550,225 @@ func configHandler(cmd *cli.Command, rcloneClient rclone.Client) http.HandlerFun
 	}
 }
 
+// listenAddresses returns the non-empty addresses configured via --web.listen-address
+func listenAddresses(cmd *cli.Command) []string {
+	var addresses []string
+	for _, addr := range cmd.StringSlice("web.listen-address") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addresses = append(addresses, addr)
+		}
+	}
+	return addresses
+}
+
+// firstOrEmpty returns the first element of values, or an empty string if there is none
+func firstOrEmpty(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// slowProbeThreshold returns the configured slow-probe threshold, defaulting to half of the rclone timeout
+func slowProbeThreshold(cmd *cli.Command) time.Duration {
+	if threshold := cmd.Duration("rclone.slow-probe-threshold"); threshold != 0 {
+		return threshold
+	}
+	return cmd.Duration("rclone.timeout") / 2
+}
+
+// syncStatsSource returns the configured rclone sync stats source, or nil if none is set
+func syncStatsSource(cmd *cli.Command) (syncstats.Source, error) {
+	rcURL, logFile := cmd.String("sync.rc-url"), cmd.String("sync.stats-log-file")
+	switch {
+	case rcURL != "" && logFile != "":
+		return nil, fmt.Errorf("--sync.rc-url and --sync.stats-log-file are mutually exclusive")
+	case rcURL != "":
+		return &syncstats.RCSource{
+			URL:      rcURL,
+			User:     cmd.String("sync.rc-user"),
+			Password: cmd.String("sync.rc-pass"),
+			Client:   &http.Client{Timeout: 10 * time.Second},
+		}, nil
+	case logFile != "":
+		return &syncstats.LogFileSource{Path: logFile}, nil
+	default:
+		return nil, nil
+	}
+}
+
 // runServer initializes the rclone client, sets up HTTP handlers, and starts the server
-func runServer(_ context.Context, cmd *cli.Command) error {
+func runServer(ctx context.Context, cmd *cli.Command) error {
+	// Load optional configuration file
+	fileConfig, err := config.Load(cmd.String("config.file"))
+	if err != nil {
+		return err
+	}
+
+	ioPriority, err := rclone.ParseIOPriority(cmd.String("rclone.ionice"))
+	if err != nil {
+		return fmt.Errorf("invalid --rclone.ionice: %w", err)
+	}
+	if err := rclone.ValidatePriority(cmd.Int("rclone.nice"), ioPriority); err != nil {
+		return fmt.Errorf("invalid rclone priority: %w", err)
+	}
+
 	// Setup rclone client
+	typeDetectionDuration := newTypeDetectionHistogram()
 	rclonePath := cmd.String("rclone.path")
 	rcloneTimeout := cmd.Duration("rclone.timeout")
-	client := rclone.NewRcloneClientWithConfig(rclonePath, rcloneTimeout)
+	rcloneOptions := rclone.Options{
+		DisableHTTP2:    cmd.Bool("rclone.disable-http2"),
+		ConnectTimeout:  cmd.Duration("rclone.contimeout"),
+		LowLevelRetries: cmd.Int("rclone.low-level-retries"),
+		UserAgent:       cmd.String("rclone.user-agent"),
+		Checkers:        cmd.Int("rclone.checkers"),
+		Transfers:       cmd.Int("rclone.transfers"),
+		Traversal:       cmd.String("rclone.traversal"),
+		SizeExtraArgs:   cmd.StringSlice("rclone.size-extra"),
+		PasswordCommand: cmd.String("rclone.config-pass-command"),
+		RemoteEnv:       fileConfig.RemoteEnv(),
+		Nice:            cmd.Int("rclone.nice"),
+		IOPriority:      ioPriority,
+
+		SuccessExitCodes:     cmd.IntSlice("rclone.success-exit-codes"),
+		ObserveTypeDetection: func(d time.Duration) { typeDetectionDuration.Observe(d.Seconds()) },
+	}
+	client := rclone.NewRcloneClientWithOptions(rclonePath, rcloneTimeout, rcloneOptions)
 
-	if err := client.CheckBinaryAvailable(); err != nil {
+	if err := client.CheckBinaryAvailable(ctx); err != nil {
 		return fmt.Errorf("rclone binary is not accessible or not functioning: %w", err)
 	}
 
+	// Alternative binaries share the options of the default one
+	binaryPaths, err := parseBinaries(cmd.StringSlice("rclone.binaries"))
+	if err != nil {
+		return fmt.Errorf("invalid --rclone.binaries: %w", err)
+	}
+	binaries := make(map[string]rclone.Client, len(binaryPaths))
+	for alias, path := range binaryPaths {
+		binary := rclone.NewRcloneClientWithOptions(path, rcloneTimeout, rcloneOptions)
+		if err := binary.CheckBinaryAvailable(ctx); err != nil {
+			return fmt.Errorf("rclone binary %q is not accessible or not functioning: %w", alias, err)
+		}
+		binaries[alias] = binary
+	}
+
+	if cmd.String("alert.webhook-url") != "" && cmd.Int("alert.failure-threshold") < 1 {
+		return fmt.Errorf("--alert.failure-threshold must be at least 1")
+	}
+
+	if threshold := cmd.Float("alert.free-percent-threshold"); threshold < 0 || threshold > 100 {
+		return fmt.Errorf("--alert.free-percent-threshold must be between 0 and 100")
+	}
+
+	if err := exporter.ValidateHelpOverrides(fileConfig.Metrics.Help); err != nil {
+		return fmt.Errorf("invalid config file: %w", err)
+	}
+
+	constLabels, err := exporter.ParseConstLabels(cmd.StringSlice("metrics.const-labels"))
+	if err != nil {
+		return fmt.Errorf("invalid --metrics.const-labels: %w", err)
+	}
+
+	if err := exporter.ValidateRemoteLabels(fileConfig.RemoteLabels(), constLabels); err != nil {
+		return fmt.Errorf("invalid config file: %w", err)
+	}
+
 	// Create Prometheus exporter
-	exp := exporter.NewExporter(client)
-	defer exp.Close() // Ensure cleanup
+	exp := exporter.NewExporterWithConfig(client, exporter.Config{
+		ProbeTimeout:       rcloneTimeout,
+		SlowProbeThreshold: slowProbeThreshold(cmd),
+		ScrapeTimeout:      cmd.Duration("scrape.timeout"),
+		HelpOverrides:      fileConfig.Metrics.Help,
+		ConstLabels:        constLabels,
+		SizeCacheTTL:       cmd.Duration("rclone.cache-ttl"),
+		MaxResultAge:       cmd.Duration("rclone.max-result-age"),
 
-	// Add build info metric to the exporter's registry
-	createBuildInfoMetric(exp.Registry())
+		ProbeFailureStatusOK: !cmd.Bool("probe.fail-status"),
+		EnrichRemoteTypes:    cmd.Bool("probe.enrich"),
+		CountOnly:            cmd.Bool("probe.count-only"),
+		DefaultRemote:        strings.TrimSpace(cmd.String("probe.default-remote")),
+		NativeHistograms:     cmd.Bool("metrics.native-histograms"),
+		ObjectCountHistogram: cmd.Bool("metrics.objects-histogram"),
+		PrerenderMetrics:     cmd.Bool("metrics.prerender"),
+		MetricsErrorHandling: cmd.String("metrics.error-handling"),
 
-	// Handler for /remotes endpoint
-	remotesHandler := func(w http.ResponseWriter, r *http.Request) {
-		remotes, err := client.ListRemotes()
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to list remotes: %v", err), http.StatusInternalServerError)
-			return
-		}
+		AlertWebhookURL:       cmd.String("alert.webhook-url"),
+		AlertFailureThreshold: cmd.Int("alert.failure-threshold"),
+		AlertRemotes:          cmd.StringSlice("alert.remotes"),
+		FreePercentThreshold:  cmd.Float("alert.free-percent-threshold"),
 
-		w.Header().Set("Content-Type", "application/json")
-		// Gather metadata
-		remoteCount := len(remotes)
-		timestamp := time.Now().UTC().Format(time.RFC3339)
-		resp := map[string]interface{}{
-			"remotes":      remotes,
-			"remote_count": remoteCount,
-			"timestamp":    timestamp,
-		}
+		RemoteLabels: fileConfig.RemoteLabels(),
+		RemotesFile:  cmd.String("rclone.remotes-file"),
+		Binaries:     binaries,
+	})
+	defer exp.Close() // Ensure cleanup
 
-		if err := json.NewEncoder(w).Encode(resp); err != nil {
-			http.Error(w, "Failed to encode remotes as JSON", http.StatusInternalServerError)
-		}
+	if err := exp.SetCacheBackend(cmd.String("cache.backend"), cmd.String("cache.path")); err != nil {
+		return fmt.Errorf("invalid --cache.backend: %w", err)
+	}
+
+	// Add build info, start time and uptime metrics to the exporter's registry
+	createBuildInfoMetric(exp.Registerer())
+	createStartTimeMetric(exp.Registerer())
+	createUptimeMetric(exp.Registerer())
+	exp.Registerer().MustRegister(typeDetectionDuration)
+	exp.SetMaintenance(cmd.Bool("maintenance"))
+
+	// CheckBinaryAvailable succeeded, so the binary resolves
+	if resolvedPath, err := exec.LookPath(rclonePath); err == nil {
+		exp.Registerer().MustRegister(newBinaryStatCollector(resolvedPath))
 	}
 
 	// Setup HTTP handlers
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", landingPageHandler(cmd))
-	mux.Handle(cmd.String("web.telemetry-path"), promhttp.HandlerFor(exp.Registry(), promhttp.HandlerOpts{}))
+	mux.Handle(cmd.String("web.telemetry-path"), exp.MetricsHandler(exp.HandlerOpts()))
 	mux.HandleFunc(cmd.String("web.probe-path"), exp.ProbeHandler)
-	mux.HandleFunc(cmd.String("web.health-path"), healthHandler)
-	mux.HandleFunc(cmd.String("web.remotes-path"), remotesHandler)
+	mux.HandleFunc(cmd.String("web.reachable-path"), exp.ReachableHandler)
+	mux.HandleFunc(cmd.String("web.health-path"), healthHandler(exp.Draining))
+	mux.HandleFunc(cmd.String("web.ready-path"), readyHandler(exp.Draining, exp.Maintenance))
+	mux.HandleFunc(cmd.String("web.version-path"), versionHandler(client))
+	mux.HandleFunc(cmd.String("web.remotes-path"), remotesHandler(client))
 	mux.HandleFunc(cmd.String("web.config-path"), configHandler(cmd, client))
 
-	// HTTP server configuration
-	server := &http.Server{
-		Addr:         cmd.String("web.listen-address"),
-		Handler:      mux,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
-	}
-
-	// Graceful shutdown routine
-	idleConnsClosed := make(chan struct{})
-	go func() {
-		defer close(idleConnsClosed)
-		sigCh := make(chan os.Signal, 1)
-		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
-		<-sigCh
-
-		log.Warn().Msg("Shutdown signal received")
-		shutdownTimeout := cmd.Duration("server.shutdown-timeout")
-		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
-		defer cancel()
-
-		if err := server.Shutdown(ctx); err != nil {
-			log.Error().Err(err).Msg("HTTP server shutdown failed")
+	// Sync stats are only exposed when a stats source is configured
+	syncSource, err := syncStatsSource(cmd)
+	if err != nil {
+		return err
+	}
+	if syncSource != nil {
+		mux.HandleFunc(cmd.String("web.sync-path"), exp.SyncStatsHandler(syncSource))
+	}
+
+	// Admin endpoints are only exposed when an admin token is configured
+	if adminToken := cmd.String("web.admin-token"); adminToken != "" {
+		mux.Handle(cmd.String("web.cache-clear-path"), requireAdminToken(adminToken, cacheClearHandler(client)))
+		mux.Handle(cmd.String("web.maintenance-path"), requireAdminToken(adminToken, maintenanceHandler(exp.Maintenance, exp.SetMaintenance)))
+		mux.Handle(cmd.String("web.snapshot-path"), requireAdminToken(adminToken, http.HandlerFunc(exp.SnapshotHandler)))
+		mux.Handle(cmd.String("web.snapshot-diff-path"), requireAdminToken(adminToken, http.HandlerFunc(exp.SnapshotDiffHandler)))
+		if cmd.Bool("web.enable-rclone-config") {
+			mux.Handle(cmd.String("web.rclone-config-path"), requireAdminToken(adminToken, rcloneConfigHandler(client)))
 		}
-	}()
+	} else {
+		log.Debug().Msg("No admin token configured, admin endpoints are disabled")
+	}
+
+	// Server configuration, one server per listen address sharing the same mux
+	pathLevels, err := logging.ParsePathLevels(cmd.StringSlice("log.path-levels"))
+	if err != nil {
+		return fmt.Errorf("invalid --log.path-levels: %w", err)
+	}
+	responseHeaders, err := parseResponseHeaders(cmd.StringSlice("web.response-headers"))
+	if err != nil {
+		return fmt.Errorf("invalid --web.response-headers: %w", err)
+	}
+	handler := withResponseCounter(exp.Registerer(), mux, withResponseHeaders(responseHeaders, mux))
+	listeners, err := buildListeners(cmd, logging.PathLevelHandler(pathLevels, handler))
+	if err != nil {
+		return err
+	}
 
 	log.Info().
 		Str("version", version).
@@ -403,39 +778,108 @@ func runServer(_ context.Context, cmd *cli.Command) error {
 		Msg("Starting rclone_exporter")
 
 	log.Info().
-		Str("listen", server.Addr).
+		Strs("listen", listenAddresses(cmd)).
+		Bool("tls", cmd.String("web.tls-cert-file") != "").
+		Bool("http3", cmd.Bool("web.enable-http3")).
 		Str("metrics_path", cmd.String("web.telemetry-path")).
 		Str("probe_path", cmd.String("web.probe-path")).
+		Str("reachable_path", cmd.String("web.reachable-path")).
 		Str("health_path", cmd.String("web.health-path")).
 		Str("remotes_path", cmd.String("web.remotes-path")).
 		Str("config_path", cmd.String("web.config-path")).
 		Str("rclone_bin", rclonePath).
 		Dur("timeout", rcloneTimeout).
-		Msg("rclone_exporter is up and listening")
+		Msg("rclone_exporter configured")
+
+	// ctx ends with the first shutdown signal, which only starts the drain. Watchers and
+	// background probes keep running on their own context until the drain is over.
+	background, stopBackground := context.WithCancel(context.WithoutCancel(ctx))
+	defer stopBackground()
+
+	if cmd.Bool("rclone.watch-config") {
+		watchCtx, stopWatching := context.WithCancel(background)
+		defer stopWatching()
+		if err := watchRcloneConfig(watchCtx, client, exp.Registerer()); err != nil {
+			return fmt.Errorf("failed to watch rclone config: %w", err)
+		}
+	}
+
+	switch mode := cmd.String("scrape.mode"); mode {
+	case exporter.ScrapeModePull:
+	case exporter.ScrapeModePush:
+		if err := exp.StartBackgroundProbes(background, cmd.StringSlice("scrape.remotes"), cmd.Duration("scrape.interval"), cmd.Duration("scrape.jitter")); err != nil {
+			return fmt.Errorf("invalid background scrape settings: %w", err)
+		}
+	default:
+		return fmt.Errorf("invalid --scrape.mode %q: must be %q or %q", mode, exporter.ScrapeModePull, exporter.ScrapeModePush)
+	}
+
+	// The file is removed again on shutdown so the textfile collector drops the metrics
+	if path := cmd.String("output.file"); path != "" {
+		stopOutput, err := exp.StartTextfileOutput(background, path, cmd.Duration("output.interval"))
+		if err != nil {
+			return fmt.Errorf("invalid --output.file settings: %w", err)
+		}
+		defer stopOutput()
+	}
+
+	// The self-test runs with its own short timeout while the servers start
+	if cmd.Bool("startup-selftest") {
+		selfTestClient := rclone.NewRcloneClientWithOptions(rclonePath, cmd.Duration("startup-selftest.timeout"), rcloneOptions)
+		go runSelfTest(selfTestClient, exp.Registerer())
+	}
 
-	// Start server
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		return fmt.Errorf("HTTP server crashed: %w", err)
+	// Start servers and block until shutdown
+	drain := drainConfig{
+		start:  exp.StartDraining,
+		period: cmd.Duration("server.drain-period"),
+		stop:   stopBackground,
+	}
+	if err := runListeners(ctx, listeners, drain, cmd.Duration("server.shutdown-timeout")); err != nil {
+		return err
 	}
 
-	<-idleConnsClosed
 	log.Info().Msg("Exporter shutdown completed")
 	return nil
 }
 
-// main function initializes the CLI application and starts the server
-func main() {
-	app := &cli.Command{
+// newApp defines the command line interface with every flag
+func newApp() *cli.Command {
+	return &cli.Command{
 		Name:    "rclone_exporter",
 		Usage:   "Prometheus exporter for rclone",
 		Version: version,
 		Flags: []cli.Flag{
-			&cli.StringFlag{
+			&cli.BoolFlag{
+				Name:    "print-config",
+				Usage:   "Print the effective configuration as JSON and exit without starting the server",
+				Value:   false,
+				Sources: cli.EnvVars("RC_EXPORTER_PRINT_CONFIG"),
+			},
+			&cli.StringSliceFlag{
 				Name:    "web.listen-address",
-				Usage:   "Address to listen on",
-				Value:   DefaultListenAddress,
+				Usage:   "Address to listen on (can be repeated to listen on multiple addresses)",
+				Value:   []string{DefaultListenAddress},
 				Sources: cli.EnvVars("RC_EXPORTER_LISTEN"),
 			},
+			&cli.StringFlag{
+				Name:    "web.tls-cert-file",
+				Usage:   "Path to the TLS certificate file (enables HTTPS together with --web.tls-key-file)",
+				Value:   "",
+				Sources: cli.EnvVars("RC_EXPORTER_TLS_CERT_FILE"),
+			},
+			&cli.StringFlag{
+				Name:    "web.tls-key-file",
+				Usage:   "Path to the TLS private key file",
+				Value:   "",
+				Sources: cli.EnvVars("RC_EXPORTER_TLS_KEY_FILE"),
+			},
+			&cli.BoolFlag{
+				Name:    "web.enable-http3",
+				Usage:   "Additionally serve HTTP/3 (QUIC) on UDP at each listen address (requires TLS)",
+				Value:   false,
+				Sources: cli.EnvVars("RC_EXPORTER_ENABLE_HTTP3"),
+			},
 			&cli.StringFlag{
 				Name:    "web.telemetry-path",
 				Usage:   "Path to expose metrics",
@@ -448,12 +892,30 @@ func main() {
 				Value:   DefaultProbePath,
 				Sources: cli.EnvVars("RC_EXPORTER_PROBE"),
 			},
+			&cli.StringFlag{
+				Name:    "web.reachable-path",
+				Usage:   "Path to expose reachability check endpoint",
+				Value:   DefaultReachablePath,
+				Sources: cli.EnvVars("RC_EXPORTER_REACHABLE"),
+			},
 			&cli.StringFlag{
 				Name:    "web.health-path",
 				Usage:   "Path to expose health check endpoint",
 				Value:   DefaultHealthPath,
 				Sources: cli.EnvVars("RC_EXPORTER_HEALTH"),
 			},
+			&cli.StringFlag{
+				Name:    "web.ready-path",
+				Usage:   "Path to expose readiness endpoint, which answers 503 while draining or in maintenance mode",
+				Value:   DefaultReadyPath,
+				Sources: cli.EnvVars("RC_EXPORTER_READY"),
+			},
+			&cli.StringFlag{
+				Name:    "web.version-path",
+				Usage:   "Path to expose version endpoint",
+				Value:   DefaultVersionPath,
+				Sources: cli.EnvVars("RC_EXPORTER_VERSION_PATH"),
+			},
 			&cli.StringFlag{
 				Name:    "web.remotes-path",
 				Usage:   "Path to expose remotes endpoint",
@@ -466,6 +928,77 @@ func main() {
 				Value:   DefaultConfigPath,
 				Sources: cli.EnvVars("RC_EXPORTER_CONFIG"),
 			},
+			&cli.StringFlag{
+				Name:    "web.sync-path",
+				Usage:   "Path to expose rclone sync stats endpoint (requires --sync.rc-url or --sync.stats-log-file)",
+				Value:   DefaultSyncPath,
+				Sources: cli.EnvVars("RC_EXPORTER_SYNC"),
+			},
+			&cli.StringSliceFlag{
+				Name:    "web.response-headers",
+				Usage:   "Header added to every response, as 'Name: value' (e.g. 'Cache-Control: no-store'); repeat for several headers",
+				Sources: cli.EnvVars("RC_EXPORTER_WEB_RESPONSE_HEADERS"),
+			},
+			&cli.BoolFlag{
+				Name:    "web.config-redact",
+				Usage:   "Mask the rclone binary path and listen addresses and omit memory stats in the config endpoint",
+				Value:   false,
+				Sources: cli.EnvVars("RC_EXPORTER_CONFIG_REDACT"),
+			},
+			&cli.StringFlag{
+				Name:    "web.cache-clear-path",
+				Usage:   "Path to expose the admin cache clear endpoint",
+				Value:   DefaultCacheClearPath,
+				Sources: cli.EnvVars("RC_EXPORTER_CACHE_CLEAR"),
+			},
+			&cli.BoolFlag{
+				Name:    "web.enable-rclone-config",
+				Usage:   "Expose the rclone config with secrets redacted on --web.rclone-config-path (requires --web.admin-token)",
+				Value:   false,
+				Sources: cli.EnvVars("RC_EXPORTER_ENABLE_RCLONE_CONFIG"),
+			},
+			&cli.StringFlag{
+				Name:    "web.rclone-config-path",
+				Usage:   "Path to expose the redacted rclone config admin endpoint",
+				Value:   DefaultRcloneConfigPath,
+				Sources: cli.EnvVars("RC_EXPORTER_RCLONE_CONFIG_PATH"),
+			},
+			&cli.StringFlag{
+				Name:    "web.maintenance-path",
+				Usage:   "Path to expose the admin maintenance mode endpoint",
+				Value:   DefaultMaintenancePath,
+				Sources: cli.EnvVars("RC_EXPORTER_MAINTENANCE_PATH"),
+			},
+			&cli.StringFlag{
+				Name:    "web.snapshot-path",
+				Usage:   "Path to expose the admin endpoint recording size snapshots of all remotes",
+				Value:   DefaultSnapshotPath,
+				Sources: cli.EnvVars("RC_EXPORTER_SNAPSHOT_PATH"),
+			},
+			&cli.StringFlag{
+				Name:    "web.snapshot-diff-path",
+				Usage:   "Path to expose the admin endpoint comparing two size snapshots",
+				Value:   DefaultSnapshotDiffPath,
+				Sources: cli.EnvVars("RC_EXPORTER_SNAPSHOT_DIFF_PATH"),
+			},
+			&cli.StringFlag{
+				Name:    "web.admin-token",
+				Usage:   "Bearer token required for admin endpoints (admin endpoints are disabled if empty)",
+				Value:   "",
+				Sources: cli.EnvVars("RC_EXPORTER_ADMIN_TOKEN"),
+			},
+			&cli.BoolFlag{
+				Name:    "maintenance",
+				Usage:   "Start in maintenance mode: probes serve cached size results only, background probes pause and the readiness endpoint answers 503",
+				Value:   false,
+				Sources: cli.EnvVars("RC_EXPORTER_MAINTENANCE"),
+			},
+			&cli.StringFlag{
+				Name:    "config.file",
+				Usage:   "Path to an optional YAML configuration file",
+				Value:   "",
+				Sources: cli.EnvVars("RC_EXPORTER_CONFIG_FILE"),
+			},
 			&cli.StringFlag{
 				Name:    "rclone.path",
 				Usage:   "Path to the rclone binary",
@@ -478,12 +1011,272 @@ func main() {
 				Value:   DefaultRcloneTimeout,
 				Sources: cli.EnvVars("RC_EXPORTER_RCLONE_TIMEOUT"),
 			},
+			&cli.DurationFlag{
+				Name:    "rclone.slow-probe-threshold",
+				Usage:   "Probe duration above which a warning is logged (0 uses half of --rclone.timeout, negative disables)",
+				Value:   0,
+				Sources: cli.EnvVars("RC_EXPORTER_RCLONE_SLOW_PROBE_THRESHOLD"),
+			},
+			&cli.DurationFlag{
+				Name:    "rclone.cache-ttl",
+				Usage:   "Serve repeated size probes of a remote from memory for this long (disabled if 0)",
+				Value:   0,
+				Sources: cli.EnvVars("RC_EXPORTER_RCLONE_CACHE_TTL"),
+			},
+			&cli.DurationFlag{
+				Name:    "rclone.max-result-age",
+				Usage:   "Hard cap on the age of a served size result, refreshing older cached results regardless of the cache TTL (no cap if 0)",
+				Value:   0,
+				Sources: cli.EnvVars("RC_EXPORTER_RCLONE_MAX_RESULT_AGE"),
+			},
+			&cli.StringFlag{
+				Name:    "cache.backend",
+				Usage:   "Where cached size results are kept: 'memory' or 'bolt' (a file that survives restarts, see --cache.path)",
+				Value:   exporter.CacheBackendMemory,
+				Sources: cli.EnvVars("RC_EXPORTER_CACHE_BACKEND"),
+			},
+			&cli.StringFlag{
+				Name:    "cache.path",
+				Usage:   "Cache file of the bolt cache backend, created if missing",
+				Sources: cli.EnvVars("RC_EXPORTER_CACHE_PATH"),
+			},
+			&cli.BoolFlag{
+				Name:    "rclone.disable-http2",
+				Usage:   "Pass --disable-http2 to rclone size commands",
+				Value:   false,
+				Sources: cli.EnvVars("RC_EXPORTER_RCLONE_DISABLE_HTTP2"),
+			},
+			&cli.DurationFlag{
+				Name:    "rclone.contimeout",
+				Usage:   "Connect timeout passed to rclone as --contimeout (0 uses rclone's default)",
+				Value:   0,
+				Sources: cli.EnvVars("RC_EXPORTER_RCLONE_CONTIMEOUT"),
+			},
+			&cli.IntFlag{
+				Name:    "rclone.low-level-retries",
+				Usage:   "Low level retries passed to rclone as --low-level-retries (0 uses rclone's default)",
+				Value:   0,
+				Sources: cli.EnvVars("RC_EXPORTER_RCLONE_LOW_LEVEL_RETRIES"),
+			},
+			&cli.IntFlag{
+				Name:    "rclone.checkers",
+				Usage:   "Checkers passed to rclone size as --checkers (0 uses rclone's default)",
+				Value:   0,
+				Sources: cli.EnvVars("RC_EXPORTER_RCLONE_CHECKERS"),
+			},
+			&cli.IntFlag{
+				Name:    "rclone.transfers",
+				Usage:   "Transfers passed to rclone size as --transfers (0 uses rclone's default)",
+				Value:   0,
+				Sources: cli.EnvVars("RC_EXPORTER_RCLONE_TRANSFERS"),
+			},
+			&cli.StringFlag{
+				Name:    "rclone.traversal",
+				Usage:   "Default traversal of recursive listings: fast-list (fewer API calls, more memory) or no-traverse, overridable per probe with the traversal query parameter",
+				Value:   rclone.TraversalFastList,
+				Sources: cli.EnvVars("RC_EXPORTER_RCLONE_TRAVERSAL"),
+			},
+			&cli.StringSliceFlag{
+				Name:    "rclone.size-extra",
+				Usage:   "Extra flag passed through to rclone size as --flag or --flag=value, e.g. --max-backlog=100000 (can be repeated)",
+				Sources: cli.EnvVars("RC_EXPORTER_RCLONE_SIZE_EXTRA"),
+			},
+			&cli.IntSliceFlag{
+				Name:    "rclone.success-exit-codes",
+				Usage:   "rclone size exit codes to report as success with zero size, e.g. 3 for a directory that does not exist yet",
+				Sources: cli.EnvVars("RC_EXPORTER_RCLONE_SUCCESS_EXIT_CODES"),
+			},
+			&cli.StringSliceFlag{
+				Name:    "rclone.binaries",
+				Usage:   "Additional rclone binaries as alias=path, selectable per probe with the binary query parameter (can be repeated)",
+				Sources: cli.EnvVars("RC_EXPORTER_RCLONE_BINARIES"),
+			},
+			&cli.IntFlag{
+				Name:    "rclone.nice",
+				Usage:   "Niceness of rclone processes, from -20 to 19 (0 leaves it unchanged, higher values yield CPU to other processes)",
+				Value:   0,
+				Sources: cli.EnvVars("RC_EXPORTER_RCLONE_NICE"),
+			},
+			&cli.StringFlag{
+				Name:    "rclone.ionice",
+				Usage:   "I/O priority of rclone processes on Linux: idle, best-effort:N or realtime:N with N from 0 to 7 (empty leaves it unchanged)",
+				Value:   "",
+				Sources: cli.EnvVars("RC_EXPORTER_RCLONE_IONICE"),
+			},
+			&cli.BoolFlag{
+				Name:    "rclone.watch-config",
+				Usage:   "Clear the remote type cache whenever the rclone config file changes",
+				Sources: cli.EnvVars("RC_EXPORTER_RCLONE_WATCH_CONFIG"),
+			},
+			&cli.StringFlag{
+				Name:    "rclone.config-pass-command",
+				Usage:   "Command rclone runs to obtain the config password (sets RCLONE_PASSWORD_COMMAND for every rclone call)",
+				Sources: cli.EnvVars("RC_EXPORTER_RCLONE_CONFIG_PASS_COMMAND"),
+			},
+			&cli.StringFlag{
+				Name:    "rclone.user-agent",
+				Usage:   "User-Agent passed to rclone as --user-agent (empty uses rclone's default)",
+				Value:   "",
+				Sources: cli.EnvVars("RC_EXPORTER_RCLONE_USER_AGENT"),
+			},
+			&cli.StringFlag{
+				Name:    "scrape.mode",
+				Usage:   "How remotes are probed: pull runs rclone on each /probe request, push probes in the background every --scrape.interval and serves the latest results on /metrics",
+				Value:   exporter.ScrapeModePull,
+				Sources: cli.EnvVars("RC_EXPORTER_SCRAPE_MODE"),
+			},
+			&cli.DurationFlag{
+				Name:    "scrape.interval",
+				Usage:   "Interval between background probes in push mode",
+				Value:   DefaultScrapeInterval,
+				Sources: cli.EnvVars("RC_EXPORTER_SCRAPE_INTERVAL"),
+			},
+			&cli.DurationFlag{
+				Name:    "scrape.jitter",
+				Usage:   "Delay each background probe by a random amount up to this duration, spreading the remotes across the interval (at most --scrape.interval)",
+				Value:   0,
+				Sources: cli.EnvVars("RC_EXPORTER_SCRAPE_JITTER"),
+			},
+			&cli.DurationFlag{
+				Name:    "scrape.timeout",
+				Usage:   "Deadline of a probe request: shortens --rclone.timeout of single probes and serves partial results of probes of several remotes (0 relies on Prometheus's scrape timeout header alone)",
+				Value:   0,
+				Sources: cli.EnvVars("RC_EXPORTER_SCRAPE_TIMEOUT"),
+			},
+			&cli.StringSliceFlag{
+				Name:    "scrape.remotes",
+				Usage:   "Remotes probed in push mode (can be repeated, default all configured remotes)",
+				Sources: cli.EnvVars("RC_EXPORTER_SCRAPE_REMOTES"),
+			},
+			&cli.StringFlag{
+				Name:    "rclone.remotes-file",
+				Usage:   "File listing the remotes probed in push mode, one per line, re-read on every round",
+				Sources: cli.EnvVars("RC_EXPORTER_RCLONE_REMOTES_FILE"),
+			},
+			&cli.StringFlag{
+				Name:    "output.file",
+				Usage:   "Also write the /metrics exposition to this file every --output.interval, e.g. for node_exporter's textfile collector (disabled if empty)",
+				Sources: cli.EnvVars("RC_EXPORTER_OUTPUT_FILE"),
+			},
+			&cli.DurationFlag{
+				Name:    "output.interval",
+				Usage:   "How often --output.file is rewritten",
+				Value:   DefaultOutputInterval,
+				Sources: cli.EnvVars("RC_EXPORTER_OUTPUT_INTERVAL"),
+			},
+			&cli.DurationFlag{
+				Name:    "server.drain-period",
+				Usage:   "After a shutdown signal, keep serving for this long while new probes and /health get 503, so scrapers notice before connections close",
+				Value:   0,
+				Sources: cli.EnvVars("RC_EXPORTER_DRAIN_PERIOD"),
+			},
 			&cli.DurationFlag{
 				Name:    "server.shutdown-timeout",
 				Usage:   "Timeout for graceful server shutdown",
 				Value:   DefaultShutdownTimeout,
 				Sources: cli.EnvVars("RC_EXPORTER_SHUTDOWN_TIMEOUT"),
 			},
+			&cli.BoolFlag{
+				Name:    "probe.fail-status",
+				Usage:   "Answer failed probes with HTTP 500; when false, answer 200 with the metrics and probe_success 0",
+				Value:   true,
+				Sources: cli.EnvVars("RC_EXPORTER_PROBE_FAIL_STATUS"),
+			},
+			&cli.BoolFlag{
+				Name:    "startup-selftest",
+				Usage:   "Check at startup that every configured remote can be listed and log a summary",
+				Sources: cli.EnvVars("RC_EXPORTER_STARTUP_SELFTEST"),
+			},
+			&cli.DurationFlag{
+				Name:    "startup-selftest.timeout",
+				Usage:   "Timeout of each remote check during the startup self-test",
+				Value:   DefaultSelfTestTimeout,
+				Sources: cli.EnvVars("RC_EXPORTER_STARTUP_SELFTEST_TIMEOUT"),
+			},
+			&cli.BoolFlag{
+				Name:    "probe.enrich",
+				Usage:   "Add backend-specific metrics to probes (S3 region, local filesystem type)",
+				Sources: cli.EnvVars("RC_EXPORTER_PROBE_ENRICH"),
+			},
+			&cli.StringFlag{
+				Name:    "probe.default-remote",
+				Usage:   "Remote probed by /probe requests without a remote parameter (empty requires the parameter)",
+				Sources: cli.EnvVars("RC_EXPORTER_PROBE_DEFAULT_REMOTE"),
+			},
+			&cli.BoolFlag{
+				Name:    "probe.count-only",
+				Usage:   "Count objects with rclone lsf instead of running rclone size, skipping byte totals (override per probe with size=true)",
+				Sources: cli.EnvVars("RC_EXPORTER_PROBE_COUNT_ONLY"),
+			},
+			&cli.StringFlag{
+				Name:    "alert.webhook-url",
+				Usage:   "Webhook URL receiving a JSON POST when a remote fails repeatedly (disabled if empty)",
+				Value:   "",
+				Sources: cli.EnvVars("RC_EXPORTER_ALERT_WEBHOOK_URL"),
+			},
+			&cli.IntFlag{
+				Name:    "alert.failure-threshold",
+				Usage:   "Number of consecutive probe failures that triggers the alert webhook",
+				Value:   DefaultAlertFailureThreshold,
+				Sources: cli.EnvVars("RC_EXPORTER_ALERT_FAILURE_THRESHOLD"),
+			},
+			&cli.StringSliceFlag{
+				Name:    "alert.remotes",
+				Usage:   "Remotes whose failures trigger the alert webhook (can be repeated, all remotes if empty)",
+				Sources: cli.EnvVars("RC_EXPORTER_ALERT_REMOTES"),
+			},
+			&cli.FloatFlag{
+				Name:    "alert.free-percent-threshold",
+				Usage:   "Free space percentage below which mode=about probes set rclone_remote_space_low to 1 (disabled if 0)",
+				Value:   0,
+				Sources: cli.EnvVars("RC_EXPORTER_ALERT_FREE_PERCENT_THRESHOLD"),
+			},
+			&cli.StringFlag{
+				Name:    "sync.rc-url",
+				Usage:   "URL of a running rclone rc server whose core/stats are exposed on the sync endpoint",
+				Sources: cli.EnvVars("RC_EXPORTER_SYNC_RC_URL"),
+			},
+			&cli.StringFlag{
+				Name:    "sync.rc-user",
+				Usage:   "Basic auth user for the rclone rc server",
+				Sources: cli.EnvVars("RC_EXPORTER_SYNC_RC_USER"),
+			},
+			&cli.StringFlag{
+				Name:    "sync.rc-pass",
+				Usage:   "Basic auth password for the rclone rc server",
+				Sources: cli.EnvVars("RC_EXPORTER_SYNC_RC_PASS"),
+			},
+			&cli.StringFlag{
+				Name:    "sync.stats-log-file",
+				Usage:   "rclone --stats-log-file written with --use-json-log whose latest stats are exposed on the sync endpoint",
+				Sources: cli.EnvVars("RC_EXPORTER_SYNC_STATS_LOG_FILE"),
+			},
+			&cli.BoolFlag{
+				Name:    "metrics.native-histograms",
+				Usage:   "Export the probe duration histogram as a Prometheus native histogram instead of classic buckets",
+				Sources: cli.EnvVars("RC_EXPORTER_METRICS_NATIVE_HISTOGRAMS"),
+			},
+			&cli.BoolFlag{
+				Name:    "metrics.objects-histogram",
+				Usage:   "Observe the object count of every successful probe in rclone_exporter_objects_count_distribution",
+				Sources: cli.EnvVars("RC_EXPORTER_METRICS_OBJECTS_HISTOGRAM"),
+			},
+			&cli.BoolFlag{
+				Name:    "metrics.prerender",
+				Usage:   "Render and gzip /metrics once and serve it until new probe data arrives (intended for --scrape.mode=push)",
+				Sources: cli.EnvVars("RC_EXPORTER_METRICS_PRERENDER"),
+			},
+			&cli.StringFlag{
+				Name:    "metrics.error-handling",
+				Usage:   "How /metrics and the probe endpoints treat a metric collection error: continue (serve what was gathered), http-error (HTTP 500) or panic",
+				Value:   exporter.ErrorHandlingContinue,
+				Sources: cli.EnvVars("RC_EXPORTER_METRICS_ERROR_HANDLING"),
+			},
+			&cli.StringSliceFlag{
+				Name:    "metrics.const-labels",
+				Usage:   "Label added to every exported metric as key=value (can be repeated)",
+				Sources: cli.EnvVars("RC_EXPORTER_METRICS_CONST_LABELS"),
+			},
 			&cli.BoolFlag{
 				Name:    "log.pretty",
 				Usage:   "Enable human-readable log format",
@@ -508,6 +1301,11 @@ func main() {
 				Value:   false,
 				Sources: cli.EnvVars("RC_EXPORTER_LOG_TRACE"),
 			},
+			&cli.StringSliceFlag{
+				Name:    "log.path-levels",
+				Usage:   "Minimum log level for requests to a path, as path=level (e.g. /probe=debug); repeat for several paths",
+				Sources: cli.EnvVars("RC_EXPORTER_LOG_PATH_LEVELS"),
+			},
 			&cli.BoolFlag{
 				Name:    "log.warn",
 				Usage:   "Set log level to warn and above only",
@@ -526,11 +1324,40 @@ func main() {
 				return fmt.Errorf("failed to setup logging: %w", err)
 			}
 
+			// Checked before the rclone binary runs, so mistakes fail fast
+			err := validateFlags(cmd)
+			if cmd.Bool("print-config") {
+				// Printed even for invalid flags, which still fail the exit code
+				if printErr := printConfig(cmd.Root().Writer, cmd); printErr != nil {
+					return fmt.Errorf("failed to print config: %w", printErr)
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("invalid flags: %w", err)
+			}
+			if cmd.Bool("print-config") {
+				return nil
+			}
+
 			return runServer(ctx, cmd)
 		},
 	}
+}
+
+// main function initializes the CLI application and starts the server
+func main() {
+	app := newApp()
+
+	// Registered before anything slow runs, so a signal during startup ends it promptly
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := app.Run(ctx, os.Args)
+	stop()
 
-	if err := app.Run(context.Background(), os.Args); err != nil {
+	switch {
+	case err == nil:
+	case errors.Is(err, context.Canceled):
+		log.Info().Msg("Shutdown signal received during startup, exiting")
+	default:
 		log.Fatal().Err(err).Msg("Application startup failed")
 	}
 }
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestRedactConfigResponse(t *testing.T) {
+	config := ConfigResponse{
+		BuildInfo: BuildInfo{Version: "v1.2.3"},
+		ServerConfig: ServerConfig{
+			ListenAddress:   "10.0.0.5:9116",
+			ListenAddresses: []string{"10.0.0.5:9116", "127.0.0.1:9116"},
+		},
+		RcloneConfig: RcloneConfig{BinaryPath: "/opt/secret/bin/rclone", Version: "rclone v1.66.0"},
+		RuntimeInfo:  &RuntimeInfo{GoMemStats: "Alloc=1MB"},
+	}
+
+	redacted := redactConfigResponse(config)
+	body, err := json.Marshal(redacted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, leaked := range []string{"/opt/secret", "10.0.0.5", "127.0.0.1", "Alloc="} {
+		if strings.Contains(string(body), leaked) {
+			t.Errorf("redacted config contains %q: %s", leaked, body)
+		}
+	}
+
+	// Build and version info stays available for version checks
+	if redacted.BuildInfo.Version != "v1.2.3" || redacted.RcloneConfig.Version != "rclone v1.66.0" {
+		t.Errorf("redaction removed version info: %+v", redacted)
+	}
+
+	// The original response is left untouched
+	if config.ServerConfig.ListenAddresses[0] != "10.0.0.5:9116" {
+		t.Errorf("redaction modified the input: %+v", config.ServerConfig)
+	}
+}
+
+func TestReadyHandler(t *testing.T) {
+	tests := []struct {
+		draining, maintenance bool
+		want                  int
+	}{
+		{false, false, http.StatusOK},
+		{true, false, http.StatusServiceUnavailable},
+		{false, true, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		handler := readyHandler(func() bool { return tt.draining }, func() bool { return tt.maintenance })
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		if rec.Code != tt.want {
+			t.Errorf("draining=%v maintenance=%v: status = %d, want %d", tt.draining, tt.maintenance, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestUptimeMetric(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	createUptimeMetric(registry)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	if len(families) != 1 || families[0].GetName() != "rclone_exporter_uptime_seconds" {
+		t.Fatalf("registered families = %v, want rclone_exporter_uptime_seconds", families)
+	}
+
+	// The gauge follows the clock rather than a value set at startup
+	first := families[0].GetMetric()[0].GetGauge().GetValue()
+	time.Sleep(20 * time.Millisecond)
+	families, _ = registry.Gather()
+	second := families[0].GetMetric()[0].GetGauge().GetValue()
+	if first <= 0 || second-first < 0.01 {
+		t.Errorf("uptime went from %v to %v, want it positive and increasing", first, second)
+	}
+}
+
+func TestPrintConfig(t *testing.T) {
+	t.Setenv("RC_EXPORTER_RCLONE_TIMEOUT", "45s")
+	t.Setenv("RC_EXPORTER_ADMIN_TOKEN", "hunter2")
+
+	var out bytes.Buffer
+	app := newApp()
+	app.Writer = &out
+	if err := app.Run(context.Background(), []string{"rclone_exporter", "--print-config", "--web.probe-path=/check"}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var config ConfigResponse
+	if err := json.Unmarshal(out.Bytes(), &config); err != nil {
+		t.Fatalf("decoding printed config: %v\n%s", err, out.String())
+	}
+	if config.RcloneConfig.Timeout != "45s" || config.Flags["rclone.timeout"] != "45s" {
+		t.Errorf("rclone timeout = %q / %v, want the environment override 45s", config.RcloneConfig.Timeout, config.Flags["rclone.timeout"])
+	}
+	if config.Endpoints.ProbePath != "/check" {
+		t.Errorf("probe path = %q, want /check", config.Endpoints.ProbePath)
+	}
+	if config.Flags["web.admin-token"] != redactedConfigValue || strings.Contains(out.String(), "hunter2") {
+		t.Errorf("admin token not masked\n%s", out.String())
+	}
+	if _, ok := config.Flags["print-config"]; ok || config.RuntimeInfo != nil {
+		t.Errorf("printed config includes print-config or runtime info\n%s", out.String())
+	}
+}
+
+func TestPrintConfigInvalidFlags(t *testing.T) {
+	var out bytes.Buffer
+	app := newApp()
+	app.Writer = &out
+	err := app.Run(context.Background(), []string{"rclone_exporter", "--print-config", "--scrape.timeout=-1s"})
+	if err == nil || !strings.Contains(err.Error(), "invalid flags") {
+		t.Fatalf("Run() error = %v, want invalid flags", err)
+	}
+	if !json.Valid(out.Bytes()) {
+		t.Errorf("config not printed for invalid flags\n%s", out.String())
+	}
+}
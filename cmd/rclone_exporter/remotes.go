@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crazyuploader/rclone_exporter/internal/rclone"
+)
+
+// remotesPage is a window into the filtered remote list of /remotes
+type remotesPage struct {
+	typ    string // Only remotes of this type, empty for all
+	name   string // Only remotes whose name matches this glob, empty for all
+	limit  int    // Page size, 0 for no limit
+	offset int    // Number of filtered remotes to skip
+}
+
+// parseRemotesPage parses the filter and pagination parameters of /remotes
+func parseRemotesPage(query url.Values) (remotesPage, error) {
+	page := remotesPage{
+		typ:  strings.TrimSpace(query.Get("type")),
+		name: strings.TrimSpace(query.Get("name")),
+	}
+
+	if page.name != "" {
+		if _, err := path.Match(page.name, ""); err != nil {
+			return remotesPage{}, fmt.Errorf("invalid name pattern: %w", err)
+		}
+	}
+
+	for param, target := range map[string]*int{"limit": &page.limit, "offset": &page.offset} {
+		value := strings.TrimSpace(query.Get(param))
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return remotesPage{}, fmt.Errorf("%s must be a non-negative integer", param)
+		}
+		*target = n
+	}
+	if query.Has("limit") && page.limit == 0 {
+		return remotesPage{}, fmt.Errorf("limit must be at least 1")
+	}
+
+	return page, nil
+}
+
+// apply filters the remotes and returns the requested page with the filtered total.
+// Filtering runs first, so offsets count matching remotes only.
+func (p remotesPage) apply(remotes []rclone.RemoteInfo) ([]rclone.RemoteInfo, int) {
+	filtered := make([]rclone.RemoteInfo, 0, len(remotes))
+	for _, remote := range remotes {
+		if p.typ != "" && remote.Type != p.typ {
+			continue
+		}
+		// The pattern was validated, so Match cannot fail
+		if ok, _ := path.Match(p.name, remote.Name); p.name != "" && !ok {
+			continue
+		}
+		filtered = append(filtered, remote)
+	}
+
+	total := len(filtered)
+	start := min(p.offset, total)
+	end := total
+	if p.limit > 0 {
+		end = min(start+p.limit, total)
+	}
+	return filtered[start:end], total
+}
+
+// remotesHandler lists the configured remotes, optionally filtered and paginated
+func remotesHandler(rcloneClient rclone.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, err := parseRemotesPage(r.URL.Query())
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid parameter: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		remotes, err := rcloneClient.ListRemotes()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to list remotes: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		pageRemotes, total := page.apply(remotes)
+		resp := map[string]interface{}{
+			"remotes":      pageRemotes,
+			"remote_count": total,
+			"total":        total,
+			"offset":       page.offset,
+			"timestamp":    time.Now().UTC().Format(time.RFC3339),
+		}
+		if page.limit > 0 {
+			resp["limit"] = page.limit
+			if next := page.offset + len(pageRemotes); next < total {
+				resp["next_offset"] = next
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, "Failed to encode remotes as JSON", http.StatusInternalServerError)
+		}
+	}
+}
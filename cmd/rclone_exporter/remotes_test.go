@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/crazyuploader/rclone_exporter/internal/rclone"
+)
+
+func TestRemotesPageApply(t *testing.T) {
+	remotes := []rclone.RemoteInfo{
+		{Name: "prod-a", Type: "s3"},
+		{Name: "prod-b", Type: "drive"},
+		{Name: "prod-c", Type: "s3"},
+		{Name: "dev-a", Type: "s3"},
+	}
+
+	tests := []struct {
+		query     string
+		want      []string
+		wantTotal int
+	}{
+		{"", []string{"prod-a", "prod-b", "prod-c", "dev-a"}, 4},
+		{"limit=2", []string{"prod-a", "prod-b"}, 4},
+		{"limit=2&offset=2", []string{"prod-c", "dev-a"}, 4},
+		{"limit=2&offset=3", []string{"dev-a"}, 4},
+		{"limit=2&offset=4", []string{}, 4},
+		{"offset=10", []string{}, 4},
+		{"limit=10", []string{"prod-a", "prod-b", "prod-c", "dev-a"}, 4},
+		// Filters apply before pagination
+		{"type=s3&limit=1&offset=1", []string{"prod-c"}, 3},
+		{"name=prod-*&type=s3", []string{"prod-a", "prod-c"}, 2},
+		{"name=none-*", []string{}, 0},
+	}
+
+	for _, tt := range tests {
+		query, _ := url.ParseQuery(tt.query)
+		page, err := parseRemotesPage(query)
+		if err != nil {
+			t.Errorf("%q: parseRemotesPage() error = %v", tt.query, err)
+			continue
+		}
+
+		got, total := page.apply(remotes)
+		names := []string{}
+		for _, remote := range got {
+			names = append(names, remote.Name)
+		}
+		if !slices.Equal(names, tt.want) || total != tt.wantTotal {
+			t.Errorf("%q: apply() = %v, %d, want %v, %d", tt.query, names, total, tt.want, tt.wantTotal)
+		}
+	}
+}
+
+func TestParseRemotesPageRejectsInvalid(t *testing.T) {
+	for _, raw := range []string{"limit=0", "limit=-1", "limit=abc", "offset=-1", "name=[", "limit="} {
+		query, _ := url.ParseQuery(raw)
+		if _, err := parseRemotesPage(query); err == nil {
+			t.Errorf("parseRemotesPage(%q) error = nil, want error", raw)
+		}
+	}
+}
+
+func TestRemotesHandlerPagination(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rclone")
+	script := `#!/bin/sh
+echo '[{"name":"a","type":"s3"},{"name":"b","type":"s3"},{"name":"c","type":"s3"}]'
+`
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	handler := remotesHandler(rclone.NewRcloneClientWithConfig(path, 5*time.Second))
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/remotes?limit=2&offset=1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var resp struct {
+		Remotes     []rclone.RemoteInfo `json:"remotes"`
+		RemoteCount int                 `json:"remote_count"`
+		Total       int                 `json:"total"`
+		NextOffset  *int                `json:"next_offset"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Remotes) != 2 || resp.Remotes[0].Name != "b" {
+		t.Errorf("remotes = %+v, want b and c", resp.Remotes)
+	}
+	if resp.Total != 3 || resp.RemoteCount != 3 {
+		t.Errorf("total = %d, remote_count = %d, want 3 and 3", resp.Total, resp.RemoteCount)
+	}
+	if resp.NextOffset != nil {
+		t.Errorf("next_offset = %d on the last page, want none", *resp.NextOffset)
+	}
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/remotes?limit=0", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("limit=0 status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// statusRecorder remembers the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+// WriteHeader implements http.ResponseWriter
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.code == 0 {
+		s.code = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements http.ResponseWriter
+func (s *statusRecorder) Write(p []byte) (int, error) {
+	if s.code == 0 {
+		s.code = http.StatusOK
+	}
+	return s.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// withResponseCounter counts responses by status code and by the mux pattern that
+// served them, so unknown paths fall under "/" instead of adding new series
+func withResponseCounter(registry prometheus.Registerer, mux *http.ServeMux, next http.Handler) http.Handler {
+	responses := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "rclone_exporter",
+			Name:      "http_responses_total",
+			Help:      "Total number of HTTP responses by handler path and status code",
+		},
+		[]string{"path", "code"},
+	)
+	registry.MustRegister(responses)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+
+		code := recorder.code
+		if code == 0 {
+			code = http.StatusOK
+		}
+		responses.WithLabelValues(pattern, strconv.Itoa(code)).Inc()
+	})
+}
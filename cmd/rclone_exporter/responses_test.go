@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestResponseCounter(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) })
+	mux.HandleFunc("/probe", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("remote") == "" {
+			http.Error(w, "missing remote", http.StatusBadRequest)
+			return
+		}
+		// Probes extend their write deadline through the recorder
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(time.Minute)); err != nil {
+			t.Errorf("SetWriteDeadline() error = %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	registry := prometheus.NewRegistry()
+	server := httptest.NewServer(withResponseCounter(registry, mux, mux))
+	defer server.Close()
+
+	for _, path := range []string{"/probe", "/probe?remote=a:", "/probe?remote=b:", "/unknown", "/"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	want := `
+# HELP rclone_exporter_http_responses_total Total number of HTTP responses by handler path and status code
+# TYPE rclone_exporter_http_responses_total counter
+rclone_exporter_http_responses_total{code="200",path="/"} 2
+rclone_exporter_http_responses_total{code="200",path="/probe"} 2
+rclone_exporter_http_responses_total{code="400",path="/probe"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
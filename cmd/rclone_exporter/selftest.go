@@ -0,0 +1,88 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/crazyuploader/rclone_exporter/internal/exporter"
+	"github.com/crazyuploader/rclone_exporter/internal/rclone"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+)
+
+// selfTestResult is the outcome of the startup check of one remote
+type selfTestResult struct {
+	Remote   string
+	Err      error
+	Duration time.Duration
+}
+
+// runSelfTest checks that every configured remote can be listed, logs a summary
+// and records rclone_exporter_selftest_success for each remote. Failures are
+// only reported; the exporter keeps serving.
+func runSelfTest(client rclone.Client, registry prometheus.Registerer) []selfTestResult {
+	selfTestSuccess := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "rclone_exporter",
+			Name:      "selftest_success",
+			Help:      "Whether the remote passed the startup self-test (1 = success, 0 = failure)",
+		},
+		[]string{"remote"},
+	)
+	registry.MustRegister(selfTestSuccess)
+
+	remotes, err := client.ListRemotes()
+	if err != nil {
+		log.Error().Err(err).Msg("Startup self-test could not list remotes")
+		return nil
+	}
+
+	results := make([]selfTestResult, len(remotes))
+	limit := make(chan struct{}, exporter.MaxProbeAllConcurrency)
+	var wg sync.WaitGroup
+	for i, info := range remotes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+
+			remote := info.Name + ":"
+			start := time.Now()
+			err := client.CheckReachable(remote)
+			results[i] = selfTestResult{Remote: remote, Err: err, Duration: time.Since(start)}
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Remote < results[j].Remote })
+
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			selfTestSuccess.WithLabelValues(result.Remote).Set(0)
+			log.Warn().
+				Err(result.Err).
+				Str("remote", result.Remote).
+				Dur("duration", result.Duration).
+				Msg("Self-test FAIL")
+			continue
+		}
+
+		selfTestSuccess.WithLabelValues(result.Remote).Set(1)
+		log.Info().
+			Str("remote", result.Remote).
+			Dur("duration", result.Duration).
+			Msg("Self-test OK")
+	}
+
+	log.Info().
+		Int("remotes", len(results)).
+		Int("passed", len(results)-failed).
+		Int("failed", failed).
+		Msg("Startup self-test completed")
+
+	return results
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crazyuploader/rclone_exporter/internal/rclone"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRunSelfTest(t *testing.T) {
+	// Fake rclone: two remotes, only "good" can be listed
+	path := filepath.Join(t.TempDir(), "rclone")
+	script := `#!/bin/sh
+case "$1" in
+listremotes) echo '[{"name":"good","type":"local"},{"name":"bad","type":"s3"}]' ;;
+lsd) [ "$2" = "good:" ] || { echo "auth failed" >&2; exit 1; } ;;
+esac
+`
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := prometheus.NewRegistry()
+	results := runSelfTest(rclone.NewRcloneClientWithConfig(path, 5*time.Second), registry)
+	if len(results) != 2 || results[0].Remote != "bad:" || results[0].Err == nil || results[1].Err != nil {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+
+	want := `
+# HELP rclone_exporter_selftest_success Whether the remote passed the startup self-test (1 = success, 0 = failure)
+# TYPE rclone_exporter_selftest_success gauge
+rclone_exporter_selftest_success{remote="bad:"} 0
+rclone_exporter_selftest_success{remote="good:"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(want), "rclone_exporter_selftest_success"); err != nil {
+		t.Error(err)
+	}
+}
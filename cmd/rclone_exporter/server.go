@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+	"github.com/rs/zerolog/log"
+	cli "github.com/urfave/cli/v3"
+)
+
+// listener abstracts a TCP or QUIC server so all of them share one lifecycle
+type listener struct {
+	protocol string
+	addr     string
+	listen   func() error // Binds the address, so serve accepts connections right away
+	close    func() error // Releases the bound address when serving never starts
+	serve    func() error
+	shutdown func(context.Context) error
+}
+
+// buildListeners creates one HTTP server per listen address, plus an HTTP/3 server
+// on the same address when enabled, all sharing the given handler
+func buildListeners(cmd *cli.Command, handler http.Handler) ([]listener, error) {
+	addresses := listenAddresses(cmd)
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("at least one listen address must be provided")
+	}
+
+	// validateFlags ensured the pair is complete and HTTP/3 only runs with TLS
+	certFile := cmd.String("web.tls-cert-file")
+	keyFile := cmd.String("web.tls-key-file")
+	tlsEnabled := certFile != ""
+	enableHTTP3 := cmd.Bool("web.enable-http3")
+
+	// Certificates are re-read when they change, so rotation needs no restart
+	var tlsConfig *tls.Config
+	if tlsEnabled {
+		reloader, err := newCertReloader(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig = &tls.Config{GetCertificate: reloader.GetCertificate}
+	}
+
+	listeners := make([]listener, 0, len(addresses)*2)
+	for _, addr := range addresses {
+		serverHandler := handler
+
+		if enableHTTP3 {
+			h3Server := &http3.Server{
+				Addr:        addr,
+				Handler:     handler,
+				TLSConfig:   tlsConfig,
+				IdleTimeout: 60 * time.Second,
+			}
+			var conn net.PacketConn
+			listeners = append(listeners, listener{
+				protocol: "http3",
+				addr:     addr,
+				listen:   func() (err error) { conn, err = net.ListenPacket("udp", addr); return err },
+				close:    func() error { return conn.Close() },
+				serve:    func() error { return h3Server.Serve(conn) },
+				shutdown: h3Server.Shutdown,
+			})
+
+			// Advertise HTTP/3 to TCP clients via the Alt-Svc header
+			serverHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := h3Server.SetQUICHeaders(w.Header()); err != nil {
+					log.Debug().Err(err).Str("listen", addr).Msg("Failed to set Alt-Svc header")
+				}
+				handler.ServeHTTP(w, r)
+			})
+		}
+
+		server := &http.Server{
+			Addr:         addr,
+			Handler:      serverHandler,
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 15 * time.Second,
+			IdleTimeout:  60 * time.Second,
+		}
+
+		var ln net.Listener
+		protocol := "http"
+		serve := func() error { return server.Serve(ln) }
+		if tlsEnabled {
+			protocol = "https"
+			server.TLSConfig = tlsConfig.Clone()
+			serve = func() error { return server.ServeTLS(ln, "", "") }
+		}
+
+		listeners = append(listeners, listener{
+			protocol: protocol,
+			addr:     addr,
+			listen:   func() (err error) { ln, err = net.Listen("tcp", addr); return err },
+			close:    func() error { return ln.Close() },
+			serve:    serve,
+			shutdown: server.Shutdown,
+		})
+	}
+
+	return listeners, nil
+}
+
+// drainConfig controls what happens between a shutdown signal and closing the listeners
+type drainConfig struct {
+	start  func()        // Called first, so handlers start rejecting new probes
+	period time.Duration // How long to keep serving those rejections before shutting down
+	stop   func()        // Called once the drain is over, so background work stops with the servers
+}
+
+// runListeners serves on all listeners until a shutdown signal arrives or one of them fails,
+// then gracefully shuts all of them down within the given timeout. On a signal the exporter
+// drains first: new probes get 503 while in-flight ones finish and background work goes on.
+// ctx is cancelled by a signal during startup, in which case none of the listeners are started.
+func runListeners(ctx context.Context, listeners []listener, drain drainConfig, shutdownTimeout time.Duration) error {
+	// Register before checking ctx, so no signal is missed in between
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Bind every address first, so readiness is only reported once all of them accept connections
+	for i, l := range listeners {
+		if l.listen == nil {
+			continue
+		}
+		if err := l.listen(); err != nil {
+			closeListeners(listeners[:i])
+			if errors.Is(err, syscall.EADDRINUSE) {
+				return fmt.Errorf("address %s already in use (%s), is another exporter running? %w", l.addr, l.protocol, err)
+			}
+			return fmt.Errorf("failed to listen on %s (%s): %w", l.addr, l.protocol, err)
+		}
+	}
+	log.Info().
+		Strs("listen", listenerAddresses(listeners)).
+		Msg("rclone_exporter is up and listening")
+
+	serveErrCh := make(chan error, len(listeners))
+	for _, l := range listeners {
+		go func() {
+			if err := l.serve(); err != nil && err != http.ErrServerClosed {
+				serveErrCh <- fmt.Errorf("%s server on %s crashed: %w", l.protocol, l.addr, err)
+				return
+			}
+			serveErrCh <- nil
+		}()
+	}
+
+	notifyReady()
+	stopWatchdog := startWatchdog()
+	defer stopWatchdog()
+
+	// Graceful shutdown: wait for a signal or the first server to stop
+	var serveErr error
+	pending := len(listeners)
+	select {
+	case <-sigCh:
+		log.Warn().Msg("Shutdown signal received")
+		notifyStopping()
+		pending -= drainListeners(drain, sigCh, serveErrCh, &serveErr)
+	case serveErr = <-serveErrCh:
+		pending--
+	}
+
+	if drain.stop != nil {
+		drain.stop()
+	}
+	shutdownListeners(listeners, shutdownTimeout)
+
+	for ; pending > 0; pending-- {
+		if err := <-serveErrCh; err != nil && serveErr == nil {
+			serveErr = err
+		}
+	}
+
+	return serveErr
+}
+
+// closeListeners releases the addresses of listeners that were bound but never served
+func closeListeners(listeners []listener) {
+	for _, l := range listeners {
+		if l.listen == nil || l.close == nil {
+			continue
+		}
+		if err := l.close(); err != nil {
+			log.Warn().Err(err).Str("protocol", l.protocol).Str("listen", l.addr).Msg("Failed to close listener")
+		}
+	}
+}
+
+// listenerAddresses returns each distinct address of the listeners, in order
+func listenerAddresses(listeners []listener) []string {
+	addresses := make([]string, 0, len(listeners))
+	for _, l := range listeners {
+		if !slices.Contains(addresses, l.addr) {
+			addresses = append(addresses, l.addr)
+		}
+	}
+	return addresses
+}
+
+// drainListeners rejects new probes for the drain period while the servers keep running.
+// A second signal or a crashed server ends the drain early. It returns how many servers
+// stopped meanwhile, recording the first failure in serveErr.
+func drainListeners(drain drainConfig, sigCh <-chan os.Signal, serveErrCh <-chan error, serveErr *error) int {
+	if drain.start != nil {
+		drain.start()
+	}
+	if drain.period <= 0 {
+		return 0
+	}
+
+	log.Info().Dur("period", drain.period).Msg("Draining, new probes are rejected with 503")
+
+	timer := time.NewTimer(drain.period)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-sigCh:
+		log.Warn().Msg("Second shutdown signal received, skipping the rest of the drain period")
+	case err := <-serveErrCh:
+		*serveErr = err
+		return 1
+	}
+	return 0
+}
+
+// shutdownListeners gracefully shuts down all listeners concurrently within the given timeout
+func shutdownListeners(listeners []listener, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, l := range listeners {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := l.shutdown(ctx); err != nil {
+				log.Error().Err(err).Str("protocol", l.protocol).Str("listen", l.addr).Msg("Server shutdown failed")
+			}
+		}()
+	}
+	wg.Wait()
+}
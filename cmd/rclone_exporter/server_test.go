@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	cli "github.com/urfave/cli/v3"
+)
+
+func TestHealthHandlerDraining(t *testing.T) {
+	draining := false
+	handler := healthHandler(func() bool { return draining })
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	draining = true
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("draining status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestRunListenersCancelledDuringStartup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	served := false
+	listeners := []listener{{
+		protocol: "http",
+		addr:     "127.0.0.1:0",
+		serve:    func() error { served = true; return nil },
+		shutdown: func(context.Context) error { return nil },
+	}}
+	err := runListeners(ctx, listeners, drainConfig{}, time.Second)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("runListeners() error = %v, want context.Canceled", err)
+	}
+	if served {
+		t.Error("listener started after a signal during startup")
+	}
+}
+
+func TestRunListenersAddressInUse(t *testing.T) {
+	free, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	freeAddr := free.Addr().String()
+	free.Close()
+
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	defer taken.Close()
+	addr := taken.Addr().String()
+
+	var listeners []listener
+	app := newApp()
+	app.Action = func(ctx context.Context, cmd *cli.Command) (err error) {
+		listeners, err = buildListeners(cmd, http.NotFoundHandler())
+		return err
+	}
+	args := []string{"rclone_exporter", "--web.listen-address=" + freeAddr, "--web.listen-address=" + addr}
+	if err := app.Run(context.Background(), args); err != nil {
+		t.Fatalf("buildListeners() error = %v", err)
+	}
+
+	served := false
+	for i := range listeners {
+		listeners[i].serve = func() error { served = true; return nil }
+	}
+	err = runListeners(context.Background(), listeners, drainConfig{}, time.Second)
+	if !errors.Is(err, syscall.EADDRINUSE) || !strings.Contains(err.Error(), "address "+addr+" already in use") {
+		t.Errorf("runListeners() error = %v, want address %s already in use", err, addr)
+	}
+	if served {
+		t.Error("listener served although an address was taken")
+	}
+
+	// The address bound before the failure must be released again
+	ln, err := net.Listen("tcp", freeAddr)
+	if err != nil {
+		t.Fatalf("first address still bound after the failed start: %v", err)
+	}
+	ln.Close()
+}
+
+func TestRunListenersKeepsBackgroundWorkDuringDrain(t *testing.T) {
+	// Mirror main: the startup context ends with the first signal, background work does not
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	background, stopBackground := context.WithCancel(context.WithoutCancel(ctx))
+	defer stopBackground()
+
+	var collected atomic.Int64
+	go func() {
+		ticker := time.NewTicker(5 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-background.Done():
+				return
+			case <-ticker.C:
+				collected.Add(1)
+			}
+		}
+	}()
+
+	serving := make(chan struct{})
+	shutdown := make(chan struct{})
+	listeners := []listener{{
+		protocol: "http",
+		addr:     "test",
+		serve:    func() error { close(serving); <-shutdown; return http.ErrServerClosed },
+		shutdown: func(context.Context) error { close(shutdown); return nil },
+	}}
+
+	var duringDrain int64
+	drain := drainConfig{
+		start: func() {
+			// Let the collector run for a while after the signal before checking on it
+			time.Sleep(50 * time.Millisecond)
+			duringDrain = collected.Load()
+			time.Sleep(50 * time.Millisecond)
+			duringDrain = collected.Load() - duringDrain
+		},
+		period: 10 * time.Millisecond,
+		stop:   stopBackground,
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- runListeners(ctx, listeners, drain, time.Second) }()
+	<-serving
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatalf("Kill() error = %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("runListeners() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("runListeners did not return after the signal")
+	}
+	if duringDrain == 0 {
+		t.Error("background collector stopped during the drain")
+	}
+	if background.Err() == nil {
+		t.Error("background context still running after the drain")
+	}
+}
+
+func TestDrainListeners(t *testing.T) {
+	var started int
+	drain := drainConfig{start: func() { started++ }, period: 50 * time.Millisecond}
+
+	var serveErr error
+	begin := time.Now()
+	if stopped := drainListeners(drain, make(chan os.Signal), make(chan error), &serveErr); stopped != 0 {
+		t.Errorf("stopped = %d, want 0", stopped)
+	}
+	if started != 1 {
+		t.Errorf("drain started %d times, want 1", started)
+	}
+	if elapsed := time.Since(begin); elapsed < drain.period {
+		t.Errorf("drain ended after %v, before the %v period", elapsed, drain.period)
+	}
+
+	// A second signal cuts the drain short
+	drain.period = time.Hour
+	sigCh := make(chan os.Signal, 1)
+	sigCh <- syscall.SIGTERM
+	done := make(chan struct{})
+	go func() {
+		drainListeners(drain, sigCh, make(chan error), &serveErr)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("second signal did not end the drain")
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// sdNotify sends a state such as READY=1 to the systemd notify socket. It does
+// nothing outside of a Type=notify unit, where NOTIFY_SOCKET is unset.
+func sdNotify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+
+	// A leading @ names a socket in the abstract namespace
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("failed to connect to systemd notify socket: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, fmt.Errorf("failed to notify systemd: %w", err)
+	}
+	return true, nil
+}
+
+// notifyReady tells systemd the exporter accepts connections
+func notifyReady() {
+	if sent, err := sdNotify("READY=1"); err != nil {
+		log.Warn().Err(err).Msg("Failed to report readiness to systemd")
+	} else if sent {
+		log.Debug().Msg("Reported readiness to systemd")
+	}
+}
+
+// notifyStopping tells systemd the exporter is shutting down
+func notifyStopping() {
+	if _, err := sdNotify("STOPPING=1"); err != nil {
+		log.Warn().Err(err).Msg("Failed to report shutdown to systemd")
+	}
+}
+
+// watchdogInterval returns how often to ping the systemd watchdog: half of
+// WATCHDOG_USEC, or 0 when the watchdog is disabled or meant for another process
+func watchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+
+	return time.Duration(usec) * time.Microsecond / 2
+}
+
+// startWatchdog pings the systemd watchdog until the returned function is called
+func startWatchdog() (stop func()) {
+	interval := watchdogInterval()
+	if interval <= 0 || os.Getenv("NOTIFY_SOCKET") == "" {
+		return func() {}
+	}
+
+	log.Info().Dur("interval", interval).Msg("Pinging the systemd watchdog")
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := sdNotify("WATCHDOG=1"); err != nil {
+					log.Warn().Err(err).Msg("Failed to ping the systemd watchdog")
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// notifySocket listens on a systemd-style notify socket and points NOTIFY_SOCKET at it
+func notifySocket(t *testing.T) *net.UnixConn {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", path)
+	return conn
+}
+
+// readNotification returns the next state sent to the socket, or "" after a short wait
+func readNotification(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+	buf := make([]byte, 256)
+	conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	n, err := conn.Read(buf)
+	if err != nil {
+		return ""
+	}
+	return string(buf[:n])
+}
+
+func TestSdNotify(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if sent, err := sdNotify("READY=1"); sent || err != nil {
+		t.Errorf("sdNotify() without NOTIFY_SOCKET = %v, %v, want false, nil", sent, err)
+	}
+
+	conn := notifySocket(t)
+	if sent, err := sdNotify("READY=1"); !sent || err != nil {
+		t.Fatalf("sdNotify() = %v, %v, want true, nil", sent, err)
+	}
+	if got := readNotification(t, conn); got != "READY=1" {
+		t.Errorf("notification = %q, want READY=1", got)
+	}
+}
+
+func TestRunListenersNotifiesReadyAfterListening(t *testing.T) {
+	conn := notifySocket(t)
+
+	// Serving fails right away, but only after every address is bound
+	listeners := []listener{{
+		protocol: "http",
+		addr:     "127.0.0.1:0",
+		listen:   func() error { return nil },
+		serve:    func() error { return errors.New("boom") },
+		shutdown: func(context.Context) error { return nil },
+	}}
+	if err := runListeners(context.Background(), listeners, drainConfig{}, time.Second); err == nil {
+		t.Fatal("runListeners() error = nil, want the serve error")
+	}
+	if got := readNotification(t, conn); got != "READY=1" {
+		t.Errorf("notification = %q, want READY=1", got)
+	}
+
+	// A failed bind never reports readiness
+	listeners[0].listen = func() error { return errors.New("address already in use") }
+	if err := runListeners(context.Background(), listeners, drainConfig{}, time.Second); err == nil {
+		t.Fatal("runListeners() error = nil, want the listen error")
+	}
+	if got := readNotification(t, conn); got != "" {
+		t.Errorf("notification after a failed bind = %q, want none", got)
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	tests := []struct {
+		usec, pid string
+		want      time.Duration
+	}{
+		{"", "", 0},
+		{"abc", "", 0},
+		{"10000000", "", 5 * time.Second},
+		{"10000000", strconv.Itoa(os.Getpid()), 5 * time.Second},
+		{"10000000", "1", 0},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("WATCHDOG_USEC", tt.usec)
+		t.Setenv("WATCHDOG_PID", tt.pid)
+		if got := watchdogInterval(); got != tt.want {
+			t.Errorf("watchdogInterval() with WATCHDOG_USEC=%q WATCHDOG_PID=%q = %v, want %v", tt.usec, tt.pid, got, tt.want)
+		}
+	}
+}
+
+func TestWatchdogPings(t *testing.T) {
+	conn := notifySocket(t)
+	t.Setenv("WATCHDOG_USEC", "100000")
+	t.Setenv("WATCHDOG_PID", "")
+
+	stop := startWatchdog()
+	defer stop()
+	if got := readNotification(t, conn); got != "WATCHDOG=1" {
+		t.Errorf("notification = %q, want WATCHDOG=1", got)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/crazyuploader/rclone_exporter/internal/exporter"
+	"github.com/crazyuploader/rclone_exporter/internal/rclone"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+// logLevelFlags are the log level switches in the order getLogLevel gives them precedence
+var logLevelFlags = []string{"log.trace", "log.debug", "log.warn", "log.error"}
+
+// validateFlags checks flags that only make sense together, reporting every problem at
+// once. Conflicting log levels only warn, since logging picks one of them anyway.
+func validateFlags(cmd *cli.Command) error {
+	if set := setLogLevelFlags(cmd); len(set) > 1 {
+		log.Warn().
+			Strs("flags", set).
+			Str("using", set[0]).
+			Msg("Conflicting log level flags set, the most verbose one wins")
+	}
+
+	var errs []error
+
+	certFile, keyFile := cmd.String("web.tls-cert-file"), cmd.String("web.tls-key-file")
+	if (certFile == "") != (keyFile == "") {
+		errs = append(errs, fmt.Errorf("both --web.tls-cert-file and --web.tls-key-file must be set to enable TLS"))
+	}
+	if cmd.Bool("web.enable-http3") && (certFile == "" || keyFile == "") {
+		errs = append(errs, fmt.Errorf("--web.enable-http3 requires --web.tls-cert-file and --web.tls-key-file"))
+	}
+
+	if cmd.Bool("web.enable-rclone-config") && cmd.String("web.admin-token") == "" {
+		errs = append(errs, fmt.Errorf("--web.enable-rclone-config requires --web.admin-token"))
+	}
+
+	switch mode := cmd.String("scrape.mode"); mode {
+	case exporter.ScrapeModePush:
+	case exporter.ScrapeModePull:
+		if cmd.String("rclone.remotes-file") != "" {
+			errs = append(errs, fmt.Errorf("--rclone.remotes-file requires --scrape.mode=%s", exporter.ScrapeModePush))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("invalid --scrape.mode %q: must be %q or %q", mode, exporter.ScrapeModePull, exporter.ScrapeModePush))
+	}
+
+	if _, err := exporter.ParseErrorHandling(cmd.String("metrics.error-handling")); err != nil {
+		errs = append(errs, fmt.Errorf("invalid --metrics.error-handling: %w", err))
+	}
+
+	if timeout := cmd.Duration("scrape.timeout"); timeout < 0 {
+		errs = append(errs, fmt.Errorf("invalid --scrape.timeout: must not be negative, got %s", timeout))
+	}
+
+	for _, code := range cmd.IntSlice("rclone.success-exit-codes") {
+		if code < 1 || code > 255 {
+			errs = append(errs, fmt.Errorf("invalid --rclone.success-exit-codes: %d is not a failure exit code (1-255)", code))
+		}
+	}
+
+	if _, err := rclone.ParseTraversal(cmd.String("rclone.traversal")); err != nil {
+		errs = append(errs, fmt.Errorf("invalid --rclone.traversal: %w", err))
+	}
+	if err := rclone.ValidateSizeExtraArgs(cmd.StringSlice("rclone.size-extra")); err != nil {
+		errs = append(errs, fmt.Errorf("invalid --rclone.size-extra: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// setLogLevelFlags returns the log level flags that are enabled, most verbose first
+func setLogLevelFlags(cmd *cli.Command) []string {
+	var set []string
+	for _, name := range logLevelFlags {
+		if cmd.Bool(name) {
+			set = append(set, "--"+name)
+		}
+	}
+	return set
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v3"
+)
+
+// runValidation parses args with the real flag set and runs validateFlags on them
+func runValidation(t *testing.T, args ...string) (logLevels []string, err error) {
+	t.Helper()
+	app := newApp()
+	app.Action = func(ctx context.Context, cmd *cli.Command) error {
+		logLevels = setLogLevelFlags(cmd)
+		return validateFlags(cmd)
+	}
+	err = app.Run(context.Background(), append([]string{"rclone_exporter"}, args...))
+	return logLevels, err
+}
+
+func TestValidateFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr []string
+	}{
+		{"defaults", nil, nil},
+		{"complete TLS with HTTP/3", []string{"--web.tls-cert-file=c.pem", "--web.tls-key-file=k.pem", "--web.enable-http3"}, nil},
+		{"cert without key", []string{"--web.tls-cert-file=c.pem"}, []string{"both --web.tls-cert-file and --web.tls-key-file"}},
+		{"HTTP/3 without TLS", []string{"--web.enable-http3"}, []string{"--web.enable-http3 requires"}},
+		{"rclone config without token", []string{"--web.enable-rclone-config"}, []string{"requires --web.admin-token"}},
+		{"remotes file in pull mode", []string{"--rclone.remotes-file=remotes.txt"}, []string{"--rclone.remotes-file requires"}},
+		{"unknown scrape mode", []string{"--scrape.mode=poll"}, []string{`invalid --scrape.mode "poll"`}},
+		{"success exit code 0", []string{"--rclone.success-exit-codes=0"}, []string{"0 is not a failure exit code"}},
+		{"no-traverse with size passthrough", []string{"--rclone.traversal=no-traverse", "--rclone.size-extra=--max-backlog=1000"}, nil},
+		{"negative scrape timeout", []string{"--scrape.timeout=-1s"}, []string{"invalid --scrape.timeout"}},
+		{"unknown metrics error handling", []string{"--metrics.error-handling=ignore"}, []string{"invalid --metrics.error-handling"}},
+		{"metrics error handling", []string{"--metrics.error-handling=http-error"}, nil},
+		{"unknown traversal", []string{"--rclone.traversal=walk"}, []string{"invalid --rclone.traversal"}},
+		{"traversal in size passthrough", []string{"--rclone.size-extra=--no-traverse"}, []string{"invalid --rclone.size-extra"}},
+		{
+			"several problems",
+			[]string{"--web.tls-key-file=k.pem", "--scrape.mode=poll"},
+			[]string{"both --web.tls-cert-file", "invalid --scrape.mode"},
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := runValidation(t, tt.args...)
+		if len(tt.wantErr) == 0 {
+			if err != nil {
+				t.Errorf("%s: validateFlags() error = %v, want nil", tt.name, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s: validateFlags() error = nil, want %q", tt.name, tt.wantErr)
+			continue
+		}
+		for _, want := range tt.wantErr {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: error %q does not mention %q", tt.name, err, want)
+			}
+		}
+	}
+}
+
+func TestConflictingLogLevelsOnlyWarn(t *testing.T) {
+	set, err := runValidation(t, "--log.error", "--log.debug")
+	if err != nil {
+		t.Fatalf("validateFlags() error = %v, want conflicting log levels to only warn", err)
+	}
+	if len(set) != 2 || set[0] != "--log.debug" || set[1] != "--log.error" {
+		t.Errorf("setLogLevelFlags() = %v, want [--log.debug --log.error]", set)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+
+	"github.com/crazyuploader/rclone_exporter/internal/rclone"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+)
+
+// watchRcloneConfig clears the remote type cache whenever the rclone config file
+// changes and counts the changes in rclone_exporter_config_changes_total
+func watchRcloneConfig(ctx context.Context, client rclone.Client, registry prometheus.Registerer) error {
+	path, err := client.ConfigFile()
+	if err != nil {
+		return err
+	}
+
+	configChanges := prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "rclone_exporter",
+			Name:      "config_changes_total",
+			Help:      "Number of rclone config file changes detected",
+		},
+	)
+	registry.MustRegister(configChanges)
+
+	err = rclone.WatchConfigFile(ctx, path, func() {
+		configChanges.Inc()
+		cleared := client.ClearCache()
+		log.Info().
+			Str("file", path).
+			Int("cleared_entries", cleared).
+			Msg("Rclone config changed, cleared remote type cache")
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("file", path).Msg("Watching rclone config file for changes")
+	return nil
+}
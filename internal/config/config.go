@@ -0,0 +1,103 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.yaml.in/yaml/v2"
+)
+
+// File represents the optional YAML configuration file passed via --config.file
+type File struct {
+	Metrics MetricsConfig           `yaml:"metrics"`
+	Remotes map[string]RemoteConfig `yaml:"remotes"`
+}
+
+// RemoteConfig holds settings for one rclone remote, keyed by remote name without the colon
+type RemoteConfig struct {
+	// Labels are static labels exported on rclone_remote_meta for the remote,
+	// e.g. team or cost_center
+	Labels map[string]string `yaml:"labels"`
+
+	// Env holds environment variables set only for rclone runs against the remote,
+	// e.g. RCLONE_SFTP_SSH for a remote behind a jump host
+	Env map[string]string `yaml:"env"`
+}
+
+// envNameRegex matches valid environment variable names
+var envNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// MetricsConfig holds settings affecting how metrics are described
+type MetricsConfig struct {
+	// Help overrides the HELP text of metrics keyed by full metric name,
+	// e.g. "rclone_remote_size_bytes"
+	Help map[string]string `yaml:"help"`
+}
+
+// RemoteLabels returns the configured labels keyed by remote name. A trailing
+// colon on the remote name in the file is ignored.
+func (f *File) RemoteLabels() map[string]map[string]string {
+	labels := make(map[string]map[string]string)
+	for name, remote := range f.Remotes {
+		if len(remote.Labels) > 0 {
+			labels[strings.TrimSuffix(name, ":")] = remote.Labels
+		}
+	}
+	return labels
+}
+
+// RemoteEnv returns the configured per-remote environment keyed by remote name. A
+// trailing colon on the remote name in the file is ignored.
+func (f *File) RemoteEnv() map[string]map[string]string {
+	env := make(map[string]map[string]string)
+	for name, remote := range f.Remotes {
+		if len(remote.Env) > 0 {
+			env[strings.TrimSuffix(name, ":")] = remote.Env
+		}
+	}
+	return env
+}
+
+// validate checks settings the YAML schema cannot express
+func (f *File) validate() error {
+	names := make([]string, 0, len(f.Remotes))
+	for name := range f.Remotes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for key := range f.Remotes[name].Env {
+			if !envNameRegex.MatchString(key) {
+				return fmt.Errorf("remote %q: invalid environment variable name %q", name, key)
+			}
+		}
+	}
+	return nil
+}
+
+// Load reads and parses the configuration file. An empty path returns an empty configuration.
+func Load(path string) (*File, error) {
+	cfg := &File{}
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file '%s': %w", path, err)
+	}
+
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file '%s': %w", path, err)
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file '%s': %w", path, err)
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadEmptyPath(t *testing.T) {
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load(\"\") error = %v", err)
+	}
+	if cfg == nil || len(cfg.Metrics.Help) != 0 {
+		t.Errorf("Load(\"\") = %+v, want empty config", cfg)
+	}
+}
+
+func TestLoadHelpOverrides(t *testing.T) {
+	path := writeConfig(t, `
+metrics:
+  help:
+    rclone_remote_size_bytes: "Custom help"
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := cfg.Metrics.Help["rclone_remote_size_bytes"]; got != "Custom help" {
+		t.Errorf("help override = %q, want %q", got, "Custom help")
+	}
+}
+
+func TestLoadRejectsUnknownFields(t *testing.T) {
+	path := writeConfig(t, `
+metrics:
+  hepl:
+    rclone_remote_size_bytes: "Custom help"
+`)
+
+	if _, err := Load(path); err == nil {
+		t.Error("Load() with unknown field succeeded, want error")
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yml")); err == nil {
+		t.Error("Load() of missing file succeeded, want error")
+	}
+}
+
+func TestLoadRemoteLabels(t *testing.T) {
+	path := writeConfig(t, `
+remotes:
+  "gdrive:":
+    labels:
+      team: data
+      cost_center: "42"
+  s3: {}
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	labels := cfg.RemoteLabels()
+	if got := labels["gdrive"]["team"]; got != "data" {
+		t.Errorf("gdrive team label = %q, want %q", got, "data")
+	}
+	if _, ok := labels["s3"]; ok {
+		t.Error("remote without labels returned an entry")
+	}
+}
+
+func TestLoadRemoteEnv(t *testing.T) {
+	path := writeConfig(t, `
+remotes:
+  "bastion:":
+    env:
+      RCLONE_SFTP_SSH: ssh -J jump.example.com
+  s3:
+    labels:
+      team: data
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	env := cfg.RemoteEnv()
+	if got := env["bastion"]["RCLONE_SFTP_SSH"]; got != "ssh -J jump.example.com" {
+		t.Errorf("bastion RCLONE_SFTP_SSH = %q, want %q", got, "ssh -J jump.example.com")
+	}
+	if _, ok := env["s3"]; ok {
+		t.Error("remote without env returned an entry")
+	}
+}
+
+func TestLoadRejectsInvalidEnvName(t *testing.T) {
+	path := writeConfig(t, `
+remotes:
+  bastion:
+    env:
+      "BAD-NAME": value
+`)
+
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "BAD-NAME") {
+		t.Errorf("Load() error = %v, want invalid environment variable name", err)
+	}
+}
@@ -0,0 +1,148 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+)
+
+const webhookTimeout = 10 * time.Second
+
+// failureTracker counts consecutive probe failures per remote
+type failureTracker struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// newFailureTracker creates an empty failure tracker
+func newFailureTracker() *failureTracker {
+	return &failureTracker{counts: make(map[string]int)}
+}
+
+// recordFailure increments and returns the consecutive failure count of a remote
+func (t *failureTracker) recordFailure(remote string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.counts[remote]++
+	return t.counts[remote]
+}
+
+// recordSuccess resets the consecutive failure count of a remote
+func (t *failureTracker) recordSuccess(remote string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.counts, remote)
+}
+
+// webhookPayload is the JSON body posted to the alert webhook
+type webhookPayload struct {
+	Remote              string `json:"remote"`
+	Error               string `json:"error"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	Timestamp           string `json:"timestamp"`
+}
+
+// alertRemote reports whether failures of a remote should trigger webhook alerts
+func (e *Exporter) alertRemote(remote string) bool {
+	if e.config.AlertWebhookURL == "" {
+		return false
+	}
+
+	if len(e.config.AlertRemotes) == 0 {
+		return true
+	}
+
+	remoteName, _ := parseRemoteName(remote)
+	for _, r := range e.config.AlertRemotes {
+		if r == remote || r == remoteName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// recordProbeResult updates the failure streak of a remote, fires the webhook once
+// the streak reaches the configured threshold, and returns the new streak length
+func (e *Exporter) recordProbeResult(remote, remoteName, remoteType string, probeErr error) int {
+	failures := 0
+	if probeErr == nil {
+		e.failures.recordSuccess(remote)
+	} else {
+		failures = e.failures.recordFailure(remote)
+	}
+
+	// The type label changes once the remote type is detected, so drop the old series
+	e.consecutiveFailures.DeletePartialMatch(prometheus.Labels{"remote": remote})
+	e.consecutiveFailures.WithLabelValues(remote, remoteName, remoteType).Set(float64(failures))
+	if probeErr == nil {
+		return 0
+	}
+	if failures != e.config.AlertFailureThreshold || !e.alertRemote(remote) {
+		return failures
+	}
+
+	payload := webhookPayload{
+		Remote:              remote,
+		Error:               probeErr.Error(),
+		ConsecutiveFailures: failures,
+		Timestamp:           time.Now().UTC().Format(time.RFC3339),
+	}
+
+	// Deliver asynchronously so a slow webhook never delays probe responses
+	go func() {
+		if err := e.sendWebhook(payload); err != nil {
+			log.Error().
+				Err(err).
+				Str("remote", remote).
+				Int("consecutive_failures", failures).
+				Msg("Failed to deliver alert webhook")
+			return
+		}
+
+		log.Info().
+			Str("remote", remote).
+			Int("consecutive_failures", failures).
+			Msg("Alert webhook delivered")
+	}()
+
+	return failures
+}
+
+// sendWebhook posts the payload to the configured webhook URL
+func (e *Exporter) sendWebhook(payload webhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.config.AlertWebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package exporter
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRecordProbeResultFiresWebhookOncePerStreak(t *testing.T) {
+	received := make(chan struct{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	e := NewExporterWithConfig(&fakeClient{}, Config{
+		AlertWebhookURL:       server.URL,
+		AlertFailureThreshold: 2,
+	})
+	defer e.Close()
+
+	probeErr := errors.New("probe failed")
+
+	// First streak: fires once at the threshold, not again on the third failure
+	e.recordProbeResult("remote:", "remote", "unknown", probeErr)
+	e.recordProbeResult("remote:", "remote", "unknown", probeErr)
+	e.recordProbeResult("remote:", "remote", "unknown", probeErr)
+	waitForWebhooks(t, received, 1)
+
+	// A success resets the streak so the next threshold fires again
+	e.recordProbeResult("remote:", "remote", "unknown", nil)
+	e.recordProbeResult("remote:", "remote", "unknown", probeErr)
+	assertNoWebhook(t, received)
+	e.recordProbeResult("remote:", "remote", "unknown", probeErr)
+	waitForWebhooks(t, received, 1)
+}
+
+func TestRecordProbeResultRespectsAlertRemotes(t *testing.T) {
+	received := make(chan struct{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+	}))
+	defer server.Close()
+
+	e := NewExporterWithConfig(&fakeClient{}, Config{
+		AlertWebhookURL:       server.URL,
+		AlertFailureThreshold: 1,
+		AlertRemotes:          []string{"critical"},
+	})
+	defer e.Close()
+
+	e.recordProbeResult("other:", "other", "unknown", errors.New("probe failed"))
+	assertNoWebhook(t, received)
+
+	e.recordProbeResult("critical:", "critical", "unknown", errors.New("probe failed"))
+	waitForWebhooks(t, received, 1)
+}
+
+func TestRecordProbeResultReplacesSeriesOnTypeChange(t *testing.T) {
+	e := NewExporter(&fakeClient{})
+	defer e.Close()
+
+	e.recordProbeResult("s3:", "s3", "unknown", errors.New("probe failed"))
+	e.recordProbeResult("s3:", "s3", "s3", errors.New("probe failed"))
+
+	if got := testutil.CollectAndCount(e.consecutiveFailures); got != 1 {
+		t.Fatalf("consecutive_failures series = %d, want 1", got)
+	}
+	if got := testutil.ToFloat64(e.consecutiveFailures.WithLabelValues("s3:", "s3", "s3")); got != 2 {
+		t.Errorf("consecutive_failures = %v, want 2", got)
+	}
+}
+
+func waitForWebhooks(t *testing.T, received <-chan struct{}, want int) {
+	t.Helper()
+	for i := 0; i < want; i++ {
+		select {
+		case <-received:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected %d webhook(s), got %d", want, i)
+		}
+	}
+	assertNoWebhook(t, received)
+}
+
+func assertNoWebhook(t *testing.T, received <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-received:
+		t.Fatal("unexpected webhook delivery")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
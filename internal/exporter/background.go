@@ -0,0 +1,222 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+)
+
+// Scrape modes selectable via --scrape.mode
+const (
+	ScrapeModePull = "pull" // rclone runs synchronously on each /probe request (default)
+	ScrapeModePush = "push" // A background scheduler probes remotes and /metrics serves the latest results
+)
+
+// backgroundResults holds the metrics of the latest background probe of each remote.
+// It is an unchecked collector because the remotes, and with them the series, change over time.
+type backgroundResults struct {
+	mu      sync.RWMutex
+	remotes map[string]*probeMetrics
+}
+
+// newBackgroundResults creates an empty result store
+func newBackgroundResults() *backgroundResults {
+	return &backgroundResults{remotes: make(map[string]*probeMetrics)}
+}
+
+// set replaces the stored metrics of a remote
+func (b *backgroundResults) set(remote string, m *probeMetrics) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.remotes[remote] = m
+}
+
+// retain drops the results of remotes that are no longer probed
+func (b *backgroundResults) retain(remotes []string) {
+	keep := make(map[string]bool, len(remotes))
+	for _, remote := range remotes {
+		keep[remote] = true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for remote := range b.remotes {
+		if !keep[remote] {
+			delete(b.remotes, remote)
+		}
+	}
+}
+
+// Describe implements prometheus.Collector
+func (b *backgroundResults) Describe(chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector
+func (b *backgroundResults) Collect(ch chan<- prometheus.Metric) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, m := range b.remotes {
+		for _, c := range m.remoteCollectors() {
+			c.Collect(ch)
+		}
+	}
+}
+
+// StartBackgroundProbes probes remotes every interval until ctx is done and serves the
+// latest results on /metrics, so scrapes never wait for rclone. An empty remotes list
+// probes the remotes of Config.RemotesFile, or every configured remote, re-read on each round. Each probe of a round starts
+// after a random delay of up to jitter, so remotes on one backend are not hit at once.
+func (e *Exporter) StartBackgroundProbes(ctx context.Context, remotes []string, interval, jitter time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("interval must be positive, got %s", interval)
+	}
+	if jitter < 0 || jitter > interval {
+		return fmt.Errorf("jitter must be between 0 and the interval (%s), got %s", interval, jitter)
+	}
+	if path := e.config.RemotesFile; path != "" {
+		if len(remotes) > 0 {
+			return fmt.Errorf("a remotes file and a list of remotes are mutually exclusive")
+		}
+		if _, err := e.readRemotesFile(path); err != nil {
+			return err
+		}
+	}
+	for _, remote := range remotes {
+		if err := e.validateRemote(remote); err != nil {
+			return fmt.Errorf("invalid remote %q: %w", remote, err)
+		}
+		if remote == ProbeAllRemotes {
+			return fmt.Errorf("invalid remote %q: leave the list empty to probe all remotes", remote)
+		}
+	}
+
+	results := newBackgroundResults()
+	if err := e.registerer.Register(results); err != nil {
+		return fmt.Errorf("failed to register background probe results: %w", err)
+	}
+
+	go func() {
+		defer e.registerer.Unregister(results)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			e.backgroundRound(ctx, results, remotes, jitter)
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	log.Info().
+		Strs("remotes", remotes).
+		Dur("interval", interval).
+		Dur("jitter", jitter).
+		Msg("Background probes started")
+	return nil
+}
+
+// jitterDelay returns a uniformly distributed delay in [0, jitter)
+func jitterDelay(jitter time.Duration) time.Duration {
+	if jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(jitter)))
+}
+
+// backgroundRound probes each remote once, with the same fan-out cap as remote=all,
+// and stores the results as they complete
+func (e *Exporter) backgroundRound(ctx context.Context, results *backgroundResults, remotes []string, jitter time.Duration) {
+	// Keep serving the previous results while the backends are under maintenance
+	if e.Maintenance() {
+		log.Debug().Msg("Skipping background probe round in maintenance mode")
+		return
+	}
+
+	if len(remotes) == 0 && e.config.RemotesFile != "" {
+		var err error
+		remotes, err = e.readRemotesFile(e.config.RemotesFile)
+		if err != nil {
+			// Keep serving the previous results until the file is fixed
+			e.scrapeErrorsTotal.Inc()
+			log.Error().Err(err).Msg("Failed to read remotes file for background probes")
+			return
+		}
+	} else if len(remotes) == 0 {
+		infos, err := e.rcloneClient.ListRemotes()
+		if err != nil {
+			e.scrapeErrorsTotal.Inc()
+			log.Error().Err(err).Msg("Failed to list remotes for background probes")
+			return
+		}
+		remotes = remoteNames(infos)
+	}
+
+	start := time.Now()
+	fanOut := make(chan struct{}, MaxProbeAllConcurrency)
+	var wg sync.WaitGroup
+	for _, remote := range remotes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// Spread the start times before queueing for a slot
+			if delay := jitterDelay(jitter); delay > 0 {
+				timer := time.NewTimer(delay)
+				defer timer.Stop()
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case fanOut <- struct{}{}:
+				defer func() { <-fanOut }()
+			case <-ctx.Done():
+				return
+			}
+
+			if !e.acquireProbeSlot(ctx.Done()) {
+				return
+			}
+			defer e.releaseProbeSlot()
+
+			m := e.newProbeMetrics()
+			opts := probeOptions{mode: ProbeModeSize, format: ProbeFormatPrometheus, countOnly: e.config.CountOnly, received: time.Now()}
+			if err := e.probeRemote(m, remote, opts); err != nil {
+				e.scrapeErrorsTotal.Inc()
+				log.Warn().
+					Err(err).
+					Str("remote", remote).
+					Msg("Background rclone probe failed")
+			}
+			results.set(remote, m)
+			e.invalidateRenderedMetrics()
+		}()
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return
+	}
+	results.retain(remotes)
+	e.invalidateRenderedMetrics()
+
+	log.Debug().
+		Int("remotes", len(remotes)).
+		Dur("duration", time.Since(start)).
+		Msg("Background probe round completed")
+}
@@ -0,0 +1,223 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/crazyuploader/rclone_exporter/internal/rclone"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// scrapeMetrics returns the /metrics output of the exporter
+func scrapeMetrics(e *Exporter) string {
+	rec := httptest.NewRecorder()
+	e.MetricsHandler(promhttp.HandlerOpts{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	return rec.Body.String()
+}
+
+func TestBackgroundProbesServeLatestResults(t *testing.T) {
+	client := &fakeClient{
+		remotes: []rclone.RemoteInfo{{Name: "good", Type: "s3"}, {Name: "bad", Type: "drive"}},
+		sizes:   map[string]*rclone.RcloneSizeOutput{"good:": {Count: 3, Bytes: 42}},
+		types:   map[string]string{"good": "s3", "bad": "drive"},
+	}
+	e := NewExporter(client)
+	defer e.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := e.StartBackgroundProbes(ctx, nil, time.Hour, 0); err != nil {
+		t.Fatalf("StartBackgroundProbes() error = %v", err)
+	}
+
+	want := []string{
+		`rclone_probe_success{remote="bad:",remote_name="bad",remote_type="drive"} 0`,
+		`rclone_probe_success{remote="good:",remote_name="good",remote_type="s3"} 1`,
+		`rclone_remote_size_bytes{path="/",remote="good:",remote_name="good",remote_type="s3"} 42`,
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		body := scrapeMetrics(e)
+		missing := ""
+		for _, line := range want {
+			if !strings.Contains(body, line) {
+				missing = line
+				break
+			}
+		}
+		if missing == "" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("/metrics missing %q\n%s", missing, body)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Serving /metrics never runs rclone
+	client.mu.Lock()
+	calls := client.sizeCalls
+	client.mu.Unlock()
+	scrapeMetrics(e)
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if client.sizeCalls != calls {
+		t.Errorf("size calls = %d after scrape, want %d", client.sizeCalls, calls)
+	}
+}
+
+func TestBackgroundRoundDropsRemovedRemotes(t *testing.T) {
+	client := &fakeClient{
+		sizes: map[string]*rclone.RcloneSizeOutput{"a:": {Bytes: 1}, "b:": {Bytes: 2}},
+	}
+	e := NewExporter(client)
+	defer e.Close()
+
+	results := newBackgroundResults()
+	e.registerer.MustRegister(results)
+
+	e.backgroundRound(context.Background(), results, []string{"a:", "b:"}, 0)
+	if body := scrapeMetrics(e); !strings.Contains(body, `remote="b:"`) {
+		t.Fatalf("/metrics missing remote b:\n%s", body)
+	}
+
+	e.backgroundRound(context.Background(), results, []string{"a:"}, 0)
+	body := scrapeMetrics(e)
+	if strings.Contains(body, `rclone_remote_size_bytes{path="/",remote="b:"`) {
+		t.Errorf("/metrics still reports removed remote b:\n%s", body)
+	}
+	if !strings.Contains(body, `rclone_remote_size_bytes{path="/",remote="a:",remote_name="a",remote_type="unknown"} 1`) {
+		t.Errorf("/metrics missing remote a:\n%s", body)
+	}
+}
+
+func TestBackgroundRoundSkippedInMaintenance(t *testing.T) {
+	client := &fakeClient{sizes: map[string]*rclone.RcloneSizeOutput{"a:": {Bytes: 1}}}
+	e := NewExporter(client)
+	defer e.Close()
+
+	results := newBackgroundResults()
+	e.registerer.MustRegister(results)
+	e.backgroundRound(context.Background(), results, []string{"a:"}, 0)
+
+	e.SetMaintenance(true)
+	client.sizes["a:"] = &rclone.RcloneSizeOutput{Bytes: 2}
+	e.backgroundRound(context.Background(), results, []string{"a:"}, 0)
+
+	if client.sizeCalls != 1 {
+		t.Errorf("size calls = %d, want 1", client.sizeCalls)
+	}
+	if body := scrapeMetrics(e); !strings.Contains(body, `rclone_remote_size_bytes{path="/",remote="a:",remote_name="a",remote_type="unknown"} 1`) {
+		t.Errorf("/metrics lost the result from before maintenance\n%s", body)
+	}
+}
+
+func TestStartBackgroundProbesValidation(t *testing.T) {
+	e := NewExporter(&fakeClient{})
+	defer e.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tests := []struct {
+		name     string
+		remotes  []string
+		interval time.Duration
+		jitter   time.Duration
+	}{
+		{"zero interval", nil, 0, 0},
+		{"invalid remote", []string{"bad remote;"}, time.Minute, 0},
+		{"all", []string{ProbeAllRemotes}, time.Minute, 0},
+		{"negative jitter", nil, time.Minute, -time.Second},
+		{"jitter above interval", nil, time.Minute, 2 * time.Minute},
+	}
+	for _, tt := range tests {
+		if err := e.StartBackgroundProbes(ctx, tt.remotes, tt.interval, tt.jitter); err == nil {
+			t.Errorf("%s: StartBackgroundProbes() error = nil, want error", tt.name)
+		}
+	}
+}
+
+func TestJitterDelayDistribution(t *testing.T) {
+	if got := jitterDelay(0); got != 0 {
+		t.Errorf("jitterDelay(0) = %s, want 0", got)
+	}
+
+	const (
+		jitter  = time.Second
+		samples = 10000
+		buckets = 10
+	)
+	var sum time.Duration
+	counts := make([]int, buckets)
+	for i := 0; i < samples; i++ {
+		delay := jitterDelay(jitter)
+		if delay < 0 || delay >= jitter {
+			t.Fatalf("jitterDelay(%s) = %s, want within [0, %s)", jitter, delay, jitter)
+		}
+		sum += delay
+		counts[int(delay*buckets/jitter)]++
+	}
+
+	// Uniform: the mean is near the middle and every tenth of the range is used
+	if mean := sum / samples; mean < 450*time.Millisecond || mean > 550*time.Millisecond {
+		t.Errorf("mean delay = %s, want about %s", mean, jitter/2)
+	}
+	for i, count := range counts {
+		if count < samples/buckets/2 {
+			t.Errorf("bucket %d has %d samples, want about %d", i, count, samples/buckets)
+		}
+	}
+}
+
+func TestBackgroundRoundSpreadsStarts(t *testing.T) {
+	var mu sync.Mutex
+	var starts []time.Time
+	client := &fakeClient{
+		sizes: map[string]*rclone.RcloneSizeOutput{},
+		onSize: func(string) {
+			mu.Lock()
+			defer mu.Unlock()
+			starts = append(starts, time.Now())
+		},
+	}
+	var remotes []string
+	for i := 0; i < MaxProbeAllConcurrency; i++ {
+		remote := fmt.Sprintf("remote%d:", i)
+		remotes = append(remotes, remote)
+		client.sizes[remote] = &rclone.RcloneSizeOutput{}
+	}
+	e := NewExporter(client)
+	defer e.Close()
+
+	const jitter = 200 * time.Millisecond
+	begin := time.Now()
+	e.backgroundRound(context.Background(), newBackgroundResults(), remotes, jitter)
+	elapsed := time.Since(begin)
+
+	if len(starts) != len(remotes) {
+		t.Fatalf("probes started = %d, want %d", len(starts), len(remotes))
+	}
+	// Probes start no later than the jitter allows, plus scheduling slack
+	if elapsed > jitter+time.Second {
+		t.Errorf("round took %s, want at most about %s", elapsed, jitter)
+	}
+	first, last := starts[0], starts[0]
+	for _, start := range starts {
+		if start.Before(first) {
+			first = start
+		}
+		if start.After(last) {
+			last = start
+		}
+	}
+	if last.Sub(first) == 0 {
+		t.Errorf("all probes started at the same time, want them spread")
+	}
+}
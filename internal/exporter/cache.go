@@ -0,0 +1,101 @@
+package exporter
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/crazyuploader/rclone_exporter/internal/rclone"
+)
+
+// cachedSize is a size probe result together with the time it was fetched
+type cachedSize struct {
+	output    rclone.RcloneSizeOutput
+	fetchedAt time.Time
+}
+
+// Size cache backends selectable via --cache.backend
+const (
+	CacheBackendMemory = "memory" // Results are kept in memory and lost on restart (default)
+	CacheBackendBolt   = "bolt"   // Results are stored in a bbolt file and survive restarts
+)
+
+// sizeCache stores size probe results keyed by remote and probe options
+type sizeCache interface {
+	get(key string) (cachedSize, bool)
+	set(key string, entry cachedSize)
+	close() error
+}
+
+// memorySizeCache is the in-memory sizeCache implementation
+type memorySizeCache struct {
+	mu      sync.RWMutex
+	entries map[string]cachedSize
+}
+
+// newMemorySizeCache creates an empty in-memory size cache
+func newMemorySizeCache() *memorySizeCache {
+	return &memorySizeCache{entries: make(map[string]cachedSize)}
+}
+
+// get returns the cached entry for key, if any
+func (c *memorySizeCache) get(key string) (cachedSize, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[key]
+	return entry, ok
+}
+
+// set stores entry under key
+func (c *memorySizeCache) set(key string, entry cachedSize) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = entry
+}
+
+// close releases the cache; the in-memory cache holds nothing to release
+func (c *memorySizeCache) close() error {
+	return nil
+}
+
+// SetCacheBackend replaces the size cache with the given backend. The bolt backend
+// stores results in the file at path, which is created if it does not exist.
+// It must be called before the exporter serves probes.
+func (e *Exporter) SetCacheBackend(backend, path string) error {
+	var cache sizeCache
+	switch backend {
+	case "", CacheBackendMemory:
+		cache = newMemorySizeCache()
+	case CacheBackendBolt:
+		if path == "" {
+			return fmt.Errorf("the %s cache backend needs a cache file path", CacheBackendBolt)
+		}
+		bolt, err := newBoltSizeCache(path)
+		if err != nil {
+			return err
+		}
+		cache = bolt
+	default:
+		return fmt.Errorf("unknown cache backend %q, must be %q or %q", backend, CacheBackendMemory, CacheBackendBolt)
+	}
+
+	if err := e.sizes.close(); err != nil {
+		return fmt.Errorf("failed to close the previous size cache: %w", err)
+	}
+	e.sizes = cache
+	return nil
+}
+
+// fresh reports whether a cached entry of the given age may still be served.
+// Entries expire after the cache TTL, and never outlive MaxResultAge when it is set.
+func (e *Exporter) fresh(age time.Duration) bool {
+	if age >= e.config.SizeCacheTTL {
+		return false
+	}
+	if e.config.MaxResultAge > 0 && age >= e.config.MaxResultAge {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,98 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltSizeBucket is the bucket holding size results in the bolt cache file
+var boltSizeBucket = []byte("sizes")
+
+// boltSizeEntry is the stored form of a cachedSize. rclone.RcloneSizeOutput does not
+// serialize its warning count, so the fields are spelled out.
+type boltSizeEntry struct {
+	Count     int64     `json:"count"`
+	Bytes     int64     `json:"bytes"`
+	Warnings  int       `json:"warnings,omitempty"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// boltSizeCache is a sizeCache persisted in a bbolt file, so results survive restarts
+// and do not have to be held in memory
+type boltSizeCache struct {
+	db *bolt.DB
+}
+
+// newBoltSizeCache opens or creates the bolt cache file at path
+func newBoltSizeCache(path string) (*boltSizeCache, error) {
+	// A second exporter on the same file fails instead of waiting for the lock forever
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cache file %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltSizeBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize cache file %s: %w", path, err)
+	}
+
+	return &boltSizeCache{db: db}, nil
+}
+
+// get returns the cached entry for key, if any. Unreadable entries count as a miss.
+func (c *boltSizeCache) get(key string) (cachedSize, bool) {
+	var stored boltSizeEntry
+	found := false
+	err := c.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(boltSizeBucket).Get([]byte(key))
+		if value == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(value, &stored)
+	})
+	if err != nil {
+		log.Warn().Err(err).Str("key", key).Msg("Failed to read cached size result")
+		return cachedSize{}, false
+	}
+	if !found {
+		return cachedSize{}, false
+	}
+
+	entry := cachedSize{fetchedAt: stored.FetchedAt}
+	entry.output.Count = stored.Count
+	entry.output.Bytes = stored.Bytes
+	entry.output.Warnings = stored.Warnings
+	return entry, true
+}
+
+// set stores entry under key. A failed write only costs a later cache miss.
+func (c *boltSizeCache) set(key string, entry cachedSize) {
+	value, err := json.Marshal(boltSizeEntry{
+		Count:     entry.output.Count,
+		Bytes:     entry.output.Bytes,
+		Warnings:  entry.output.Warnings,
+		FetchedAt: entry.fetchedAt,
+	})
+	if err == nil {
+		err = c.db.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket(boltSizeBucket).Put([]byte(key), value)
+		})
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("key", key).Msg("Failed to store cached size result")
+	}
+}
+
+// close closes the cache file
+func (c *boltSizeCache) close() error {
+	return c.db.Close()
+}
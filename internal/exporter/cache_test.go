@@ -0,0 +1,224 @@
+package exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crazyuploader/rclone_exporter/internal/rclone"
+)
+
+func TestSizeCacheHitWithinMaxResultAge(t *testing.T) {
+	client := &fakeClient{sizes: map[string]*rclone.RcloneSizeOutput{"remote:": {Count: 1, Bytes: 5}}}
+	e := NewExporterWithConfig(client, Config{SizeCacheTTL: time.Hour, MaxResultAge: time.Hour})
+	defer e.Close()
+
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=remote:", nil))
+		if !strings.Contains(rec.Body.String(), `rclone_remote_size_bytes{path="/",remote="remote:",remote_name="remote",remote_type="unknown"} 5`) {
+			t.Fatalf("probe %d missing size metric\n%s", i, rec.Body)
+		}
+	}
+
+	if client.sizeCalls != 1 {
+		t.Errorf("rclone size ran %d times, want 1", client.sizeCalls)
+	}
+}
+
+func TestSizeCacheRefreshesOverMaxResultAge(t *testing.T) {
+	client := &fakeClient{sizes: map[string]*rclone.RcloneSizeOutput{"remote:": {Count: 1, Bytes: 5}}}
+	e := NewExporterWithConfig(client, Config{SizeCacheTTL: time.Hour, MaxResultAge: 20 * time.Millisecond})
+	defer e.Close()
+
+	probe := func() string {
+		rec := httptest.NewRecorder()
+		e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=remote:", nil))
+		return rec.Body.String()
+	}
+
+	probe()
+	time.Sleep(30 * time.Millisecond)
+	body := probe()
+
+	if client.sizeCalls != 2 {
+		t.Errorf("rclone size ran %d times, want 2", client.sizeCalls)
+	}
+	if want := `rclone_probe_result_age_seconds{remote="remote:",remote_name="remote",remote_type="unknown"} 0`; !strings.Contains(body, want) {
+		t.Errorf("refreshed probe missing %q\n%s", want, body)
+	}
+}
+
+func TestSizeCacheDisabledByDefault(t *testing.T) {
+	client := &fakeClient{sizes: map[string]*rclone.RcloneSizeOutput{"remote:": {}}}
+	e := NewExporter(client)
+	defer e.Close()
+
+	for i := 0; i < 2; i++ {
+		e.ProbeHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/probe?remote=remote:", nil))
+	}
+
+	if client.sizeCalls != 2 {
+		t.Errorf("rclone size ran %d times, want 2", client.sizeCalls)
+	}
+}
+
+func TestCacheOnlyProbe(t *testing.T) {
+	client := &fakeClient{sizes: map[string]*rclone.RcloneSizeOutput{"remote:": {Count: 1, Bytes: 5}}}
+	e := NewExporterWithConfig(client, Config{SizeCacheTTL: time.Hour})
+	defer e.Close()
+
+	probe := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?"+query, nil))
+		return rec
+	}
+
+	// Cold cache: 503 without running rclone
+	if rec := probe("remote=remote:&cache=only"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("cold cache status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if client.sizeCalls != 0 {
+		t.Fatalf("cache-only probe ran rclone size %d times", client.sizeCalls)
+	}
+
+	// Warm the cache with a regular probe
+	if rec := probe("remote=remote:"); !strings.Contains(rec.Body.String(), `rclone_remote_cache_hit{remote="remote:",remote_name="remote",remote_type="unknown"} 0`) {
+		t.Errorf("regular probe missing cache_hit 0\n%s", rec.Body)
+	}
+
+	// Cache-only probes stay available while every slot is taken
+	for i := 0; i < MaxConcurrentProbes; i++ {
+		e.semaphore <- struct{}{}
+	}
+	rec := probe("remote=remote:&cache=only")
+	for i := 0; i < MaxConcurrentProbes; i++ {
+		<-e.semaphore
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("warm cache status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if want := `rclone_remote_cache_hit{remote="remote:",remote_name="remote",remote_type="unknown"} 1`; !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("cache-only probe missing %q\n%s", want, rec.Body)
+	}
+	if client.sizeCalls != 1 {
+		t.Errorf("rclone size ran %d times, want 1", client.sizeCalls)
+	}
+}
+
+func TestCacheOnlyRejectsUnsupportedCombinations(t *testing.T) {
+	e := NewExporterWithConfig(&fakeClient{}, Config{SizeCacheTTL: time.Hour})
+	defer e.Close()
+
+	for _, query := range []string{"remote=all&cache=only", "remote=remote:&cache=only&command=about", "remote=remote:&cache=bogus"} {
+		rec := httptest.NewRecorder()
+		e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?"+query, nil))
+		if probeStatus(rec) != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", query, probeStatus(rec), http.StatusBadRequest)
+		}
+	}
+}
+
+func TestBoltCacheSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	client := &fakeClient{sizes: map[string]*rclone.RcloneSizeOutput{"remote:": {Count: 3, Bytes: 42}}}
+
+	probe := func(e *Exporter) string {
+		rec := httptest.NewRecorder()
+		e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=remote:", nil))
+		return rec.Body.String()
+	}
+
+	first := NewExporterWithConfig(client, Config{SizeCacheTTL: time.Hour})
+	if err := first.SetCacheBackend(CacheBackendBolt, path); err != nil {
+		t.Fatalf("SetCacheBackend() error = %v", err)
+	}
+	probe(first)
+	first.Close()
+
+	second := NewExporterWithConfig(client, Config{SizeCacheTTL: time.Hour})
+	if err := second.SetCacheBackend(CacheBackendBolt, path); err != nil {
+		t.Fatalf("SetCacheBackend() after restart error = %v", err)
+	}
+	defer second.Close()
+
+	body := probe(second)
+	for _, want := range []string{
+		`rclone_remote_size_bytes{path="/",remote="remote:",remote_name="remote",remote_type="unknown"} 42`,
+		`rclone_remote_cache_hit{remote="remote:",remote_name="remote",remote_type="unknown"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("probe after restart missing %q\n%s", want, body)
+		}
+	}
+	if client.sizeCalls != 1 {
+		t.Errorf("rclone size ran %d times, want 1", client.sizeCalls)
+	}
+}
+
+func TestSetCacheBackendValidation(t *testing.T) {
+	e := NewExporter(&fakeClient{})
+	defer e.Close()
+
+	if err := e.SetCacheBackend("redis", ""); err == nil {
+		t.Error("SetCacheBackend(redis) error = nil, want unknown backend")
+	}
+	if err := e.SetCacheBackend(CacheBackendBolt, ""); err == nil {
+		t.Error("SetCacheBackend(bolt) without a path error = nil, want error")
+	}
+	if err := e.SetCacheBackend(CacheBackendMemory, ""); err != nil {
+		t.Errorf("SetCacheBackend(memory) error = %v", err)
+	}
+}
+
+func TestProbeNoCacheBypassesWarmCache(t *testing.T) {
+	client := &fakeClient{sizes: map[string]*rclone.RcloneSizeOutput{"remote:": {Count: 1, Bytes: 5}}}
+	e := NewExporterWithConfig(client, Config{SizeCacheTTL: time.Hour})
+	defer e.Close()
+
+	probe := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?"+query, nil))
+		return rec
+	}
+
+	probe("remote=remote:")
+	client.mu.Lock()
+	client.sizes["remote:"] = &rclone.RcloneSizeOutput{Count: 2, Bytes: 9}
+	client.mu.Unlock()
+
+	rec := probe("remote=remote:&nocache=true")
+	if client.sizeCalls != 2 {
+		t.Fatalf("rclone size ran %d times, want a fresh run despite the warm cache", client.sizeCalls)
+	}
+	for _, want := range []string{
+		`rclone_remote_size_bytes{path="/",remote="remote:",remote_name="remote",remote_type="unknown"} 9`,
+		`rclone_remote_cache_hit{remote="remote:",remote_name="remote",remote_type="unknown"} 0`,
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("nocache probe missing %q\n%s", want, rec.Body)
+		}
+	}
+	if len(client.invalidated) != 1 || client.invalidated[0] != "remote" {
+		t.Errorf("invalidated type cache entries = %v, want [remote]", client.invalidated)
+	}
+
+	// The fresh result replaced the cached one
+	rec = probe("remote=remote:&cache=only")
+	if !strings.Contains(rec.Body.String(), `rclone_remote_size_bytes{path="/",remote="remote:",remote_name="remote",remote_type="unknown"} 9`) {
+		t.Errorf("cache not updated by the nocache probe\n%s", rec.Body)
+	}
+	if client.sizeCalls != 2 {
+		t.Errorf("rclone size ran %d times, want 2", client.sizeCalls)
+	}
+
+	for _, query := range []string{"remote=remote:&nocache=maybe", "remote=remote:&nocache=true&cache=only"} {
+		if rec := probe(query); probeStatus(rec) != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", query, probeStatus(rec), http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package exporter
+
+import "sync"
+
+// sizeTracker remembers the byte total of the previous size probe of each remote
+type sizeTracker struct {
+	mu    sync.Mutex
+	bytes map[string]int64
+}
+
+// newSizeTracker creates an empty size tracker
+func newSizeTracker() *sizeTracker {
+	return &sizeTracker{bytes: make(map[string]int64)}
+}
+
+// record stores the byte total of a probe and returns the change since the previous
+// one. ok is false on the first probe of the key.
+func (t *sizeTracker) record(key string, bytes int64) (delta int64, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	previous, ok := t.bytes[key]
+	t.bytes[key] = bytes
+	return bytes - previous, ok
+}
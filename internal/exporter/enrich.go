@@ -0,0 +1,62 @@
+package exporter
+
+import (
+	"github.com/rs/zerolog/log"
+)
+
+// remoteEnricher records backend-specific metrics for a probed remote
+type remoteEnricher func(e *Exporter, m *probeMetrics, t probeTarget) error
+
+// remoteEnrichers maps remote types to their enrichment step
+var remoteEnrichers = map[string]remoteEnricher{
+	"s3":    (*Exporter).enrichS3,
+	"local": (*Exporter).enrichLocal,
+}
+
+// enrichRemote runs the enrichment step of the remote's type, if any.
+// Enrichment is best effort and never fails the probe.
+func (e *Exporter) enrichRemote(m *probeMetrics, t probeTarget) {
+	enrich, ok := remoteEnrichers[t.remoteType]
+	if !ok {
+		return
+	}
+
+	if err := enrich(e, m, t); err != nil {
+		log.Debug().
+			Err(err).
+			Str("remote", t.remote).
+			Str("remote_type", t.remoteType).
+			Msg("Failed to enrich remote metrics")
+	}
+}
+
+// enrichS3 records the region configured for an S3 remote
+func (e *Exporter) enrichS3(m *probeMetrics, t probeTarget) error {
+	region, err := t.client.GetRemoteOption(t.remoteName, "region")
+	if err != nil {
+		return err
+	}
+
+	// rclone treats an empty region as us-east-1
+	if region == "" {
+		region = "us-east-1"
+	}
+	m.s3RegionInfo.WithLabelValues(t.remote, t.remoteName, region).Set(1)
+	return nil
+}
+
+// enrichLocal records the filesystem type backing a local remote's path
+func (e *Exporter) enrichLocal(m *probeMetrics, t probeTarget) error {
+	path := t.remotePath
+	if path == "" {
+		path = "."
+	}
+
+	fsType, err := filesystemType(path)
+	if err != nil {
+		return err
+	}
+
+	m.localFilesystemInfo.WithLabelValues(t.remote, t.remoteName, path, fsType).Set(1)
+	return nil
+}
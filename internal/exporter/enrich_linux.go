@@ -0,0 +1,46 @@
+//go:build linux
+
+package exporter
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// filesystemNames maps statfs magic numbers to filesystem names
+var filesystemNames = map[int64]string{
+	0x0000EF53: "ext4", // Shared by ext2, ext3 and ext4
+	0x58465342: "xfs",
+	0x9123683E: "btrfs",
+	0x2FC12FC1: "zfs",
+	0xF2F52010: "f2fs",
+	0x01021994: "tmpfs",
+	0x858458F6: "ramfs",
+	0x794C7630: "overlay",
+	0x73717368: "squashfs",
+	0x65735546: "fuse",
+	0x00006969: "nfs",
+	0xFF534D42: "cifs",
+	0xFE534D42: "smb2",
+	0x00004D44: "vfat",
+	0x2011BAB0: "exfat",
+	0x5346544E: "ntfs",
+}
+
+// filesystemType returns the name of the filesystem holding path
+func filesystemType(path string) (string, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return "", fmt.Errorf("statfs %s: %w", path, err)
+	}
+
+	magic := int64(stat.Type)
+	if magic < 0 {
+		// Type is a signed 32-bit value on some architectures
+		magic = int64(uint32(stat.Type))
+	}
+	if name, ok := filesystemNames[magic]; ok {
+		return name, nil
+	}
+	return fmt.Sprintf("0x%x", magic), nil
+}
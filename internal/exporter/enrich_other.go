@@ -0,0 +1,12 @@
+//go:build !linux
+
+package exporter
+
+import (
+	"errors"
+)
+
+// filesystemType is only implemented on Linux
+func filesystemType(string) (string, error) {
+	return "", errors.New("filesystem type detection is not supported on this platform")
+}
@@ -0,0 +1,66 @@
+package exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/crazyuploader/rclone_exporter/internal/rclone"
+)
+
+func TestProbeEnrichesS3Region(t *testing.T) {
+	client := &fakeClient{
+		sizes:   map[string]*rclone.RcloneSizeOutput{"bucket:": {Count: 1, Bytes: 10}, "default:": {}},
+		types:   map[string]string{"bucket": "s3", "default": "s3"},
+		options: map[string]map[string]string{"bucket": {"region": "eu-west-1"}},
+	}
+
+	probe := func(config Config, remote string) string {
+		e := NewExporterWithConfig(client, config)
+		defer e.Close()
+
+		rec := httptest.NewRecorder()
+		e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote="+remote, nil))
+		return rec.Body.String()
+	}
+
+	if body := probe(Config{}, "bucket:"); strings.Contains(body, "rclone_s3_region_info{") {
+		t.Errorf("enrichment ran without being enabled\n%s", body)
+	}
+
+	enabled := Config{EnrichRemoteTypes: true}
+	want := `rclone_s3_region_info{region="eu-west-1",remote="bucket:",remote_name="bucket"} 1`
+	if body := probe(enabled, "bucket:"); !strings.Contains(body, want) {
+		t.Errorf("probe output missing %q\n%s", want, body)
+	}
+
+	want = `rclone_s3_region_info{region="us-east-1",remote="default:",remote_name="default"} 1`
+	if body := probe(enabled, "default:"); !strings.Contains(body, want) {
+		t.Errorf("probe output missing %q\n%s", want, body)
+	}
+}
+
+func TestProbeEnrichesLocalFilesystem(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("filesystem type detection is Linux only")
+	}
+
+	dir := t.TempDir()
+	remote := "disk:" + dir
+	client := &fakeClient{
+		sizes: map[string]*rclone.RcloneSizeOutput{remote: {}},
+		types: map[string]string{"disk": "local"},
+	}
+	e := NewExporterWithConfig(client, Config{EnrichRemoteTypes: true})
+	defer e.Close()
+
+	rec := httptest.NewRecorder()
+	e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote="+remote, nil))
+
+	want := `rclone_local_filesystem_info{fstype="`
+	if !strings.Contains(rec.Body.String(), want) || !strings.Contains(rec.Body.String(), `path="`+dir+`"`) {
+		t.Errorf("probe output missing local filesystem info for %s\n%s", dir, rec.Body)
+	}
+}
@@ -0,0 +1,50 @@
+package exporter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metric error handling strategies selectable via --metrics.error-handling, applied
+// to /metrics and to every probe endpoint alike
+const (
+	// ErrorHandlingContinue serves the metrics that were gathered and logs the
+	// collection error (default)
+	ErrorHandlingContinue = "continue"
+
+	// ErrorHandlingHTTPError answers a collection error with HTTP 500 and no metrics
+	ErrorHandlingHTTPError = "http-error"
+
+	// ErrorHandlingPanic panics on a collection error, for catching broken
+	// collectors during development
+	ErrorHandlingPanic = "panic"
+)
+
+// errorHandlingModes maps each strategy to its promhttp equivalent
+var errorHandlingModes = map[string]promhttp.HandlerErrorHandling{
+	ErrorHandlingContinue:  promhttp.ContinueOnError,
+	ErrorHandlingHTTPError: promhttp.HTTPErrorOnError,
+	ErrorHandlingPanic:     promhttp.PanicOnError,
+}
+
+// ParseErrorHandling validates a metric error handling strategy. Empty selects the default.
+func ParseErrorHandling(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if _, ok := errorHandlingModes[value]; !ok && value != "" {
+		return "", fmt.Errorf("unknown error handling %q (want %s, %s or %s)",
+			value, ErrorHandlingContinue, ErrorHandlingHTTPError, ErrorHandlingPanic)
+	}
+	return value, nil
+}
+
+// HandlerOpts returns the promhttp options of every metrics endpoint, with the
+// configured error handling
+func (e *Exporter) HandlerOpts() promhttp.HandlerOpts {
+	errorHandling, ok := errorHandlingModes[strings.TrimSpace(e.config.MetricsErrorHandling)]
+	if !ok {
+		errorHandling = promhttp.ContinueOnError
+	}
+	return promhttp.HandlerOpts{ErrorHandling: errorHandling}
+}
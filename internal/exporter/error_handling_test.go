@@ -0,0 +1,90 @@
+package exporter
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// brokenCollector is a collector whose only metric fails to collect
+type brokenCollector struct{}
+
+func (brokenCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (brokenCollector) Collect(ch chan<- prometheus.Metric) {
+	desc := prometheus.NewDesc("rclone_broken", "Always fails.", nil, nil)
+	ch <- prometheus.NewInvalidMetric(desc, errors.New("collection failed"))
+}
+
+func TestMetricsErrorHandling(t *testing.T) {
+	tests := []struct {
+		mode      string
+		wantCode  int
+		wantPanic bool
+	}{
+		{"", http.StatusOK, false},
+		{ErrorHandlingContinue, http.StatusOK, false},
+		{ErrorHandlingHTTPError, http.StatusInternalServerError, false},
+		{ErrorHandlingPanic, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			e := NewExporterWithConfig(&fakeClient{}, Config{MetricsErrorHandling: tt.mode})
+			defer e.Close()
+			e.Registry().MustRegister(brokenCollector{})
+
+			rec := httptest.NewRecorder()
+			panicked := func() (panicked bool) {
+				defer func() { panicked = recover() != nil }()
+				e.MetricsHandler(e.HandlerOpts()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+				return false
+			}()
+
+			if panicked != tt.wantPanic {
+				t.Fatalf("panicked = %v, want %v", panicked, tt.wantPanic)
+			}
+			if tt.wantPanic {
+				return
+			}
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if tt.wantCode == http.StatusOK && !strings.Contains(rec.Body.String(), "rclone_exporter_probe_requests_total") {
+				t.Errorf("continued output missing the healthy metrics\n%s", rec.Body)
+			}
+		})
+	}
+}
+
+func TestHandlerOpts(t *testing.T) {
+	for mode, want := range errorHandlingModes {
+		e := NewExporterWithConfig(&fakeClient{}, Config{MetricsErrorHandling: mode})
+		if got := e.HandlerOpts().ErrorHandling; got != want {
+			t.Errorf("%s: ErrorHandling = %v, want %v", mode, got, want)
+		}
+		e.Close()
+	}
+
+	// Probes kept continuing on errors before the strategy was configurable
+	e := NewExporter(&fakeClient{})
+	defer e.Close()
+	if got := e.HandlerOpts().ErrorHandling; got != promhttp.ContinueOnError {
+		t.Errorf("default ErrorHandling = %v, want ContinueOnError", got)
+	}
+}
+
+func TestParseErrorHandling(t *testing.T) {
+	for _, value := range []string{"", "continue", " http-error ", "panic"} {
+		if _, err := ParseErrorHandling(value); err != nil {
+			t.Errorf("ParseErrorHandling(%q) error = %v", value, err)
+		}
+	}
+	if _, err := ParseErrorHandling("ignore"); err == nil {
+		t.Error("ParseErrorHandling(ignore) succeeded, want an error")
+	}
+}
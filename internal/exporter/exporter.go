@@ -4,45 +4,227 @@ import (
 	"fmt"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/crazyuploader/rclone_exporter/internal/logging"
 	"github.com/crazyuploader/rclone_exporter/internal/rclone"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/singleflight"
 )
 
 const (
 	MaxRemoteNameLength = 255
 	MaxConcurrentProbes = 10
+	MaxProbeDepth       = 20
 	namespace           = "rclone"
+
+	// MaxProbeAllConcurrency caps how many remotes a remote=all probe runs at once,
+	// leaving the remaining MaxConcurrentProbes slots free for single-remote probes
+	MaxProbeAllConcurrency = MaxConcurrentProbes / 2
+
+	// writeDeadlineMargin is added on top of the expected probe duration when
+	// extending the response write deadline
+	writeDeadlineMargin = 15 * time.Second
+
+	// AnomalyObjectCountThreshold is the object count above which zero total bytes is considered suspicious
+	AnomalyObjectCountThreshold = 1000
+
+	// NativeHistogramBucketFactor bounds the growth between native histogram buckets (10%)
+	NativeHistogramBucketFactor = 1.1
+	// NativeHistogramMaxBuckets caps the buckets of a native histogram before resolution is reduced
+	NativeHistogramMaxBuckets = 100
 )
 
 var (
-	// Regex for validating remote names (basic alphanumeric with common chars)
-	remoteNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_\-\.:/]+$`)
+	// Regex for validating remote names (basic alphanumeric with common chars, plus
+	// the commas, equals signs and quotes used by rclone connection strings)
+	remoteNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_\-\.:/,="']+$`)
+
+	// Regex for rclone size suffixes such as 500K, 1.5G or 100Mi (a bare number is KiB)
+	minSizeRegex = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?([bBkKmMgGtTpPeE][iI]?[bB]?)?$`)
 )
 
+// Config holds optional settings for the Exporter.
+type Config struct {
+	// ProbeTimeout is the per-remote rclone timeout, used to extend the response
+	// write deadline for long-running probes. Zero leaves the server deadline untouched.
+	ProbeTimeout time.Duration
+
+	// ScrapeTimeout is the deadline of a probe request. Single-remote probes use it as
+	// the rclone timeout when it is shorter than ProbeTimeout, probes of several remotes
+	// serve the remotes that finished and report the rest with probe_success 0. A
+	// shorter Prometheus scrape timeout header wins. Zero relies on the header alone.
+	ScrapeTimeout time.Duration
+
+	// SlowProbeThreshold is the probe duration above which a warning is logged.
+	// Zero disables slow-probe detection.
+	SlowProbeThreshold time.Duration
+
+	// HelpOverrides replaces the HELP text of probe metrics keyed by full metric name.
+	HelpOverrides map[string]string
+
+	// AlertWebhookURL receives a JSON POST when a remote fails AlertFailureThreshold
+	// times in a row. Empty disables webhook alerts.
+	AlertWebhookURL       string
+	AlertFailureThreshold int
+	// AlertRemotes restricts webhook alerts to these remotes. Empty means all remotes.
+	AlertRemotes []string
+
+	// ConstLabels are added to every metric on /metrics and in probe responses.
+	ConstLabels prometheus.Labels
+
+	// ProbeFailureStatusOK answers failed single-remote probes with 200 and the probe
+	// metrics, including probe_success 0, instead of a 500 error.
+	ProbeFailureStatusOK bool
+
+	// SizeCacheTTL serves repeated size probes of the same remote from memory for
+	// this long. Zero disables the size cache.
+	SizeCacheTTL time.Duration
+
+	// MaxResultAge is a hard cap on the age of a served size result. Older cached
+	// results are refreshed regardless of SizeCacheTTL. Zero means no cap.
+	MaxResultAge time.Duration
+
+	// FreePercentThreshold sets rclone_remote_space_low to 1 when an about probe
+	// reports less free space than this percentage. Zero disables the gauge.
+	FreePercentThreshold float64
+
+	// EnrichRemoteTypes adds backend-specific metrics to probes, such as the
+	// region of S3 remotes and the filesystem type of local remotes.
+	EnrichRemoteTypes bool
+
+	// RemoteLabels are static labels per remote name, exported on rclone_remote_meta
+	RemoteLabels map[string]map[string]string
+
+	// NativeHistograms exports rclone_exporter_probe_duration_seconds as a native
+	// histogram instead of one with classic buckets.
+	NativeHistograms bool
+
+	// ObjectCountHistogram observes the object count of every successful probe in
+	// rclone_exporter_objects_count_distribution, for capacity planning across remotes
+	ObjectCountHistogram bool
+
+	// PrerenderMetrics renders and gzips /metrics once and serves that until new probe
+	// data arrives, instead of encoding it on every scrape. Meant for push mode, where
+	// many Prometheus replicas scrape results that only change once per round.
+	PrerenderMetrics bool
+
+	// MetricsErrorHandling is how /metrics and the probe endpoints treat a metric
+	// collection error, one of the ErrorHandling* strategies. Empty continues.
+	MetricsErrorHandling string
+
+	// CountOnly makes size probes count objects with a listing instead of running
+	// rclone size, skipping the byte totals. Probes override it with the size parameter.
+	CountOnly bool
+
+	// DefaultRemote is probed by /probe requests without a remote parameter.
+	// Empty keeps the parameter required.
+	DefaultRemote string
+
+	// RemotesFile lists the remotes of background probes, one per line. It is re-read
+	// on every round, so remotes can be added or removed without a restart.
+	RemotesFile string
+
+	// Binaries are alternative rclone clients keyed by alias, selectable per probe
+	// with the binary query parameter. Probes without it use the default client.
+	Binaries map[string]rclone.Client
+}
+
 // Exporter defines Prometheus metrics and wraps an rclone client.
 type Exporter struct {
 	rcloneClient       rclone.Client
+	config             Config
 	scrapeErrorsTotal  prometheus.Counter
 	probeRequestsTotal prometheus.Counter
+	slowProbesTotal    prometheus.Counter
+	reachableTotal     prometheus.Counter
+	probesInFlight     prometheus.Gauge
+	maxConcurrent      prometheus.Gauge
+	typeCacheExpiry    prometheus.Gauge
 	registry           *prometheus.Registry
 	semaphore          chan struct{}
+	failures           *failureTracker
+	previousSizes      *sizeTracker
+	sizeGroup          singleflight.Group
 	mu                 sync.RWMutex
+
+	// probeRejectedTotal counts probes turned away before running, by remote and reason
+	probeRejectedTotal *prometheus.CounterVec
+
+	// consecutiveFailures holds the failure streak of every probed remote on /metrics
+	consecutiveFailures *prometheus.GaugeVec
+
+	// stderrBytesTotal counts the stderr output of the size probes of every remote on /metrics
+	stderrBytesTotal *prometheus.CounterVec
+
+	// probeTimeoutRatio holds the last successful probe duration of every remote as a
+	// fraction of the rclone timeout on /metrics
+	probeTimeoutRatio *prometheus.GaugeVec
+
+	// registerer wraps registry to add the configured const labels
+	registerer prometheus.Registerer
+
+	// sizes caches size results when SizeCacheTTL is set
+	sizes sizeCache
+
+	// probeDuration records the duration of every probe on /metrics
+	probeDuration *prometheus.HistogramVec
+
+	// rendered holds the pre-rendered /metrics output when PrerenderMetrics is set
+	rendered renderedMetrics
+
+	// snapshots holds named size snapshots of every remote, taken via SnapshotHandler
+	snapshots snapshotStore
+
+	// objectsDistribution records the object count of every probe on /metrics, nil unless enabled
+	objectsDistribution prometheus.Histogram
+
+	// activeSubprocesses reports the rclone processes currently running
+	activeSubprocesses prometheus.GaugeFunc
+
+	// emptyOutputRetries counts size probes retried after empty rclone output
+	emptyOutputRetries prometheus.CounterFunc
+
+	// configParseErrors counts unparseable rclone config dump and listremotes output
+	configParseErrors prometheus.CounterFunc
+
+	// typeDetectionFailures counts remote type lookups that fell back to "unknown"
+	typeDetectionFailures prometheus.CounterFunc
+
+	// draining rejects new probes while the exporter shuts down
+	draining atomic.Bool
+
+	// maintenance serves probes from the size cache only and pauses background probes
+	maintenance     atomic.Bool
+	maintenanceMode prometheus.GaugeFunc
+
+	// remoteLabelNames are the label names of rclone_remote_meta beyond remote and remote_name
+	remoteLabelNames []string
 }
 
 // NewExporter creates a new Exporter instance with a custom registry.
 func NewExporter(rcloneClient rclone.Client) *Exporter {
+	return NewExporterWithConfig(rcloneClient, Config{})
+}
+
+// NewExporterWithConfig creates a new Exporter instance with custom settings.
+func NewExporterWithConfig(rcloneClient rclone.Client, config Config) *Exporter {
 	registry := prometheus.NewRegistry()
 
 	e := &Exporter{
-		rcloneClient: rcloneClient,
-		registry:     registry,
-		semaphore:    make(chan struct{}, MaxConcurrentProbes),
+		rcloneClient:  rcloneClient,
+		config:        config,
+		registry:      registry,
+		registerer:    prometheus.WrapRegistererWith(config.ConstLabels, registry),
+		semaphore:     make(chan struct{}, MaxConcurrentProbes),
+		failures:      newFailureTracker(),
+		previousSizes: newSizeTracker(),
+		sizes:         newMemorySizeCache(),
 		scrapeErrorsTotal: prometheus.NewCounter(
 			prometheus.CounterOpts{
 				Namespace: namespace,
@@ -59,31 +241,318 @@ func NewExporter(rcloneClient rclone.Client) *Exporter {
 				Help:      "Total number of probe requests received.",
 			},
 		),
+		slowProbesTotal: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: "exporter",
+				Name:      "slow_probes_total",
+				Help:      "Total number of probes that exceeded the slow-probe threshold.",
+			},
+		),
+		reachableTotal: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: "exporter",
+				Name:      "reachable_requests_total",
+				Help:      "Total number of reachability check requests received.",
+			},
+		),
+		probeRejectedTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: "exporter",
+				Name:      "probe_rejected_total",
+				Help:      "Total number of probes rejected before running, by remote and reason.",
+			},
+			[]string{"remote", "reason"},
+		),
+		probesInFlight: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "exporter",
+				Name:      "probes_in_flight",
+				Help:      "Number of rclone probes currently holding a concurrency slot.",
+			},
+		),
+		maxConcurrent: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "exporter",
+				Name:      "max_concurrent_probes",
+				Help:      "Maximum number of rclone probes that may run concurrently.",
+			},
+		),
+		typeCacheExpiry: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "exporter",
+				Name:      "type_cache_expiry_seconds",
+				Help:      "How long detected remote types are cached, in seconds.",
+			},
+		),
+		consecutiveFailures: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "remote",
+				Name:      "consecutive_failures",
+				Help:      "Number of consecutive failed probes of the remote (0 after a success).",
+			},
+			[]string{"remote", "remote_name", "remote_type"},
+		),
+		stderrBytesTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: "remote",
+				Name:      "stderr_bytes_total",
+				Help:      "Total bytes rclone wrote to stderr during size probes of the remote.",
+			},
+			[]string{"remote"},
+		),
+		probeTimeoutRatio: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "remote",
+				Name:      "probe_timeout_ratio",
+				Help:      "Duration of the last successful probe of the remote divided by the rclone timeout.",
+			},
+			[]string{"remote"},
+		),
+		probeDuration: newProbeDurationHistogram(config.NativeHistograms),
+		activeSubprocesses: prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "exporter",
+				Name:      "active_subprocesses",
+				Help:      "Number of rclone subprocesses currently running.",
+			},
+			func() float64 { return float64(rclone.ActiveSubprocesses()) },
+		),
+		emptyOutputRetries: prometheus.NewCounterFunc(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: "exporter",
+				Name:      "empty_output_retries_total",
+				Help:      "Total number of size probes retried because rclone returned empty output.",
+			},
+			func() float64 { return float64(rclone.EmptyOutputRetries()) },
+		),
+		configParseErrors: prometheus.NewCounterFunc(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: "exporter",
+				Name:      "config_parse_errors_total",
+				Help:      "Total number of rclone config dump or listremotes outputs that could not be parsed.",
+			},
+			func() float64 { return float64(rclone.ConfigParseErrors()) },
+		),
+		typeDetectionFailures: prometheus.NewCounterFunc(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: "exporter",
+				Name:      "type_detection_failures_total",
+				Help:      "Total number of remote type lookups that failed and reported the type as unknown.",
+			},
+			func() float64 { return float64(rclone.TypeDetectionFailures()) },
+		),
+		remoteLabelNames: remoteLabelNames(config.RemoteLabels),
 	}
+	e.maintenanceMode = prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "exporter",
+			Name:      "maintenance_mode",
+			Help:      "Whether the exporter is in maintenance mode and serves cached results only (1) or not (0).",
+		},
+		func() float64 {
+			if e.Maintenance() {
+				return 1
+			}
+			return 0
+		},
+	)
+	e.maxConcurrent.Set(float64(cap(e.semaphore)))
+	e.typeCacheExpiry.Set(rcloneClient.CacheExpiry().Seconds())
 
-	// Register only the global counters with the shared registry
-	registry.MustRegister(
+	// Register only the global metrics with the shared registry
+	e.registerer.MustRegister(
 		e.scrapeErrorsTotal,
 		e.probeRequestsTotal,
+		e.slowProbesTotal,
+		e.reachableTotal,
+		e.probeRejectedTotal,
+		e.probesInFlight,
+		e.maxConcurrent,
+		e.typeCacheExpiry,
+		e.consecutiveFailures,
+		e.stderrBytesTotal,
+		e.probeTimeoutRatio,
+		e.probeDuration,
+		e.activeSubprocesses,
+		e.emptyOutputRetries,
+		e.configParseErrors,
+		e.typeDetectionFailures,
+		e.maintenanceMode,
 	)
 
+	if config.ObjectCountHistogram {
+		e.objectsDistribution = prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Subsystem: "exporter",
+				Name:      "objects_count_distribution",
+				Help:      "Distribution of the object counts returned by successful probes.",
+				Buckets:   prometheus.ExponentialBuckets(1, 10, 10),
+			},
+		)
+		e.registerer.MustRegister(e.objectsDistribution)
+	}
+
 	return e
 }
 
+// observeObjectCount records a probed object count when the histogram is enabled
+func (e *Exporter) observeObjectCount(objects int64) {
+	if e.objectsDistribution != nil {
+		e.objectsDistribution.Observe(float64(objects))
+	}
+}
+
+// newProbeDurationHistogram creates the probe duration histogram. Classic buckets
+// span half a second to about 17 minutes; native histograms adapt to any range.
+func newProbeDurationHistogram(native bool) *prometheus.HistogramVec {
+	opts := prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "exporter",
+		Name:      "probe_duration_seconds",
+		Help:      "Duration of rclone probes in seconds.",
+		Buckets:   prometheus.ExponentialBuckets(0.5, 2, 12),
+	}
+
+	if native {
+		// Buckets nil keeps only the native histogram
+		opts.Buckets = nil
+		opts.NativeHistogramBucketFactor = NativeHistogramBucketFactor
+		opts.NativeHistogramMaxBucketNumber = NativeHistogramMaxBuckets
+		opts.NativeHistogramMinResetDuration = time.Hour
+	}
+
+	return prometheus.NewHistogramVec(opts, []string{"remote", "command"})
+}
+
+// client returns the rclone client selected by the probe's binary parameter
+func (e *Exporter) client(opts probeOptions) rclone.Client {
+	if client, ok := e.config.Binaries[opts.binary]; ok && opts.binary != "" {
+		return client
+	}
+	return e.rcloneClient
+}
+
+// StartDraining makes new probes fail with 503 while in-flight ones finish
+func (e *Exporter) StartDraining() {
+	e.draining.Store(true)
+}
+
+// Draining reports whether the exporter is shutting down
+func (e *Exporter) Draining() bool {
+	return e.draining.Load()
+}
+
+// SetMaintenance enables or disables maintenance mode. While enabled, probes only
+// serve the last cached size results and never run rclone, so planned backend
+// outages do not show up as probe failures.
+func (e *Exporter) SetMaintenance(enabled bool) {
+	if e.maintenance.Swap(enabled) != enabled {
+		log.Info().Bool("enabled", enabled).Msg("Maintenance mode changed")
+	}
+}
+
+// Maintenance reports whether the exporter is in maintenance mode
+func (e *Exporter) Maintenance() bool {
+	return e.maintenance.Load()
+}
+
 // Registry returns the custom prometheus registry
 func (e *Exporter) Registry() *prometheus.Registry {
 	return e.registry
 }
 
+// Registerer returns a registerer for the custom registry that adds the configured const labels
+func (e *Exporter) Registerer() prometheus.Registerer {
+	return e.registerer
+}
+
 // Close unregisters all metrics to prevent memory leaks
 func (e *Exporter) Close() {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
 	// Unregister from custom registry
-	if e.registry != nil {
-		e.registry.Unregister(e.scrapeErrorsTotal)
-		e.registry.Unregister(e.probeRequestsTotal)
+	if e.registerer != nil {
+		e.registerer.Unregister(e.scrapeErrorsTotal)
+		e.registerer.Unregister(e.probeRequestsTotal)
+		e.registerer.Unregister(e.slowProbesTotal)
+		e.registerer.Unregister(e.reachableTotal)
+		e.registerer.Unregister(e.probeRejectedTotal)
+		e.registerer.Unregister(e.probesInFlight)
+		e.registerer.Unregister(e.maxConcurrent)
+		e.registerer.Unregister(e.typeCacheExpiry)
+		e.registerer.Unregister(e.consecutiveFailures)
+		e.registerer.Unregister(e.stderrBytesTotal)
+		e.registerer.Unregister(e.probeTimeoutRatio)
+		e.registerer.Unregister(e.probeDuration)
+		e.registerer.Unregister(e.activeSubprocesses)
+		e.registerer.Unregister(e.emptyOutputRetries)
+		e.registerer.Unregister(e.configParseErrors)
+		e.registerer.Unregister(e.typeDetectionFailures)
+		e.registerer.Unregister(e.maintenanceMode)
+		if e.objectsDistribution != nil {
+			e.registerer.Unregister(e.objectsDistribution)
+		}
+	}
+
+	if err := e.sizes.close(); err != nil {
+		log.Warn().Err(err).Msg("Failed to close size cache")
+	}
+}
+
+// tryAcquireProbeSlot takes a concurrency slot without blocking and reports whether it succeeded
+func (e *Exporter) tryAcquireProbeSlot() bool {
+	select {
+	case e.semaphore <- struct{}{}:
+		e.probesInFlight.Inc()
+		return true
+	default:
+		return false
+	}
+}
+
+// acquireProbeSlot waits for a concurrency slot until done is closed and reports whether it got one
+func (e *Exporter) acquireProbeSlot(done <-chan struct{}) bool {
+	select {
+	case e.semaphore <- struct{}{}:
+		e.probesInFlight.Inc()
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// releaseProbeSlot returns a slot taken by tryAcquireProbeSlot or acquireProbeSlot
+func (e *Exporter) releaseProbeSlot() {
+	e.probesInFlight.Dec()
+	<-e.semaphore
+}
+
+// extendWriteDeadline pushes the response write deadline out far enough for a probe
+// running the given number of sequential rclone batches
+func (e *Exporter) extendWriteDeadline(w http.ResponseWriter, batches int) {
+	if e.config.ProbeTimeout <= 0 || batches < 1 {
+		return
+	}
+
+	deadline := time.Now().Add(time.Duration(batches)*e.config.ProbeTimeout + writeDeadlineMargin)
+	if err := http.NewResponseController(w).SetWriteDeadline(deadline); err != nil {
+		log.Debug().Err(err).Msg("Failed to extend response write deadline")
 	}
 }
 
@@ -104,182 +573,123 @@ func (e *Exporter) validateRemote(remote string) error {
 	return nil
 }
 
-// handleError provides consistent error handling
-func (e *Exporter) handleError(w http.ResponseWriter, r *http.Request, remote, message string, status int, err error) {
-	e.scrapeErrorsTotal.Inc()
-	http.Error(w, message, status)
-
-	logEvent := log.Warn().
-		Str("client", r.RemoteAddr).
-		Str("remote", remote).
-		Str("user_agent", r.UserAgent())
+// parseDepth parses the optional depth parameter limiting traversal
+func parseDepth(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
 
+	depth, err := strconv.Atoi(value)
 	if err != nil {
-		logEvent = logEvent.Err(err)
+		return 0, fmt.Errorf("depth must be an integer")
 	}
 
-	logEvent.Msg(message)
-}
-
-// parseRemoteName extracts the remote name and optional subpath from the remote parameter
-func parseRemoteName(remote string) (name, remotePath string) {
-	// Split on first colon to get remote name
-	parts := strings.SplitN(remote, ":", 2)
-	name = parts[0]
-
-	// If there's a subpath after the colon, include it
-	if len(parts) > 1 {
-		remotePath = parts[1]
-		if remotePath == "" {
-			remotePath = "/"
-		}
-	} else {
-		remotePath = "/"
+	if depth < 1 || depth > MaxProbeDepth {
+		return 0, fmt.Errorf("depth must be between 1 and %d", MaxProbeDepth)
 	}
 
-	return name, remotePath
+	return depth, nil
 }
 
-// ProbeHandler handles /probe requests and emits Prometheus metrics.
-func (e *Exporter) ProbeHandler(w http.ResponseWriter, r *http.Request) {
-	e.probeRequestsTotal.Inc()
-
-	remote := strings.TrimSpace(r.URL.Query().Get("remote"))
-	if err := e.validateRemote(remote); err != nil {
-		e.handleError(w, r, remote, fmt.Sprintf("Invalid remote parameter: %v", err), http.StatusBadRequest, err)
-		return
+// parseMinSize parses the optional minsize parameter in rclone's size suffix format
+func parseMinSize(value string) (string, error) {
+	if value == "" {
+		return "", nil
 	}
 
-	// Rate limiting using semaphore
-	select {
-	case e.semaphore <- struct{}{}:
-		defer func() { <-e.semaphore }()
-	default:
-		e.handleError(w, r, remote, "Too many concurrent requests", http.StatusTooManyRequests, nil)
-		return
+	if !minSizeRegex.MatchString(value) {
+		return "", fmt.Errorf("minsize must be a size such as 500K, 100M or 1.5G")
 	}
 
-	start := time.Now()
-	log.Debug().
-		Str("remote", remote).
-		Str("client", r.RemoteAddr).
-		Str("user_agent", r.UserAgent()).
-		Msg("Starting rclone probe")
+	return value, nil
+}
 
-	// Parse remote to extract name and path for better labeling
-	remoteName, remotePath := parseRemoteName(remote)
+// parseBoolParam parses an optional boolean query parameter, defaulting to false
+func parseBoolParam(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
 
-	// Create a fresh registry for this probe
-	probeRegistry := prometheus.NewRegistry()
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("must be true or false")
+	}
+	return b, nil
+}
 
-	// Create metrics for this specific probe with enhanced labels including remote_type
-	sizeBytes := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "remote",
-			Name:      "size_bytes",
-			Help:      "Total size of the rclone remote in bytes.",
-		},
-		[]string{"remote", "remote_name", "path", "remote_type"},
-	)
+// detectAnomaly returns a reason when a successful probe result looks like a misconfigured remote
+func detectAnomaly(output *rclone.RcloneSizeOutput) string {
+	// Many objects totalling zero bytes usually means directory entries are reported as files
+	if output.Count >= AnomalyObjectCountThreshold && output.Bytes == 0 {
+		return "zero_bytes_many_objects"
+	}
 
-	objectsCount := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "remote",
-			Name:      "objects_count",
-			Help:      "Total number of objects in the rclone remote.",
-		},
-		[]string{"remote", "remote_name", "path", "remote_type"},
-	)
+	return ""
+}
 
-	probeSuccess := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "probe",
-			Name:      "success",
-			Help:      "Whether the last rclone probe was successful (1 = success, 0 = failure).",
-		},
-		[]string{"remote", "remote_name", "remote_type"},
-	)
+// handleError provides consistent error handling
+func (e *Exporter) handleError(w http.ResponseWriter, r *http.Request, remote, message string, status int, err error) {
+	e.scrapeErrorsTotal.Inc()
+	http.Error(w, message, status)
+	e.logRequestError(r, remote, message, err)
+}
 
-	probeDurationSeconds := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "probe",
-			Name:      "duration_seconds",
-			Help:      "Duration of the rclone size probe in seconds.",
-		},
-		[]string{"remote", "remote_name", "remote_type"},
-	)
+// logRequestError logs a rejected or failed request
+func (e *Exporter) logRequestError(r *http.Request, remote, message string, err error) {
+	logEvent := logging.FromContext(r.Context()).Warn().
+		Str("client", r.RemoteAddr).
+		Str("remote", remote).
+		Str("user_agent", r.UserAgent())
 
-	probeInfo := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "probe",
-			Name:      "info",
-			Help:      "Information about the probe target (always 1).",
-		},
-		[]string{"remote", "remote_name", "path", "remote_type"},
-	)
+	if err != nil {
+		logEvent = logEvent.Err(err)
+	}
+
+	logEvent.Msg(message)
+}
 
-	// Register probe-specific metrics with the probe registry
-	probeRegistry.MustRegister(sizeBytes)
-	probeRegistry.MustRegister(objectsCount)
-	probeRegistry.MustRegister(probeSuccess)
-	probeRegistry.MustRegister(probeDurationSeconds)
-	probeRegistry.MustRegister(probeInfo)
-
-	// Also register the global counters so they appear in probe output
-	probeRegistry.MustRegister(e.scrapeErrorsTotal)
-	probeRegistry.MustRegister(e.probeRequestsTotal)
-
-	// Get remote type (best effort - default to "unknown" if fails)
-	remoteType, typeErr := e.rcloneClient.GetRemoteType(remoteName)
-	if typeErr != nil {
-		log.Debug().
-			Err(typeErr).
-			Str("remote", remoteName).
-			Msg("Failed to detect remote type, using 'unknown'")
-		remoteType = "unknown"
+// parseRemoteName extracts the remote name and optional subpath from the remote parameter.
+// Connection strings such as `:sftp,host=example.com:path` are split on the colon that
+// ends the remote spec, honoring rclone's quoting of values containing colons, and their
+// parameters are dropped from the name (`:sftp`).
+func parseRemoteName(remote string) (name, remotePath string) {
+	// Find the colon ending the remote spec, skipping the leading colon of on-the-fly
+	// backends and colons inside quoted parameter values
+	end := -1
+	var quote rune
+scan:
+	for i, r := range remote {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ':' && i > 0:
+			end = i
+			break scan
+		}
 	}
 
-	// Set probe info metric with type
-	probeInfo.WithLabelValues(remote, remoteName, remotePath, remoteType).Set(1)
-
-	// Always update probe duration, even on failure
-	defer func() {
-		duration := time.Since(start).Seconds()
-		probeDurationSeconds.WithLabelValues(remote, remoteName, remoteType).Set(duration)
-		log.Debug().
-			Str("remote", remote).
-			Str("remote_type", remoteType).
-			Float64("duration_seconds", duration).
-			Msg("Probe completed")
-	}()
-
-	// Get remote size and type information
-	output, err := e.rcloneClient.GetRemoteSize(remote)
-	if err != nil {
-		probeSuccess.WithLabelValues(remote, remoteName, remoteType).Set(0)
-		e.handleError(w, r, remote, "rclone probe failed", http.StatusInternalServerError, err)
-		return
+	spec := remote
+	remotePath = "/"
+	if end >= 0 {
+		spec = remote[:end]
+		if subpath := remote[end+1:]; subpath != "" {
+			remotePath = subpath
+		}
 	}
 
-	// Update metrics with labels including remote type
-	sizeBytes.WithLabelValues(remote, remoteName, remotePath, remoteType).Set(float64(output.Bytes))
-	objectsCount.WithLabelValues(remote, remoteName, remotePath, remoteType).Set(float64(output.Count))
-	probeSuccess.WithLabelValues(remote, remoteName, remoteType).Set(1)
+	// Connection string parameters are not part of the remote's name
+	name, _, _ = strings.Cut(spec, ",")
+	return name, remotePath
+}
 
-	log.Debug().
-		Str("remote", remote).
-		Str("remote_type", remoteType).
-		Int64("bytes", output.Bytes).
-		Int64("objects", output.Count).
-		Msg("Probe successful")
-
-	// Serve metrics using the probe-specific registry
-	promhttp.HandlerFor(probeRegistry, promhttp.HandlerOpts{
-		ErrorHandling: promhttp.ContinueOnError,
-	}).ServeHTTP(w, r)
+// connectionStringBackend returns the backend of an on-the-fly remote name like `:sftp`
+func connectionStringBackend(name string) (backend string, ok bool) {
+	if !strings.HasPrefix(name, ":") || len(name) == 1 {
+		return "", false
+	}
+	return name[1:], true
 }
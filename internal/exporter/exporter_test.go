@@ -0,0 +1,119 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDepth(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "", want: 0},
+		{value: "0", wantErr: true},
+		{value: "21", wantErr: true},
+		{value: "abc", wantErr: true},
+		{value: "3", want: 3},
+	}
+
+	for _, tt := range tests {
+		got, err := parseDepth(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseDepth(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseDepth(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseMinSize(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantErr bool
+	}{
+		{value: ""},
+		{value: "100"},
+		{value: "500K"},
+		{value: "1.5G"},
+		{value: "100Mi"},
+		{value: "2TiB"},
+		{value: "-1M", wantErr: true},
+		{value: "10X", wantErr: true},
+		{value: "1G --delete", wantErr: true},
+		{value: "M", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseMinSize(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseMinSize(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.value {
+			t.Errorf("parseMinSize(%q) = %q, want %q", tt.value, got, tt.value)
+		}
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		command, mode string
+		want          string
+		wantErr       bool
+	}{
+		{"", "", ProbeModeSize, false},
+		{"about", "", ProbeModeAbout, false},
+		{"", "dirs", ProbeModeDirs, false},
+		{"size", "size", ProbeModeSize, false},
+		{"size", "about", "", true},
+		{"bogus", "", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseCommand(tt.command, tt.mode)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseCommand(%q, %q) error = %v, wantErr %v", tt.command, tt.mode, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseCommand(%q, %q) = %q, want %q", tt.command, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestParseRemoteName(t *testing.T) {
+	tests := []struct {
+		remote   string
+		wantName string
+		wantPath string
+	}{
+		{"name:", "name", "/"},
+		{"name:path/sub", "name", "path/sub"},
+		{"name", "name", "/"},
+		{":sftp,host=x:path", ":sftp", "path"},
+		{":sftp,host=x:", ":sftp", "/"},
+		{`:sftp,host="example.com:22":backups`, ":sftp", "backups"},
+		{"gdrive,shared_with_me:docs", "gdrive", "docs"},
+		{":local:/tmp", ":local", "/tmp"},
+	}
+
+	for _, tt := range tests {
+		name, path := parseRemoteName(tt.remote)
+		if name != tt.wantName || path != tt.wantPath {
+			t.Errorf("parseRemoteName(%q) = (%q, %q), want (%q, %q)", tt.remote, name, path, tt.wantName, tt.wantPath)
+		}
+	}
+}
+
+func TestTypeCacheExpiryGauge(t *testing.T) {
+	e := NewExporter(&fakeClient{})
+	defer e.Close()
+
+	if body := scrapeMetrics(e); !strings.Contains(body, "rclone_exporter_type_cache_expiry_seconds 300") {
+		t.Errorf("/metrics missing the type cache expiry\n%s", body)
+	}
+}
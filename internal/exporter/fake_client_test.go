@@ -0,0 +1,159 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crazyuploader/rclone_exporter/internal/rclone"
+)
+
+// fakeClient is an in-memory rclone.Client used by the exporter tests
+type fakeClient struct {
+	mu          sync.Mutex
+	sizes       map[string]*rclone.RcloneSizeOutput
+	dirs        map[string]int64
+	objects     map[string]int64
+	classes     map[string]map[string]int64 // Object counts by storage class, by remote
+	duplicates  map[string]int64
+	abouts      map[string]*rclone.RcloneAboutOutput
+	upstreams   map[string][]rclone.Upstream
+	options     map[string]map[string]string
+	types       map[string]string
+	backends    map[string]string // Backend types of wrapping remotes by name
+	remotes     []rclone.RemoteInfo
+	sizeErrs    map[string]error // Errors returned by size calls, by remote
+	sizeCalls   int
+	sizeOpts    rclone.ProbeOptions // Options of the last size call
+	onSize      func(remote string) // Optional hook run before a size result is returned
+	invalidated []string            // Remotes passed to InvalidateCache
+}
+
+func (f *fakeClient) GetRemoteSize(remote string) (*rclone.RcloneSizeOutput, error) {
+	return f.GetRemoteSizeWithOptions(remote, rclone.ProbeOptions{})
+}
+
+func (f *fakeClient) GetRemoteSizeWithOptions(remote string, opts rclone.ProbeOptions) (*rclone.RcloneSizeOutput, error) {
+	if f.onSize != nil {
+		f.onSize(remote)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.sizeCalls++
+	f.sizeOpts = opts
+	if err, ok := f.sizeErrs[remote]; ok {
+		return nil, err
+	}
+	if size, ok := f.sizes[remote]; ok {
+		return size, nil
+	}
+	return nil, fmt.Errorf("remote '%s' failed", remote)
+}
+
+func (f *fakeClient) GetRemoteDirCount(remote string, _ rclone.ProbeOptions) (int64, error) {
+	if dirs, ok := f.dirs[remote]; ok {
+		return dirs, nil
+	}
+	return 0, fmt.Errorf("remote '%s' failed", remote)
+}
+
+func (f *fakeClient) GetRemoteStorageClasses(remote string, _ rclone.ProbeOptions) (map[string]int64, error) {
+	if classes, ok := f.classes[remote]; ok {
+		return classes, nil
+	}
+	return nil, fmt.Errorf("remote '%s' failed", remote)
+}
+
+func (f *fakeClient) GetRemoteObjectCount(remote string, _ rclone.ProbeOptions) (int64, error) {
+	if objects, ok := f.objects[remote]; ok {
+		return objects, nil
+	}
+	return 0, fmt.Errorf("remote '%s' failed", remote)
+}
+
+func (f *fakeClient) GetRemoteDuplicates(remote string) (int64, error) {
+	if duplicates, ok := f.duplicates[remote]; ok {
+		return duplicates, nil
+	}
+	return 0, fmt.Errorf("remote '%s' failed", remote)
+}
+
+func (f *fakeClient) GetRemoteAbout(remote string) (*rclone.RcloneAboutOutput, error) {
+	if about, ok := f.abouts[remote]; ok {
+		return about, nil
+	}
+	return nil, fmt.Errorf("remote '%s' failed", remote)
+}
+
+func (f *fakeClient) GetUpstreams(remote string) ([]rclone.Upstream, error) {
+	return f.upstreams[remote], nil
+}
+
+func (f *fakeClient) GetRemoteOption(remote, key string) (string, error) {
+	return f.options[strings.TrimSuffix(remote, ":")][key], nil
+}
+
+func (f *fakeClient) GetRedactedConfig() (map[string]map[string]interface{}, error) {
+	return map[string]map[string]interface{}{}, nil
+}
+
+func (f *fakeClient) GetRemoteSizeWithType(remote string) (*rclone.RemoteSizeWithType, error) {
+	size, err := f.GetRemoteSize(remote)
+	if err != nil {
+		return nil, err
+	}
+	remoteType, _ := f.GetRemoteType(remote)
+	return &rclone.RemoteSizeWithType{RcloneSizeOutput: size, RemoteType: remoteType}, nil
+}
+
+func (f *fakeClient) CacheExpiry() time.Duration { return 5 * time.Minute }
+
+func (f *fakeClient) CheckBinaryAvailable(context.Context) error { return nil }
+
+func (f *fakeClient) CheckReachable(remote string) error { return nil }
+
+func (f *fakeClient) GetVersion() (string, error) { return "rclone v0.0.0-test", nil }
+
+func (f *fakeClient) ListRemotes() ([]rclone.RemoteInfo, error) { return f.remotes, nil }
+
+func (f *fakeClient) GetRemoteType(remote string) (string, error) {
+	if t, ok := f.types[remote]; ok {
+		return t, nil
+	}
+	return "unknown", fmt.Errorf("remote '%s' not found in config", remote)
+}
+
+func (f *fakeClient) CachedRemoteType(remote string) (string, bool) {
+	t, ok := f.types[remote]
+	return t, ok
+}
+
+func (f *fakeClient) GetBackendType(remote string) (string, error) {
+	if t, ok := f.backends[remote]; ok {
+		return t, nil
+	}
+	return f.GetRemoteType(remote)
+}
+
+func (f *fakeClient) CachedBackendType(remote string) (string, bool) {
+	if t, ok := f.backends[remote]; ok {
+		return t, true
+	}
+	return f.CachedRemoteType(remote)
+}
+
+func (f *fakeClient) ConfigFile() (string, error) { return "/dev/null", nil }
+
+func (f *fakeClient) InvalidateCache(remote string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.invalidated = append(f.invalidated, remote)
+	return false
+}
+
+func (f *fakeClient) ClearCache() int { return 0 }
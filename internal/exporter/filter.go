@@ -0,0 +1,66 @@
+package exporter
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// nameFilteredGatherer keeps only the metric families matching one of its patterns.
+// A pattern ending in "*" matches names with that prefix, anything else an exact name.
+type nameFilteredGatherer struct {
+	gatherer prometheus.Gatherer
+	patterns []string
+}
+
+// Gather implements prometheus.Gatherer
+func (g nameFilteredGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.gatherer.Gather()
+
+	filtered := families[:0]
+	for _, family := range families {
+		if g.matches(family.GetName()) {
+			filtered = append(filtered, family)
+		}
+	}
+	return filtered, err
+}
+
+// matches reports whether name matches any of the patterns
+func (g nameFilteredGatherer) matches(name string) bool {
+	for _, pattern := range g.patterns {
+		if prefix, isPrefix := strings.CutSuffix(pattern, "*"); isPrefix {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// MetricsHandler serves the exporter's metrics. Repeated name query parameters
+// restrict the output to matching metrics, e.g. /metrics?name=rclone_remote_*
+func (e *Exporter) MetricsHandler(opts promhttp.HandlerOpts) http.Handler {
+	all := promhttp.HandlerFor(e.registry, opts)
+
+	// Native histograms need the protobuf format, which is negotiated per request
+	prerender := e.config.PrerenderMetrics && !e.config.NativeHistograms
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		patterns := r.URL.Query()["name"]
+		if len(patterns) == 0 {
+			if prerender && e.serveRenderedMetrics(w, r) {
+				return
+			}
+			all.ServeHTTP(w, r)
+			return
+		}
+
+		promhttp.HandlerFor(nameFilteredGatherer{gatherer: e.registry, patterns: patterns}, opts).ServeHTTP(w, r)
+	})
+}
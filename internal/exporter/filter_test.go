@@ -0,0 +1,54 @@
+package exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func TestMetricsHandlerNameFilter(t *testing.T) {
+	e := NewExporter(&fakeClient{})
+	defer e.Close()
+	e.consecutiveFailures.WithLabelValues("remote:", "remote", "unknown").Set(1)
+	handler := e.MetricsHandler(promhttp.HandlerOpts{})
+
+	scrape := func(query string) string {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics"+query, nil))
+		return rec.Body.String()
+	}
+
+	all := scrape("")
+	for _, name := range []string{"rclone_exporter_scrape_errors_total", "rclone_remote_consecutive_failures"} {
+		if !strings.Contains(all, "# TYPE "+name) {
+			t.Errorf("unfiltered output missing %s", name)
+		}
+	}
+
+	tests := []struct {
+		query   string
+		want    []string
+		notWant []string
+	}{
+		{"?name=rclone_remote_*", []string{"rclone_remote_consecutive_failures"}, []string{"rclone_exporter_"}},
+		{"?name=rclone_exporter_max_concurrent_probes", []string{"rclone_exporter_max_concurrent_probes"}, []string{"rclone_exporter_probes_in_flight", "rclone_remote_"}},
+		{"?name=rclone_exporter_max_concurrent_probes&name=rclone_remote_*", []string{"rclone_exporter_max_concurrent_probes", "rclone_remote_consecutive_failures"}, []string{"rclone_exporter_probes_in_flight"}},
+		{"?name=rclone_exporter_max", nil, []string{"# TYPE"}},
+	}
+	for _, tt := range tests {
+		body := scrape(tt.query)
+		for _, name := range tt.want {
+			if !strings.Contains(body, "# TYPE "+name) {
+				t.Errorf("%s: output missing %s\n%s", tt.query, name, body)
+			}
+		}
+		for _, name := range tt.notWant {
+			if strings.Contains(body, name) {
+				t.Errorf("%s: output contains %s\n%s", tt.query, name, body)
+			}
+		}
+	}
+}
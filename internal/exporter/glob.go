@@ -0,0 +1,96 @@
+package exporter
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/crazyuploader/rclone_exporter/internal/rclone"
+)
+
+// MaxGlobMatches caps how many remotes a single glob probe may expand to
+const MaxGlobMatches = 50
+
+// globMetaChars are the characters that make a remote parameter a glob
+const globMetaChars = "*?["
+
+// remoteGlobRegex limits glob remote parameters to a name pattern followed by a colon.
+// Paths are not supported, the pattern only selects configured remote names.
+var remoteGlobRegex = regexp.MustCompile(`^[a-zA-Z0-9_\-\.*?\[\]^!]+:$`)
+
+// isRemoteGlob reports whether the remote parameter is a glob such as prod-*:
+func isRemoteGlob(remote string) bool {
+	return strings.ContainsAny(remote, globMetaChars)
+}
+
+// validateRemoteGlob validates a glob remote parameter
+func validateRemoteGlob(remote string) error {
+	if len(remote) > MaxRemoteNameLength {
+		return fmt.Errorf("remote glob too long (max %d characters)", MaxRemoteNameLength)
+	}
+
+	if !remoteGlobRegex.MatchString(remote) {
+		return fmt.Errorf("remote glob must be a remote name pattern followed by ':', e.g. prod-*:")
+	}
+
+	if _, err := path.Match(strings.TrimSuffix(remote, ":"), ""); err != nil {
+		return fmt.Errorf("invalid remote glob: %w", err)
+	}
+
+	return nil
+}
+
+// matchRemotes returns the remotes whose names match the glob, in the order listed
+func matchRemotes(glob string, remotes []rclone.RemoteInfo) []string {
+	pattern := strings.TrimSuffix(glob, ":")
+
+	var matches []string
+	for _, info := range remotes {
+		// The pattern was validated, so Match cannot fail
+		if ok, _ := path.Match(pattern, info.Name); ok {
+			matches = append(matches, info.Name+":")
+		}
+	}
+	return matches
+}
+
+// remoteNames returns the probe target of each listed remote
+func remoteNames(remotes []rclone.RemoteInfo) []string {
+	names := make([]string, 0, len(remotes))
+	for _, info := range remotes {
+		names = append(names, info.Name+":")
+	}
+	return names
+}
+
+// probeGlobRemotes probes every configured remote matching the glob and serves the combined metrics
+func (e *Exporter) probeGlobRemotes(w http.ResponseWriter, r *http.Request, glob string, opts probeOptions) {
+	remotes, err := e.client(opts).ListRemotes()
+	if err != nil {
+		e.handleError(w, r, glob, "Failed to list remotes", http.StatusInternalServerError, err)
+		return
+	}
+
+	matches := matchRemotes(glob, remotes)
+	switch {
+	case len(matches) == 0:
+		err := fmt.Errorf("no configured remote matches %s", glob)
+		e.handleProbeError(w, r, glob, err.Error(), http.StatusNotFound, err)
+		return
+	case len(matches) > MaxGlobMatches:
+		err := fmt.Errorf("%s matches %d remotes, more than the limit of %d", glob, len(matches), MaxGlobMatches)
+		e.handleProbeError(w, r, glob, err.Error(), http.StatusBadRequest, err)
+		return
+	}
+
+	opts.log().Debug().
+		Str("glob", glob).
+		Int("remotes", len(matches)).
+		Str("client", r.RemoteAddr).
+		Str("user_agent", r.UserAgent()).
+		Msg("Starting rclone probe of matching remotes")
+
+	e.probeRemoteList(w, r, matches, opts)
+}
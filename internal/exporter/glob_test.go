@@ -0,0 +1,118 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/crazyuploader/rclone_exporter/internal/rclone"
+)
+
+func TestValidateRemoteGlob(t *testing.T) {
+	tests := []struct {
+		glob    string
+		wantErr bool
+	}{
+		{"prod-*:", false},
+		{"backup-?:", false},
+		{"[ab]*:", false},
+		{"prod-*", true},        // Missing colon
+		{"prod-*:path", true},   // Paths are not supported
+		{"prod-[:", true},       // Malformed character class
+		{"prod;rm -rf*:", true}, // Invalid characters
+		{strings.Repeat("a", MaxRemoteNameLength) + "*:", true},
+	}
+	for _, tt := range tests {
+		if err := validateRemoteGlob(tt.glob); (err != nil) != tt.wantErr {
+			t.Errorf("validateRemoteGlob(%q) error = %v, wantErr %v", tt.glob, err, tt.wantErr)
+		}
+	}
+}
+
+func TestMatchRemotes(t *testing.T) {
+	remotes := []rclone.RemoteInfo{{Name: "prod-a"}, {Name: "prod-b"}, {Name: "staging-a"}, {Name: "prod"}}
+
+	tests := []struct {
+		glob string
+		want []string
+	}{
+		{"prod-*:", []string{"prod-a:", "prod-b:"}},
+		{"*-a:", []string{"prod-a:", "staging-a:"}},
+		{"prod?:", nil},
+		{"prod*:", []string{"prod-a:", "prod-b:", "prod:"}},
+		{"[ps]*-a:", []string{"prod-a:", "staging-a:"}},
+	}
+	for _, tt := range tests {
+		if got := matchRemotes(tt.glob, remotes); !slices.Equal(got, tt.want) {
+			t.Errorf("matchRemotes(%q) = %v, want %v", tt.glob, got, tt.want)
+		}
+	}
+}
+
+func TestProbeGlobRemotes(t *testing.T) {
+	client := &fakeClient{
+		remotes: []rclone.RemoteInfo{{Name: "prod-a"}, {Name: "prod-b"}, {Name: "staging"}},
+		sizes: map[string]*rclone.RcloneSizeOutput{
+			"prod-a:":  {Bytes: 1},
+			"prod-b:":  {Bytes: 2},
+			"staging:": {Bytes: 3},
+		},
+	}
+	e := NewExporter(client)
+	defer e.Close()
+
+	rec := httptest.NewRecorder()
+	e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=prod-*:", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d\n%s", rec.Code, http.StatusOK, rec.Body)
+	}
+
+	body, _ := io.ReadAll(rec.Body)
+	for _, want := range []string{
+		`rclone_remote_size_bytes{path="/",remote="prod-a:",remote_name="prod-a",remote_type="unknown"} 1`,
+		`rclone_remote_size_bytes{path="/",remote="prod-b:",remote_name="prod-b",remote_type="unknown"} 2`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("probe output missing %q\n%s", want, body)
+		}
+	}
+	if strings.Contains(string(body), `remote="staging:"`) {
+		t.Errorf("probe output contains non-matching remote\n%s", body)
+	}
+}
+
+func TestProbeGlobRemotesErrors(t *testing.T) {
+	client := &fakeClient{sizes: map[string]*rclone.RcloneSizeOutput{}}
+	for i := 0; i <= MaxGlobMatches; i++ {
+		client.remotes = append(client.remotes, rclone.RemoteInfo{Name: fmt.Sprintf("many%d", i)})
+	}
+	e := NewExporter(client)
+	defer e.Close()
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"remote=many*:", http.StatusBadRequest},            // Over the match cap
+		{"remote=none-*:", http.StatusNotFound},             // No matches
+		{"remote=many[:", http.StatusBadRequest},            // Malformed glob
+		{"remote=many*:&cache=only", http.StatusBadRequest}, // Cache-only needs a single remote
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?"+tt.query, nil))
+		if probeStatus(rec) != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.query, probeStatus(rec), tt.want)
+		}
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if client.sizeCalls != 0 {
+		t.Errorf("size calls = %d, want 0", client.sizeCalls)
+	}
+}
@@ -0,0 +1,79 @@
+package exporter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// defaultHelp holds the default HELP text of every metric whose HELP can be
+// overridden via the config file, keyed by full metric name
+var defaultHelp = map[string]string{
+	"rclone_remote_size_bytes":                 "Total size of the rclone remote in bytes.",
+	"rclone_remote_size_delta_bytes":           "Change in bytes of the rclone remote since its previous size probe.",
+	"rclone_remote_objects_count":              "Total number of objects in the rclone remote.",
+	"rclone_probe_success":                     "Whether the last rclone probe was successful (1 = success, 0 = failure).",
+	"rclone_probe_duration_seconds":            "Duration of the rclone size probe in seconds.",
+	"rclone_exporter_probe_series_count":       "Number of series in this probe response, including this one.",
+	"rclone_probe_total_duration_seconds":      "Duration of the whole probe request in seconds, including queueing and overhead.",
+	"rclone_probe_info":                        "Information about the probe target (always 1).",
+	"rclone_remote_anomaly":                    "Whether the probe result looks suspicious despite succeeding (1 = anomaly detected).",
+	"rclone_remote_meta":                       "Static labels configured for the remote (always 1).",
+	"rclone_remote_probe_warnings":             "Number of warning lines rclone logged during a successful size probe.",
+	"rclone_remote_token_invalid":              "Whether the last probe failed because the OAuth token of the remote is invalid (1 = run rclone config reconnect).",
+	"rclone_remote_dirs_count":                 "Total number of directories in the rclone remote.",
+	"rclone_remote_duplicates":                 "Number of redundant duplicate files found by rclone dedupe --dry-run.",
+	"rclone_remote_large_objects_count":        "Number of objects of at least min_size in the rclone remote.",
+	"rclone_remote_large_objects_bytes":        "Total size in bytes of the objects of at least min_size in the rclone remote.",
+	"rclone_remote_reachable":                  "Whether the rclone remote could be listed (1 = reachable, 0 = unreachable).",
+	"rclone_remote_reachable_duration_seconds": "Duration of the rclone reachability check in seconds.",
+	"rclone_remote_quota_total_bytes":          "Total quota of the rclone remote in bytes, as reported by rclone about.",
+	"rclone_remote_quota_used_bytes":           "Used quota of the rclone remote in bytes, as reported by rclone about.",
+	"rclone_remote_quota_free_bytes":           "Free quota of the rclone remote in bytes, as reported by rclone about.",
+	"rclone_remote_free_percent":               "Free space of the rclone remote as a percentage of its total quota.",
+	"rclone_remote_space_low":                  "Whether free space is below the configured threshold (1 = low).",
+	"rclone_remote_consecutive_failures":       "Number of consecutive failed probes of the remote (0 after a success).",
+	"rclone_remote_upstream_size_bytes":        "Size in bytes of each upstream of a union or combine remote.",
+	"rclone_remote_upstream_objects_count":     "Number of objects in each upstream of a union or combine remote.",
+	"rclone_remote_objects_by_storage_class":   "Number of objects of each storage class in an S3 remote.",
+	"rclone_probe_result_age_seconds":          "Age of the served size result in seconds (0 when freshly computed).",
+	"rclone_remote_cache_hit":                  "Whether the size result was served from the size cache (1 = cached, 0 = computed or missing).",
+	"rclone_remote_trashed_bytes":              "Bytes in the trash of the rclone remote, as reported by rclone about.",
+	"rclone_remote_trashed_bytes_present":      "Whether the backend reports its trash size (1 = reported, 0 = unknown).",
+	"rclone_s3_region_info":                    "Region of an S3 remote from its config (always 1).",
+	"rclone_local_filesystem_info":             "Filesystem type of a local remote's path (always 1).",
+	"rclone_sync_stats_up":                     "Whether the rclone sync stats could be read (1 = success, 0 = failure).",
+	"rclone_sync_transfers_total":              "Number of completed transfers reported by rclone sync.",
+	"rclone_sync_errors_total":                 "Number of errors reported by rclone sync.",
+	"rclone_sync_checks_total":                 "Number of completed checks reported by rclone sync.",
+	"rclone_sync_bytes_transferred":            "Bytes transferred reported by rclone sync.",
+}
+
+// help returns the HELP text for a metric, honoring configured overrides
+func (e *Exporter) help(subsystem, name string) string {
+	fqName := prometheus.BuildFQName(namespace, subsystem, name)
+	if override, ok := e.config.HelpOverrides[fqName]; ok && override != "" {
+		return override
+	}
+	return defaultHelp[fqName]
+}
+
+// ValidateHelpOverrides returns an error listing any override keys that do not
+// name a metric whose HELP text can be overridden
+func ValidateHelpOverrides(overrides map[string]string) error {
+	var unknown []string
+	for name := range overrides {
+		if _, ok := defaultHelp[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	sort.Strings(unknown)
+	return fmt.Errorf("unknown metric names in metrics.help: %s", strings.Join(unknown, ", "))
+}
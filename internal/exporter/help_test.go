@@ -0,0 +1,61 @@
+package exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/crazyuploader/rclone_exporter/internal/rclone"
+)
+
+func TestHelpOverrideAndFallback(t *testing.T) {
+	e := NewExporterWithConfig(&fakeClient{}, Config{
+		HelpOverrides: map[string]string{
+			"rclone_remote_size_bytes":    "Custom size help.",
+			"rclone_remote_objects_count": "",
+		},
+	})
+	defer e.Close()
+
+	if got := e.help("remote", "size_bytes"); got != "Custom size help." {
+		t.Errorf("help(size_bytes) = %q, want override", got)
+	}
+	if got, want := e.help("remote", "objects_count"), defaultHelp["rclone_remote_objects_count"]; got != want {
+		t.Errorf("help(objects_count) = %q, want default %q for empty override", got, want)
+	}
+	if got, want := e.help("probe", "success"), defaultHelp["rclone_probe_success"]; got != want {
+		t.Errorf("help(success) = %q, want default %q", got, want)
+	}
+}
+
+func TestHelpOverrideAppearsInProbeOutput(t *testing.T) {
+	client := &fakeClient{
+		sizes: map[string]*rclone.RcloneSizeOutput{"remote:": {Count: 1, Bytes: 1}},
+	}
+	e := NewExporterWithConfig(client, Config{
+		HelpOverrides: map[string]string{"rclone_remote_size_bytes": "Custom size help."},
+	})
+	defer e.Close()
+
+	rec := httptest.NewRecorder()
+	e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=remote:", nil))
+
+	if !strings.Contains(rec.Body.String(), "# HELP rclone_remote_size_bytes Custom size help.") {
+		t.Errorf("probe output missing overridden HELP:\n%s", rec.Body.String())
+	}
+}
+
+func TestValidateHelpOverrides(t *testing.T) {
+	if err := ValidateHelpOverrides(map[string]string{"rclone_remote_size_bytes": "x"}); err != nil {
+		t.Errorf("ValidateHelpOverrides(known) = %v, want nil", err)
+	}
+	if err := ValidateHelpOverrides(nil); err != nil {
+		t.Errorf("ValidateHelpOverrides(nil) = %v, want nil", err)
+	}
+
+	err := ValidateHelpOverrides(map[string]string{"rclone_remote_size_byte": "typo"})
+	if err == nil || !strings.Contains(err.Error(), "rclone_remote_size_byte") {
+		t.Errorf("ValidateHelpOverrides(typo) = %v, want error naming the key", err)
+	}
+}
@@ -0,0 +1,102 @@
+package exporter
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// labelNameRegex matches valid Prometheus label names
+var labelNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// reservedLabelNames are variable labels of exporter metrics that const labels must not shadow
+var reservedLabelNames = map[string]bool{
+	"remote":      true,
+	"remote_name": true,
+	"remote_type": true,
+	"path":        true,
+	"reason":      true,
+	"version":     true,
+	"commit":      true,
+	"build_date":  true,
+	"go_version":  true,
+	"command":     true,
+	"upstream":    true,
+	"region":      true,
+	"fstype":      true,
+}
+
+// ParseConstLabels parses key=value pairs into const labels applied to every exported metric
+func ParseConstLabels(pairs []string) (prometheus.Labels, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+
+	labels := make(prometheus.Labels, len(pairs))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("const label %q must be in key=value form", pair)
+		}
+
+		if !labelNameRegex.MatchString(name) || strings.HasPrefix(name, "__") {
+			return nil, fmt.Errorf("invalid const label name %q", name)
+		}
+
+		if reservedLabelNames[name] {
+			return nil, fmt.Errorf("const label name %q is already used by exporter metrics", name)
+		}
+
+		if _, exists := labels[name]; exists {
+			return nil, fmt.Errorf("duplicate const label name %q", name)
+		}
+
+		labels[name] = value
+	}
+
+	return labels, nil
+}
+
+// ValidateRemoteLabels checks the per-remote labels from the config file. Their names
+// must be valid, must not shadow exporter labels and must not repeat a const label.
+func ValidateRemoteLabels(remoteLabels map[string]map[string]string, constLabels prometheus.Labels) error {
+	for remote, labels := range remoteLabels {
+		for name := range labels {
+			if !labelNameRegex.MatchString(name) || strings.HasPrefix(name, "__") {
+				return fmt.Errorf("invalid label name %q for remote %q", name, remote)
+			}
+
+			if reservedLabelNames[name] {
+				return fmt.Errorf("label name %q for remote %q is already used by exporter metrics", name, remote)
+			}
+
+			if _, exists := constLabels[name]; exists {
+				return fmt.Errorf("label name %q for remote %q is already a const label", name, remote)
+			}
+		}
+	}
+
+	return nil
+}
+
+// remoteLabelNames returns the sorted union of the label names of all remotes.
+// rclone_remote_meta carries all of them so its label set is the same for every remote.
+func remoteLabelNames(remoteLabels map[string]map[string]string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, labels := range remoteLabels {
+		for name := range labels {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
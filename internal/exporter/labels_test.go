@@ -0,0 +1,76 @@
+package exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/crazyuploader/rclone_exporter/internal/rclone"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func TestParseConstLabels(t *testing.T) {
+	tests := []struct {
+		pairs   []string
+		wantErr bool
+	}{
+		{pairs: nil},
+		{pairs: []string{"environment=prod", "datacenter=eu-1"}},
+		{pairs: []string{"empty="}},
+		{pairs: []string{"novalue"}, wantErr: true},
+		{pairs: []string{"1bad=x"}, wantErr: true},
+		{pairs: []string{"__reserved=x"}, wantErr: true},
+		{pairs: []string{"remote=x"}, wantErr: true},
+		{pairs: []string{"env=a", "env=b"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if _, err := ParseConstLabels(tt.pairs); (err != nil) != tt.wantErr {
+			t.Errorf("ParseConstLabels(%q) error = %v, wantErr %v", tt.pairs, err, tt.wantErr)
+		}
+	}
+}
+
+func TestConstLabelsAppearOnAllOutput(t *testing.T) {
+	labels, err := ParseConstLabels([]string{"environment=prod", "datacenter=eu-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &fakeClient{sizes: map[string]*rclone.RcloneSizeOutput{"remote:": {Count: 1, Bytes: 2}}}
+	e := NewExporterWithConfig(client, Config{ConstLabels: labels})
+	defer e.Close()
+
+	rec := httptest.NewRecorder()
+	e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=remote:", nil))
+	for _, want := range []string{
+		`rclone_remote_size_bytes{datacenter="eu-1",environment="prod",path="/",remote="remote:",remote_name="remote",remote_type="unknown"} 2`,
+		`rclone_exporter_probe_requests_total{datacenter="eu-1",environment="prod"} 1`,
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("probe output missing %q\n%s", want, rec.Body)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	promhttp.HandlerFor(e.Registry(), promhttp.HandlerOpts{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if want := `rclone_exporter_probe_requests_total{datacenter="eu-1",environment="prod"} 1`; !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("/metrics missing %q\n%s", want, rec.Body)
+	}
+}
+
+func TestValidateRemoteLabels(t *testing.T) {
+	constLabels := prometheus.Labels{"env": "prod"}
+
+	if err := ValidateRemoteLabels(map[string]map[string]string{"gdrive": {"team": "data"}}, constLabels); err != nil {
+		t.Errorf("valid labels rejected: %v", err)
+	}
+
+	for _, name := range []string{"remote", "region", "env", "9lives", "__meta"} {
+		if err := ValidateRemoteLabels(map[string]map[string]string{"gdrive": {name: "x"}}, constLabels); err == nil {
+			t.Errorf("label name %q accepted, want error", name)
+		}
+	}
+}
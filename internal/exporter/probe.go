@@ -0,0 +1,1446 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crazyuploader/rclone_exporter/internal/logging"
+	"github.com/crazyuploader/rclone_exporter/internal/rclone"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/expfmt"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// ProbeAllRemotes is the special remote value that probes every configured remote
+const ProbeAllRemotes = "all"
+
+// Probe commands selectable via the command query parameter (mode is accepted as an alias)
+const (
+	ProbeModeSize   = "size"   // rclone size: bytes and object count (default)
+	ProbeModeDirs   = "dirs"   // rclone lsf --dirs-only: directory count only
+	ProbeModeAbout  = "about"  // rclone about: quota and free space
+	ProbeModeLarge  = "large"  // rclone size --min-size: count and bytes of objects above minsize
+	ProbeModeDedupe = "dedupe" // rclone dedupe --dry-run: number of duplicate files
+)
+
+// Reasons of rclone_exporter_probe_rejected_total
+const (
+	RejectReasonConcurrency = "concurrency" // Every probe slot was taken
+)
+
+// ProbeRejectedHeader carries the status of a probe rejected before rclone ran, such as
+// 400 or 429, since the response itself is a 200 so Prometheus keeps probe_success 0
+const ProbeRejectedHeader = "X-Probe-Rejected-Status"
+
+// ProbeCacheOnly is the cache parameter value that serves cached results without running rclone
+const ProbeCacheOnly = "only"
+
+// errCacheMiss is returned by cache-only probes without a fresh cached result
+var errCacheMiss = errors.New("no fresh cached result")
+
+// probeOptions holds the per-request settings of a probe
+type probeOptions struct {
+	rclone rclone.ProbeOptions
+	mode   string // One of the ProbeMode* constants
+	format string // One of the ProbeFormat* constants
+
+	// upstreams also probes each upstream of union and combine remotes
+	upstreams bool
+	// storageClasses also counts the objects of S3 remotes by storage class
+	storageClasses bool
+	// cacheOnly serves fresh cached size results and never runs rclone
+	cacheOnly bool
+	// maintenance makes cache-only probes serve cached results of any age
+	maintenance bool
+	// noCache skips cached size results and remote types; the fresh results are cached
+	noCache bool
+	// countOnly makes size probes count objects without summing their sizes
+	countOnly bool
+	// extendDeadline, when set, makes room in the response deadline for extra rclone runs
+	extendDeadline func(extraRuns int)
+	// logger is the request's path-scoped logger; nil uses the global logger
+	logger *zerolog.Logger
+	// received is when the probe request reached the handler
+	received time.Time
+	// binary is the alias of the rclone binary selected with the binary parameter,
+	// empty for the default binary
+	binary string
+}
+
+// log returns the logger of the probe request
+func (o probeOptions) log() *zerolog.Logger {
+	if o.logger != nil {
+		return o.logger
+	}
+	return &log.Logger
+}
+
+// probeTarget identifies the remote a probe command runs against, with its metric labels
+type probeTarget struct {
+	remote     string
+	remoteName string
+	remotePath string
+	remoteType string
+	result     *probeResult  // JSON report entry filled in by the probe command
+	client     rclone.Client // Client of the rclone binary selected for the probe
+}
+
+// probeCommand runs one rclone command for a probe and records its metrics in m
+type probeCommand func(e *Exporter, m *probeMetrics, t probeTarget, opts probeOptions) error
+
+// probeCommands maps each command query value to its implementation. Adding a new
+// JSON-emitting rclone command only needs a new entry here.
+var probeCommands = map[string]probeCommand{
+	ProbeModeSize:   (*Exporter).probeSize,
+	ProbeModeDirs:   (*Exporter).probeDirs,
+	ProbeModeAbout:  (*Exporter).probeAbout,
+	ProbeModeLarge:  (*Exporter).probeLarge,
+	ProbeModeDedupe: (*Exporter).probeDedupe,
+}
+
+// parseCommand returns the probe command selected by the command (or legacy mode)
+// query parameter, defaulting to size
+func parseCommand(command, mode string) (string, error) {
+	if command != "" && mode != "" && command != mode {
+		return "", fmt.Errorf("command and mode disagree (%q vs %q)", command, mode)
+	}
+	if command == "" {
+		command = mode
+	}
+	if command == "" {
+		return ProbeModeSize, nil
+	}
+
+	if _, ok := probeCommands[command]; !ok {
+		names := make([]string, 0, len(probeCommands))
+		for name := range probeCommands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return "", fmt.Errorf("command must be one of: %s", strings.Join(names, ", "))
+	}
+
+	return command, nil
+}
+
+// probeMetrics holds the metric vectors emitted for a single probe request
+type probeMetrics struct {
+	sizeBytes            *prometheus.GaugeVec
+	sizeDeltaBytes       *prometheus.GaugeVec
+	objectsCount         *prometheus.GaugeVec
+	probeSuccess         *prometheus.GaugeVec
+	probeDurationSeconds *prometheus.GaugeVec
+	probeInfo            *prometheus.GaugeVec
+	remoteAnomaly        *prometheus.GaugeVec
+	dirsCount            *prometheus.GaugeVec
+	duplicates           *prometheus.GaugeVec
+	largeObjectsCount    *prometheus.GaugeVec
+	largeObjectsBytes    *prometheus.GaugeVec
+	quotaTotalBytes      *prometheus.GaugeVec
+	quotaUsedBytes       *prometheus.GaugeVec
+	quotaFreeBytes       *prometheus.GaugeVec
+	freePercent          *prometheus.GaugeVec
+	spaceLow             *prometheus.GaugeVec
+	consecutiveFailures  *prometheus.GaugeVec
+	upstreamSizeBytes    *prometheus.GaugeVec
+	upstreamObjects      *prometheus.GaugeVec
+	objectsByClass       *prometheus.GaugeVec
+	resultAgeSeconds     *prometheus.GaugeVec
+	cacheHit             *prometheus.GaugeVec
+	trashedBytes         *prometheus.GaugeVec
+	trashedBytesPresent  *prometheus.GaugeVec
+	s3RegionInfo         *prometheus.GaugeVec
+	localFilesystemInfo  *prometheus.GaugeVec
+	totalDurationSeconds prometheus.Gauge
+	remoteMeta           *prometheus.GaugeVec
+	probeWarnings        *prometheus.GaugeVec
+	tokenInvalid         *prometheus.GaugeVec
+	seriesCount          prometheus.Gauge
+	report               probeReport
+}
+
+// newProbeRegistry creates a fresh registry holding the probe metrics and the global counters
+func (e *Exporter) newProbeRegistry() (*prometheus.Registry, *probeMetrics) {
+	probeRegistry := prometheus.NewRegistry()
+	m := e.newProbeMetrics()
+
+	// Register probe-specific metrics with the probe registry, adding the const labels
+	registerer := prometheus.WrapRegistererWith(e.config.ConstLabels, probeRegistry)
+	registerer.MustRegister(m.remoteCollectors()...)
+	registerer.MustRegister(m.consecutiveFailures)
+	registerer.MustRegister(m.totalDurationSeconds)
+	registerer.MustRegister(m.seriesCount)
+
+	// Also register the global metrics so they appear in probe output
+	registerer.MustRegister(e.scrapeErrorsTotal)
+	registerer.MustRegister(e.probeRequestsTotal)
+	registerer.MustRegister(e.slowProbesTotal)
+	registerer.MustRegister(e.probesInFlight)
+	registerer.MustRegister(e.maxConcurrent)
+
+	return probeRegistry, m
+}
+
+// newProbeMetrics creates unregistered metric vectors for a single probe
+func (e *Exporter) newProbeMetrics() *probeMetrics {
+	// Create metrics for this specific probe with enhanced labels including remote_type
+	return &probeMetrics{
+		sizeBytes: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "remote",
+				Name:      "size_bytes",
+				Help:      e.help("remote", "size_bytes"),
+			},
+			[]string{"remote", "remote_name", "path", "remote_type"},
+		),
+		sizeDeltaBytes: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "remote",
+				Name:      "size_delta_bytes",
+				Help:      e.help("remote", "size_delta_bytes"),
+			},
+			[]string{"remote", "remote_name", "path", "remote_type"},
+		),
+		objectsCount: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "remote",
+				Name:      "objects_count",
+				Help:      e.help("remote", "objects_count"),
+			},
+			[]string{"remote", "remote_name", "path", "remote_type"},
+		),
+		probeSuccess: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "probe",
+				Name:      "success",
+				Help:      e.help("probe", "success"),
+			},
+			[]string{"remote", "remote_name", "remote_type"},
+		),
+		probeDurationSeconds: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "probe",
+				Name:      "duration_seconds",
+				Help:      e.help("probe", "duration_seconds"),
+			},
+			[]string{"remote", "remote_name", "remote_type"},
+		),
+		probeInfo: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "probe",
+				Name:      "info",
+				Help:      e.help("probe", "info"),
+			},
+			[]string{"remote", "remote_name", "path", "remote_type", "backend_type"},
+		),
+		remoteAnomaly: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "remote",
+				Name:      "anomaly",
+				Help:      e.help("remote", "anomaly"),
+			},
+			[]string{"remote", "remote_name", "remote_type", "reason"},
+		),
+		dirsCount: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "remote",
+				Name:      "dirs_count",
+				Help:      e.help("remote", "dirs_count"),
+			},
+			[]string{"remote", "remote_name", "path", "remote_type"},
+		),
+		duplicates: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "remote",
+				Name:      "duplicates",
+				Help:      e.help("remote", "duplicates"),
+			},
+			[]string{"remote", "remote_name", "path", "remote_type"},
+		),
+		largeObjectsCount: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "remote",
+				Name:      "large_objects_count",
+				Help:      e.help("remote", "large_objects_count"),
+			},
+			[]string{"remote", "remote_name", "path", "remote_type", "min_size"},
+		),
+		largeObjectsBytes: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "remote",
+				Name:      "large_objects_bytes",
+				Help:      e.help("remote", "large_objects_bytes"),
+			},
+			[]string{"remote", "remote_name", "path", "remote_type", "min_size"},
+		),
+		quotaTotalBytes: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "remote",
+				Name:      "quota_total_bytes",
+				Help:      e.help("remote", "quota_total_bytes"),
+			},
+			[]string{"remote", "remote_name", "remote_type"},
+		),
+		quotaUsedBytes: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "remote",
+				Name:      "quota_used_bytes",
+				Help:      e.help("remote", "quota_used_bytes"),
+			},
+			[]string{"remote", "remote_name", "remote_type"},
+		),
+		quotaFreeBytes: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "remote",
+				Name:      "quota_free_bytes",
+				Help:      e.help("remote", "quota_free_bytes"),
+			},
+			[]string{"remote", "remote_name", "remote_type"},
+		),
+		freePercent: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "remote",
+				Name:      "free_percent",
+				Help:      e.help("remote", "free_percent"),
+			},
+			[]string{"remote", "remote_name", "remote_type"},
+		),
+		spaceLow: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "remote",
+				Name:      "space_low",
+				Help:      e.help("remote", "space_low"),
+			},
+			[]string{"remote", "remote_name", "remote_type"},
+		),
+		consecutiveFailures: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "remote",
+				Name:      "consecutive_failures",
+				Help:      e.help("remote", "consecutive_failures"),
+			},
+			[]string{"remote", "remote_name", "remote_type"},
+		),
+		upstreamSizeBytes: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "remote",
+				Name:      "upstream_size_bytes",
+				Help:      e.help("remote", "upstream_size_bytes"),
+			},
+			[]string{"remote", "remote_name", "upstream"},
+		),
+		upstreamObjects: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "remote",
+				Name:      "upstream_objects_count",
+				Help:      e.help("remote", "upstream_objects_count"),
+			},
+			[]string{"remote", "remote_name", "upstream"},
+		),
+		objectsByClass: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "remote",
+				Name:      "objects_by_storage_class",
+				Help:      e.help("remote", "objects_by_storage_class"),
+			},
+			[]string{"remote", "remote_name", "class"},
+		),
+		resultAgeSeconds: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "probe",
+				Name:      "result_age_seconds",
+				Help:      e.help("probe", "result_age_seconds"),
+			},
+			[]string{"remote", "remote_name", "remote_type"},
+		),
+		cacheHit: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "remote",
+				Name:      "cache_hit",
+				Help:      e.help("remote", "cache_hit"),
+			},
+			[]string{"remote", "remote_name", "remote_type"},
+		),
+		trashedBytes: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "remote",
+				Name:      "trashed_bytes",
+				Help:      e.help("remote", "trashed_bytes"),
+			},
+			[]string{"remote", "remote_name", "remote_type"},
+		),
+		trashedBytesPresent: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "remote",
+				Name:      "trashed_bytes_present",
+				Help:      e.help("remote", "trashed_bytes_present"),
+			},
+			[]string{"remote", "remote_name", "remote_type"},
+		),
+		s3RegionInfo: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "s3",
+				Name:      "region_info",
+				Help:      e.help("s3", "region_info"),
+			},
+			[]string{"remote", "remote_name", "region"},
+		),
+		localFilesystemInfo: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "local",
+				Name:      "filesystem_info",
+				Help:      e.help("local", "filesystem_info"),
+			},
+			[]string{"remote", "remote_name", "path", "fstype"},
+		),
+		totalDurationSeconds: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "probe",
+				Name:      "total_duration_seconds",
+				Help:      e.help("probe", "total_duration_seconds"),
+			},
+		),
+		remoteMeta: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "remote",
+				Name:      "meta",
+				Help:      e.help("remote", "meta"),
+			},
+			append([]string{"remote", "remote_name"}, e.remoteLabelNames...),
+		),
+		probeWarnings: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "remote",
+				Name:      "probe_warnings",
+				Help:      e.help("remote", "probe_warnings"),
+			},
+			[]string{"remote", "remote_name", "remote_type"},
+		),
+		tokenInvalid: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "remote",
+				Name:      "token_invalid",
+				Help:      e.help("remote", "token_invalid"),
+			},
+			[]string{"remote", "remote_name", "remote_type"},
+		),
+		seriesCount: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "exporter",
+				Name:      "probe_series_count",
+				Help:      e.help("exporter", "probe_series_count"),
+			},
+		),
+	}
+}
+
+// remoteCollectors returns the probe metrics labeled by remote. The unlabeled total
+// duration is left out, and so is the failure streak: probe registries add it on their
+// own, while /metrics carries the exporter-wide gauge with the same labels instead.
+func (m *probeMetrics) remoteCollectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.sizeBytes,
+		m.sizeDeltaBytes,
+		m.objectsCount,
+		m.probeSuccess,
+		m.probeDurationSeconds,
+		m.probeInfo,
+		m.remoteAnomaly,
+		m.dirsCount,
+		m.duplicates,
+		m.largeObjectsCount,
+		m.largeObjectsBytes,
+		m.quotaTotalBytes,
+		m.quotaUsedBytes,
+		m.quotaFreeBytes,
+		m.freePercent,
+		m.spaceLow,
+		m.upstreamSizeBytes,
+		m.upstreamObjects,
+		m.objectsByClass,
+		m.resultAgeSeconds,
+		m.cacheHit,
+		m.trashedBytes,
+		m.trashedBytesPresent,
+		m.s3RegionInfo,
+		m.localFilesystemInfo,
+		m.remoteMeta,
+		m.probeWarnings,
+		m.tokenInvalid,
+	}
+}
+
+// rcloneTimeout returns the timeout the probe's rclone runs get: the configured probe
+// timeout, or the shorter one derived from the scrape timeout of the request
+func (e *Exporter) rcloneTimeout(opts probeOptions) time.Duration {
+	timeout := e.config.ProbeTimeout
+	if override := opts.rclone.Timeout; override > 0 && (timeout <= 0 || override < timeout) {
+		timeout = override
+	}
+	return timeout
+}
+
+// probeRemote runs the selected probe command against a single remote and records the results in m
+func (e *Exporter) probeRemote(m *probeMetrics, remote string, opts probeOptions) (err error) {
+	// Runs last, once the global metrics of the probe are recorded
+	defer e.invalidateRenderedMetrics()
+
+	start := time.Now()
+	client := e.client(opts)
+
+	// Parse remote to extract name and path for better labeling
+	remoteName, remotePath := parseRemoteName(remote)
+
+	// Get remote type (best effort - default to "unknown" if fails). Connection
+	// strings name their backend directly and have no config entry.
+	remoteType, isConnectionString := connectionStringBackend(remoteName)
+	if !isConnectionString && opts.cacheOnly {
+		// Cache-only probes must not run rclone config dump
+		var cached bool
+		if remoteType, cached = client.CachedRemoteType(remoteName); !cached {
+			remoteType = "unknown"
+		}
+	} else if !isConnectionString {
+		// Drop the cached type so it is read from a fresh config dump and cached again
+		if opts.noCache {
+			client.InvalidateCache(remoteName)
+		}
+
+		var typeErr error
+		remoteType, typeErr = client.GetRemoteType(remoteName)
+		if typeErr != nil {
+			opts.log().Debug().
+				Err(typeErr).
+				Str("remote", remoteName).
+				Msg("Failed to detect remote type, using 'unknown'")
+			remoteType = "unknown"
+		}
+	}
+
+	// Crypt and other wrapping remotes also report the backend that stores the data
+	backendType := remoteType
+	if !isConnectionString && rclone.IsWrappingBackend(remoteType) {
+		backendType = e.backendType(client, remoteName, opts)
+	}
+
+	result := &probeResult{
+		Remote:     remote,
+		RemoteName: remoteName,
+		Path:       remotePath,
+		RemoteType: remoteType,
+		Command:    opts.mode,
+	}
+	if backendType != remoteType {
+		result.BackendType = backendType
+	}
+	m.report.add(result)
+
+	// Track the failure streak once the probe outcome is known
+	defer func() {
+		result.Success = err == nil
+		if err != nil {
+			result.Error = err.Error()
+		}
+
+		// A cache miss says nothing about the health of the remote
+		if errors.Is(err, errCacheMiss) {
+			return
+		}
+
+		// An expired or revoked OAuth token needs a human, not a retry
+		tokenInvalid := 0.0
+		if result.TokenInvalid = rclone.IsTokenError(err); result.TokenInvalid {
+			tokenInvalid = 1
+			opts.log().Warn().
+				Str("remote", remote).
+				Str("remote_type", remoteType).
+				Msgf("OAuth token of the remote is invalid, run: rclone config reconnect %s:", remoteName)
+		}
+		m.tokenInvalid.WithLabelValues(remote, remoteName, remoteType).Set(tokenInvalid)
+
+		failures := e.recordProbeResult(remote, remoteName, remoteType, err)
+		m.consecutiveFailures.WithLabelValues(remote, remoteName, remoteType).Set(float64(failures))
+	}()
+
+	// Set probe info metric with type
+	m.probeInfo.WithLabelValues(remote, remoteName, remotePath, remoteType, backendType).Set(1)
+
+	// Configured labels go on a separate info metric so other metrics keep a fixed label set
+	if labels, ok := e.config.RemoteLabels[remoteName]; ok {
+		values := []string{remote, remoteName}
+		for _, name := range e.remoteLabelNames {
+			values = append(values, labels[name])
+		}
+		m.remoteMeta.WithLabelValues(values...).Set(1)
+	}
+
+	// Always update probe duration, even on failure
+	defer func() {
+		elapsed := time.Since(start)
+		duration := elapsed.Seconds()
+		result.DurationSeconds = duration
+		m.probeDurationSeconds.WithLabelValues(remote, remoteName, remoteType).Set(duration)
+		e.probeDuration.WithLabelValues(remote, opts.mode).Observe(duration)
+		opts.log().Debug().
+			Str("remote", remote).
+			Str("remote_type", remoteType).
+			Float64("duration_seconds", duration).
+			Msg("Probe completed")
+
+		if threshold := e.config.SlowProbeThreshold; threshold > 0 && elapsed > threshold {
+			e.slowProbesTotal.Inc()
+			opts.log().Warn().
+				Str("remote", remote).
+				Str("remote_type", remoteType).
+				Dur("duration", elapsed).
+				Dur("threshold", threshold).
+				Msg("Slow probe detected")
+		}
+
+		// Only a successful rclone run shows how close the remote is to the timeout
+		if timeout := e.rcloneTimeout(opts); timeout > 0 && err == nil && !result.cached {
+			e.probeTimeoutRatio.WithLabelValues(remote).Set(elapsed.Seconds() / timeout.Seconds())
+		}
+	}()
+
+	target := probeTarget{remote: remote, remoteName: remoteName, remotePath: remotePath, remoteType: remoteType, result: result, client: client}
+	if e.config.EnrichRemoteTypes && !opts.cacheOnly {
+		e.enrichRemote(m, target)
+	}
+
+	if err := probeCommands[opts.mode](e, m, target, opts); err != nil {
+		m.probeSuccess.WithLabelValues(remote, remoteName, remoteType).Set(0)
+		return err
+	}
+
+	m.probeSuccess.WithLabelValues(remote, remoteName, remoteType).Set(1)
+	return nil
+}
+
+// backendType resolves the backend under a wrapping remote, best effort like the remote type
+func (e *Exporter) backendType(client rclone.Client, remoteName string, opts probeOptions) string {
+	if opts.cacheOnly {
+		if backendType, cached := client.CachedBackendType(remoteName); cached {
+			return backendType
+		}
+		return "unknown"
+	}
+
+	backendType, err := client.GetBackendType(remoteName)
+	if err != nil {
+		opts.log().Debug().
+			Err(err).
+			Str("remote", remoteName).
+			Msg("Failed to resolve backend type, using 'unknown'")
+		return "unknown"
+	}
+	return backendType
+}
+
+// probeSize runs rclone size, sharing one rclone run between identical concurrent probes
+func (e *Exporter) probeSize(m *probeMetrics, t probeTarget, opts probeOptions) error {
+	if opts.countOnly {
+		return e.probeCount(m, t, opts)
+	}
+
+	size, err := e.coalescedRemoteSize(t, t.remote, opts)
+	if err != nil {
+		if errors.Is(err, errCacheMiss) {
+			m.cacheHit.WithLabelValues(t.remote, t.remoteName, t.remoteType).Set(0)
+		}
+		return err
+	}
+	output := size.output
+	t.result.cached = size.cached
+
+	cacheHit := 0.0
+	if size.cached {
+		cacheHit = 1
+	}
+	m.cacheHit.WithLabelValues(t.remote, t.remoteName, t.remoteType).Set(cacheHit)
+	m.resultAgeSeconds.WithLabelValues(t.remote, t.remoteName, t.remoteType).Set(size.age.Seconds())
+
+	// Update metrics with labels including remote type
+	m.sizeBytes.WithLabelValues(t.remote, t.remoteName, t.remotePath, t.remoteType).Set(float64(output.Bytes))
+	m.objectsCount.WithLabelValues(t.remote, t.remoteName, t.remotePath, t.remoteType).Set(float64(output.Count))
+	t.result.setBytes(output.Bytes)
+	objects := output.Count
+	t.result.Objects = &objects
+
+	// Cached results repeat an earlier total, so only fresh ones update the delta
+	// and the distribution. Depth-limited probes of a remote are tracked apart.
+	if !size.cached {
+		e.observeObjectCount(output.Count)
+		key := resultKey(opts.binary, t.remote, opts.rclone)
+		if delta, ok := e.previousSizes.record(key, output.Bytes); ok {
+			m.sizeDeltaBytes.WithLabelValues(t.remote, t.remoteName, t.remotePath, t.remoteType).Set(float64(delta))
+		}
+	}
+	m.probeWarnings.WithLabelValues(t.remote, t.remoteName, t.remoteType).Set(float64(output.Warnings))
+	if output.Warnings > 0 {
+		warnings := output.Warnings
+		t.result.Warnings = &warnings
+	}
+
+	if reason := detectAnomaly(output); reason != "" {
+		m.remoteAnomaly.WithLabelValues(t.remote, t.remoteName, t.remoteType, reason).Set(1)
+		opts.log().Warn().
+			Str("remote", t.remote).
+			Str("remote_type", t.remoteType).
+			Str("reason", reason).
+			Int64("bytes", output.Bytes).
+			Int64("objects", output.Count).
+			Msg("Probe result looks anomalous")
+	}
+
+	opts.log().Debug().
+		Str("remote", t.remote).
+		Str("remote_type", t.remoteType).
+		Int64("bytes", output.Bytes).
+		Int64("objects", output.Count).
+		Msg("Probe successful")
+
+	if opts.upstreams {
+		e.probeUpstreams(m, t, opts)
+	}
+	if opts.storageClasses {
+		e.probeStorageClasses(m, t, opts)
+	}
+
+	return nil
+}
+
+// probeUpstreams runs rclone size against each upstream of a union or combine remote.
+// Upstream failures are logged and skipped so they never fail the merged probe.
+func (e *Exporter) probeUpstreams(m *probeMetrics, t probeTarget, opts probeOptions) {
+	if t.remoteType != "union" && t.remoteType != "combine" {
+		return
+	}
+
+	upstreams, err := t.client.GetUpstreams(t.remoteName)
+	if err != nil {
+		opts.log().Warn().
+			Err(err).
+			Str("remote", t.remote).
+			Msg("Failed to read upstreams")
+		return
+	}
+
+	if opts.extendDeadline != nil {
+		opts.extendDeadline(len(upstreams))
+	}
+
+	for _, upstream := range upstreams {
+		size, err := e.coalescedRemoteSize(t, upstream.Remote, opts)
+		if err != nil {
+			opts.log().Warn().
+				Err(err).
+				Str("remote", t.remote).
+				Str("upstream", upstream.Remote).
+				Msg("Upstream probe failed")
+			continue
+		}
+
+		m.upstreamSizeBytes.WithLabelValues(t.remote, t.remoteName, upstream.Name).Set(float64(size.output.Bytes))
+		m.upstreamObjects.WithLabelValues(t.remote, t.remoteName, upstream.Name).Set(float64(size.output.Count))
+	}
+}
+
+// probeStorageClasses counts the objects of an S3 remote by storage class with an extra
+// listing. Other remote types have no storage classes and are skipped, and a failed
+// listing is logged without failing the probe.
+func (e *Exporter) probeStorageClasses(m *probeMetrics, t probeTarget, opts probeOptions) {
+	if t.remoteType != "s3" {
+		return
+	}
+
+	if opts.extendDeadline != nil {
+		opts.extendDeadline(1)
+	}
+
+	classes, err := t.client.GetRemoteStorageClasses(t.remote, opts.rclone)
+	if err != nil {
+		opts.log().Warn().
+			Err(err).
+			Str("remote", t.remote).
+			Msg("Storage class listing failed")
+		return
+	}
+
+	for class, objects := range classes {
+		m.objectsByClass.WithLabelValues(t.remote, t.remoteName, class).Set(float64(objects))
+	}
+	t.result.StorageClasses = classes
+}
+
+// probeCount reports the object count from a listing, without the byte total
+func (e *Exporter) probeCount(m *probeMetrics, t probeTarget, opts probeOptions) error {
+	objects, err := t.client.GetRemoteObjectCount(t.remote, opts.rclone)
+	if err != nil {
+		return err
+	}
+
+	m.objectsCount.WithLabelValues(t.remote, t.remoteName, t.remotePath, t.remoteType).Set(float64(objects))
+	t.result.Objects = &objects
+	e.observeObjectCount(objects)
+	if opts.storageClasses {
+		e.probeStorageClasses(m, t, opts)
+	}
+
+	opts.log().Debug().
+		Str("remote", t.remote).
+		Str("remote_type", t.remoteType).
+		Int64("objects", objects).
+		Msg("Count-only probe successful")
+	return nil
+}
+
+// probeDirs only lists directories and skips the size computation
+func (e *Exporter) probeDirs(m *probeMetrics, t probeTarget, opts probeOptions) error {
+	dirs, err := t.client.GetRemoteDirCount(t.remote, opts.rclone)
+	if err != nil {
+		return err
+	}
+
+	m.dirsCount.WithLabelValues(t.remote, t.remoteName, t.remotePath, t.remoteType).Set(float64(dirs))
+	t.result.Dirs = &dirs
+	return nil
+}
+
+// probeLarge counts the objects of at least the minsize threshold
+func (e *Exporter) probeLarge(m *probeMetrics, t probeTarget, opts probeOptions) error {
+	size, err := e.coalescedRemoteSize(t, t.remote, opts)
+	if err != nil {
+		return err
+	}
+	output, minSize := size.output, opts.rclone.MinSize
+	t.result.cached = size.cached
+
+	m.largeObjectsCount.WithLabelValues(t.remote, t.remoteName, t.remotePath, t.remoteType, minSize).Set(float64(output.Count))
+	m.largeObjectsBytes.WithLabelValues(t.remote, t.remoteName, t.remotePath, t.remoteType, minSize).Set(float64(output.Bytes))
+	t.result.setBytes(output.Bytes)
+	objects := output.Count
+	t.result.Objects = &objects
+
+	opts.log().Debug().
+		Str("remote", t.remote).
+		Str("min_size", minSize).
+		Int64("bytes", output.Bytes).
+		Int64("objects", output.Count).
+		Msg("Large object probe successful")
+	return nil
+}
+
+// probeDedupe counts duplicate files with a dedupe dry run, which changes nothing
+func (e *Exporter) probeDedupe(m *probeMetrics, t probeTarget, opts probeOptions) error {
+	duplicates, err := t.client.GetRemoteDuplicates(t.remote)
+	if err != nil {
+		return err
+	}
+
+	m.duplicates.WithLabelValues(t.remote, t.remoteName, t.remotePath, t.remoteType).Set(float64(duplicates))
+	t.result.Duplicates = &duplicates
+	return nil
+}
+
+// probeAbout reports quota instead of walking the remote
+func (e *Exporter) probeAbout(m *probeMetrics, t probeTarget, opts probeOptions) error {
+	about, err := t.client.GetRemoteAbout(t.remote)
+	if err != nil {
+		return err
+	}
+
+	e.recordAbout(m, about, t, opts)
+	return nil
+}
+
+// recordAbout sets the quota metrics reported by the backend. Values the backend
+// does not report are left out rather than exported as zero.
+func (e *Exporter) recordAbout(m *probeMetrics, about *rclone.RcloneAboutOutput, t probeTarget, opts probeOptions) {
+	remote, remoteName, remoteType := t.remote, t.remoteName, t.remoteType
+	t.result.QuotaTotalBytes, t.result.QuotaUsedBytes, t.result.QuotaFreeBytes = about.Total, about.Used, about.Free
+	t.result.TrashedBytes = about.Trashed
+	if about.Total != nil {
+		m.quotaTotalBytes.WithLabelValues(remote, remoteName, remoteType).Set(float64(*about.Total))
+	}
+	if about.Used != nil {
+		m.quotaUsedBytes.WithLabelValues(remote, remoteName, remoteType).Set(float64(*about.Used))
+	}
+	if about.Free != nil {
+		m.quotaFreeBytes.WithLabelValues(remote, remoteName, remoteType).Set(float64(*about.Free))
+	}
+
+	// Distinguish "nothing in the trash" from "backend does not report trash"
+	if about.Trashed != nil {
+		m.trashedBytes.WithLabelValues(remote, remoteName, remoteType).Set(float64(*about.Trashed))
+		m.trashedBytesPresent.WithLabelValues(remote, remoteName, remoteType).Set(1)
+	} else {
+		m.trashedBytesPresent.WithLabelValues(remote, remoteName, remoteType).Set(0)
+	}
+
+	percent, ok := about.FreePercent()
+	if !ok {
+		return
+	}
+	m.freePercent.WithLabelValues(remote, remoteName, remoteType).Set(percent)
+	t.result.FreePercent = &percent
+
+	if threshold := e.config.FreePercentThreshold; threshold > 0 {
+		low := 0.0
+		if percent < threshold {
+			low = 1
+			opts.log().Warn().
+				Str("remote", remote).
+				Str("remote_type", remoteType).
+				Float64("free_percent", percent).
+				Float64("threshold", threshold).
+				Msg("Remote free space below threshold")
+		}
+		m.spaceLow.WithLabelValues(remote, remoteName, remoteType).Set(low)
+	}
+}
+
+// sizeResult is a size probe result and where it came from
+type sizeResult struct {
+	output *rclone.RcloneSizeOutput
+	age    time.Duration // How old the result is, 0 when freshly computed
+	cached bool          // Whether the result was served from the size cache
+}
+
+// resultKey identifies the size results of a remote probed with a binary and options.
+// The timeout only bounds how long a probe may wait, so it does not split cached results.
+// In-flight runs are still only shared between callers with the same timeout, see
+// coalescedRemoteSize.
+func resultKey(binary, remote string, opts rclone.ProbeOptions) string {
+	opts.Timeout = 0
+	return fmt.Sprintf("%s|%s|%+v", binary, remote, opts)
+}
+
+// coalescedRemoteSize returns the size of the remote, serving fresh cached results when the
+// size cache is enabled and otherwise running rclone size. Concurrent callers with the same
+// remote, binary and options share a single rclone execution and its result. With cacheOnly
+// set, rclone never runs and errCacheMiss is returned when no fresh result is cached.
+func (e *Exporter) coalescedRemoteSize(t probeTarget, remote string, probeOpts probeOptions) (sizeResult, error) {
+	opts, cacheOnly := probeOpts.rclone, probeOpts.cacheOnly
+	key := resultKey(probeOpts.binary, remote, opts)
+
+	if e.config.SizeCacheTTL > 0 && !probeOpts.noCache {
+		if entry, ok := e.sizes.get(key); ok {
+			if age := time.Since(entry.fetchedAt); e.fresh(age) || probeOpts.maintenance {
+				log.Debug().
+					Str("remote", remote).
+					Dur("age", age).
+					Msg("Serving cached rclone size result")
+				output := entry.output
+				return sizeResult{output: &output, age: age, cached: true}, nil
+			}
+		}
+	}
+
+	if cacheOnly {
+		return sizeResult{}, errCacheMiss
+	}
+
+	// A run bounded by a shorter timeout than the caller's could hand it a timeout it
+	// would not have hit, so only callers with the same timeout join a run. Only
+	// successful results are cached, and those hold whatever the timeout.
+	flightKey := fmt.Sprintf("%s|%s", key, opts.Timeout)
+	result, err, shared := e.sizeGroup.Do(flightKey, func() (interface{}, error) {
+		output, err := t.client.GetRemoteSizeWithOptions(remote, opts)
+		e.countStderrBytes(remote, output, err)
+		if err == nil && e.config.SizeCacheTTL > 0 {
+			e.sizes.set(key, cachedSize{output: *output, fetchedAt: time.Now()})
+		}
+		return output, err
+	})
+
+	if shared {
+		log.Debug().
+			Str("remote", remote).
+			Msg("Shared rclone size result with concurrent identical probe")
+	}
+
+	if err != nil {
+		return sizeResult{}, err
+	}
+	return sizeResult{output: result.(*rclone.RcloneSizeOutput)}, nil
+}
+
+// countStderrBytes adds the stderr output of one rclone size run to
+// rclone_remote_stderr_bytes_total, whether the run succeeded or failed
+func (e *Exporter) countStderrBytes(remote string, output *rclone.RcloneSizeOutput, err error) {
+	var stderrBytes int64
+	var cmdErr *rclone.CommandError
+	switch {
+	case err == nil:
+		stderrBytes = output.StderrBytes
+	case errors.As(err, &cmdErr):
+		stderrBytes = int64(len(cmdErr.Stderr))
+	}
+	e.stderrBytesTotal.WithLabelValues(remote).Add(float64(stderrBytes))
+}
+
+// parseProbeOptions parses and validates the optional query parameters of a probe
+func (e *Exporter) parseProbeOptions(query url.Values) (probeOptions, error) {
+	depth, err := parseDepth(strings.TrimSpace(query.Get("depth")))
+	if err != nil {
+		return probeOptions{}, fmt.Errorf("Invalid depth parameter: %w", err)
+	}
+
+	command, err := parseCommand(strings.TrimSpace(query.Get("command")), strings.TrimSpace(query.Get("mode")))
+	if err != nil {
+		return probeOptions{}, fmt.Errorf("Invalid command parameter: %w", err)
+	}
+
+	format, err := parseFormat(strings.TrimSpace(query.Get("format")))
+	if err != nil {
+		return probeOptions{}, fmt.Errorf("Invalid format parameter: %w", err)
+	}
+
+	upstreams, err := parseBoolParam(strings.TrimSpace(query.Get("upstreams")))
+	if err != nil {
+		return probeOptions{}, fmt.Errorf("Invalid upstreams parameter: %w", err)
+	}
+
+	storageClasses, err := parseBoolParam(strings.TrimSpace(query.Get("storageclass")))
+	if err != nil {
+		return probeOptions{}, fmt.Errorf("Invalid storageclass parameter: %w", err)
+	}
+	if storageClasses && command != ProbeModeSize {
+		return probeOptions{}, fmt.Errorf("Invalid storageclass parameter: storageclass only supports command=%s", ProbeModeSize)
+	}
+
+	minSize, err := parseMinSize(strings.TrimSpace(query.Get("minsize")))
+	if err != nil {
+		return probeOptions{}, fmt.Errorf("Invalid minsize parameter: %w", err)
+	}
+	switch {
+	case command == ProbeModeLarge && minSize == "":
+		return probeOptions{}, fmt.Errorf("Missing minsize parameter: command=%s requires a size threshold", ProbeModeLarge)
+	case command != ProbeModeLarge && minSize != "":
+		return probeOptions{}, fmt.Errorf("Invalid minsize parameter: minsize only supports command=%s", ProbeModeLarge)
+	}
+
+	// size=false selects count-only probes, size=true overrides a count-only default
+	countOnly := e.config.CountOnly && command == ProbeModeSize
+	if value := strings.TrimSpace(query.Get("size")); value != "" {
+		size, err := parseBoolParam(value)
+		if err != nil {
+			return probeOptions{}, fmt.Errorf("Invalid size parameter: %w", err)
+		}
+		if !size && command != ProbeModeSize {
+			return probeOptions{}, fmt.Errorf("Invalid size parameter: size=false only supports command=%s", ProbeModeSize)
+		}
+		countOnly = !size
+	}
+	if countOnly && upstreams {
+		return probeOptions{}, fmt.Errorf("Invalid upstreams parameter: upstreams needs sizes, add size=true")
+	}
+
+	traversal, err := rclone.ParseTraversal(query.Get("traversal"))
+	if err != nil {
+		return probeOptions{}, fmt.Errorf("Invalid traversal parameter: %w", err)
+	}
+	if traversal != "" && (command == ProbeModeAbout || command == ProbeModeDedupe) {
+		return probeOptions{}, fmt.Errorf("Invalid traversal parameter: command=%s does not list recursively", command)
+	}
+
+	noCache, err := parseBoolParam(strings.TrimSpace(query.Get("nocache")))
+	if err != nil {
+		return probeOptions{}, fmt.Errorf("Invalid nocache parameter: %w", err)
+	}
+
+	cacheOnly := false
+	switch cache := strings.TrimSpace(query.Get("cache")); cache {
+	case "":
+	case ProbeCacheOnly:
+		if command != ProbeModeSize {
+			return probeOptions{}, fmt.Errorf("Invalid cache parameter: cache=%s only supports command=%s", ProbeCacheOnly, ProbeModeSize)
+		}
+		if countOnly {
+			return probeOptions{}, fmt.Errorf("Invalid cache parameter: cache=%s needs sizes, add size=true", ProbeCacheOnly)
+		}
+		if noCache {
+			return probeOptions{}, fmt.Errorf("Invalid cache parameter: cache=%s and nocache=true are mutually exclusive", ProbeCacheOnly)
+		}
+		cacheOnly = true
+	default:
+		return probeOptions{}, fmt.Errorf("Invalid cache parameter: cache must be %q", ProbeCacheOnly)
+	}
+
+	return probeOptions{
+		rclone:    rclone.ProbeOptions{MaxDepth: depth, MinSize: minSize, Traversal: traversal},
+		mode:      command,
+		format:    format,
+		upstreams: upstreams,
+		cacheOnly: cacheOnly,
+		noCache:   noCache,
+		countOnly: countOnly,
+		binary:    strings.TrimSpace(query.Get("binary")),
+
+		storageClasses: storageClasses,
+	}, nil
+}
+
+// ProbeHandler handles /probe requests and emits Prometheus metrics.
+func (e *Exporter) ProbeHandler(w http.ResponseWriter, r *http.Request) {
+	received := time.Now()
+	e.probeRequestsTotal.Inc()
+
+	// A plain 503 tells Prometheus the target is going away, without a probe_success 0
+	// that would look like a failing remote
+	if e.Draining() {
+		e.handleError(w, r, r.URL.Query().Get("remote"), "Exporter is shutting down", http.StatusServiceUnavailable, nil)
+		return
+	}
+
+	remote := strings.TrimSpace(r.URL.Query().Get("remote"))
+	if remote == "" {
+		remote = e.config.DefaultRemote
+	}
+	glob := isRemoteGlob(remote)
+	validate := e.validateRemote
+	if glob {
+		validate = validateRemoteGlob
+	}
+	if err := validate(remote); err != nil {
+		e.handleProbeError(w, r, remote, fmt.Sprintf("Invalid remote parameter: %v", err), http.StatusBadRequest, err)
+		return
+	}
+
+	opts, err := e.parseProbeOptions(r.URL.Query())
+	if err != nil {
+		e.handleProbeError(w, r, remote, err.Error(), http.StatusBadRequest, err)
+		return
+	}
+	opts.logger = logging.FromContext(r.Context())
+	opts.received = received
+
+	if _, ok := e.config.Binaries[opts.binary]; opts.binary != "" && !ok {
+		err := fmt.Errorf("unknown rclone binary %q", opts.binary)
+		e.handleProbeError(w, r, remote, fmt.Sprintf("Invalid binary parameter: %v", err), http.StatusBadRequest, err)
+		return
+	}
+
+	// An explicit format parameter wins over the Accept header
+	w.Header().Add("Vary", "Accept")
+	if strings.TrimSpace(r.URL.Query().Get("format")) == "" {
+		opts.format = negotiateFormat(r.Header.Get("Accept"))
+	}
+
+	// In maintenance only cached size results are served, whatever their age
+	if e.Maintenance() {
+		if remote == ProbeAllRemotes || glob || opts.mode != ProbeModeSize || opts.countOnly || opts.noCache {
+			e.handleProbeError(w, r, remote, "Exporter is in maintenance mode, only cached size probes are served", http.StatusServiceUnavailable, nil)
+			return
+		}
+		// Listing the upstreams of a union remote or the storage classes of S3 runs rclone
+		opts.cacheOnly, opts.maintenance, opts.upstreams, opts.storageClasses = true, true, false, false
+	}
+
+	if remote == ProbeAllRemotes || glob {
+		if opts.cacheOnly {
+			err := fmt.Errorf("cache=only is not supported with remote=%s", remote)
+			e.handleProbeError(w, r, remote, fmt.Sprintf("Invalid cache parameter: %v", err), http.StatusBadRequest, err)
+			return
+		}
+		if glob {
+			e.probeGlobRemotes(w, r, remote, opts)
+		} else {
+			e.probeAllRemotes(w, r, opts)
+		}
+		return
+	}
+
+	// Rate limiting using semaphore. Cache-only probes never run rclone, so they
+	// stay available while slow probes hold every slot.
+	if !opts.cacheOnly {
+		if !e.tryAcquireProbeSlot() {
+			e.probeRejectedTotal.WithLabelValues(remote, RejectReasonConcurrency).Inc()
+			e.handleProbeError(w, r, remote, "Too many concurrent requests", http.StatusTooManyRequests, nil)
+			return
+		}
+		defer e.releaseProbeSlot()
+	}
+
+	opts.log().Debug().
+		Str("remote", remote).
+		Str("client", r.RemoteAddr).
+		Str("user_agent", r.UserAgent()).
+		Msg("Starting rclone probe")
+
+	// Give up on rclone before Prometheus gives up on the scrape
+	if timeout := e.scrapeTimeout(r); timeout > 0 && (e.config.ProbeTimeout <= 0 || timeout < e.config.ProbeTimeout) {
+		opts.rclone.Timeout = timeout
+		opts.log().Debug().
+			Str("remote", remote).
+			Dur("timeout", timeout).
+			Msg("Shortening rclone timeout to the scrape timeout")
+	}
+
+	e.extendWriteDeadline(w, 1)
+	opts.extendDeadline = func(extraRuns int) { e.extendWriteDeadline(w, 1+extraRuns) }
+
+	probeRegistry, metrics := e.newProbeRegistry()
+	if err := e.probeRemote(metrics, remote, opts); err != nil {
+		if errors.Is(err, errCacheMiss) {
+			e.handleError(w, r, remote, "No fresh cached result", http.StatusServiceUnavailable, err)
+			return
+		}
+
+		if !e.config.ProbeFailureStatusOK {
+			e.handleError(w, r, remote, "rclone probe failed", http.StatusInternalServerError, err)
+			return
+		}
+
+		// Serve the metrics with probe_success 0 so the scrape itself succeeds
+		e.scrapeErrorsTotal.Inc()
+		opts.log().Warn().
+			Err(err).
+			Str("client", r.RemoteAddr).
+			Str("remote", remote).
+			Msg("rclone probe failed")
+	}
+
+	e.serveProbe(w, r, probeRegistry, metrics, opts)
+}
+
+// handleProbeError rejects a probe before rclone runs. The response is a 200 carrying
+// probe_success 0, labeled with the remote when it is valid, so the series stays
+// continuous. status is only used when the metrics cannot be rendered.
+func (e *Exporter) handleProbeError(w http.ResponseWriter, r *http.Request, remote, message string, status int, err error) {
+	e.scrapeErrorsTotal.Inc()
+	e.logRequestError(r, remote, message, err)
+
+	var remoteName, remoteType string
+	if remote != ProbeAllRemotes && e.validateRemote(remote) == nil {
+		// Never run rclone here: rate-limited probes must stay cheap
+		remoteName, remoteType = e.cachedRemoteLabels(remote)
+	} else {
+		remote = ""
+	}
+
+	probeRegistry, m := e.newProbeRegistry()
+	m.probeSuccess.WithLabelValues(remote, remoteName, remoteType).Set(0)
+
+	families, gatherErr := probeRegistry.Gather()
+	if gatherErr != nil {
+		http.Error(w, message, status)
+		return
+	}
+
+	// Prometheus drops the body of any non-2xx scrape, which would leave a gap
+	format := expfmt.NewFormat(expfmt.TypeTextPlain)
+	w.Header().Set("Content-Type", string(format))
+	w.Header().Set(ProbeRejectedHeader, strconv.Itoa(status))
+	w.WriteHeader(http.StatusOK)
+
+	encoder := expfmt.NewEncoder(w, format)
+	for _, family := range families {
+		if err := encoder.Encode(family); err != nil {
+			logging.FromContext(r.Context()).Debug().Err(err).Msg("Failed to write probe error metrics")
+			return
+		}
+	}
+}
+
+// cachedRemoteLabels returns the remote_name and remote_type labels of a valid remote
+// without running rclone, with an unknown type unless it is cached
+func (e *Exporter) cachedRemoteLabels(remote string) (remoteName, remoteType string) {
+	remoteName, _ = parseRemoteName(remote)
+
+	remoteType, isConnectionString := connectionStringBackend(remoteName)
+	if !isConnectionString {
+		var cached bool
+		if remoteType, cached = e.rcloneClient.CachedRemoteType(remoteName); !cached {
+			remoteType = "unknown"
+		}
+	}
+	return remoteName, remoteType
+}
+
+// serveProbe writes the probe results in the requested format
+func (e *Exporter) serveProbe(w http.ResponseWriter, r *http.Request, probeRegistry *prometheus.Registry, m *probeMetrics, opts probeOptions) {
+	// Covers everything before the response, including queueing and type detection
+	total := time.Since(opts.received)
+	m.totalDurationSeconds.Set(total.Seconds())
+
+	if opts.format == ProbeFormatJSON {
+		writeJSONReport(w, &m.report, total)
+		return
+	}
+
+	// Count the series before serving, this gauge included, to catch label blowups early
+	families, err := probeRegistry.Gather()
+	if err == nil {
+		series := 0
+		for _, family := range families {
+			series += len(family.GetMetric())
+		}
+		m.seriesCount.Set(float64(series))
+	}
+
+	// Serve metrics using the probe-specific registry
+	promhttp.HandlerFor(probeRegistry, e.HandlerOpts()).ServeHTTP(w, r)
+}
+
+// probeAllRemotes probes every configured remote and serves the combined metrics.
+// Individual failures are reported via probe_success=0 while the response stays 200.
+func (e *Exporter) probeAllRemotes(w http.ResponseWriter, r *http.Request, opts probeOptions) {
+	remotes, err := e.client(opts).ListRemotes()
+	if err != nil {
+		e.handleError(w, r, ProbeAllRemotes, "Failed to list remotes", http.StatusInternalServerError, err)
+		return
+	}
+
+	opts.log().Debug().
+		Int("remotes", len(remotes)).
+		Str("client", r.RemoteAddr).
+		Str("user_agent", r.UserAgent()).
+		Msg("Starting rclone probe of all remotes")
+
+	e.probeRemoteList(w, r, remoteNames(remotes), opts)
+}
+
+// probeRemoteList probes the given remotes and serves the combined metrics.
+// Individual failures are reported via probe_success=0 while the response stays 200.
+// Remotes still running at the scrape timeout are reported as failed as well.
+func (e *Exporter) probeRemoteList(w http.ResponseWriter, r *http.Request, remotes []string, opts probeOptions) {
+	// Remotes run in batches of MaxProbeAllConcurrency, each bounded by the rclone timeout
+	batches := (len(remotes) + MaxProbeAllConcurrency - 1) / MaxProbeAllConcurrency
+	e.extendWriteDeadline(w, batches)
+
+	ctx := r.Context()
+	timeout := e.scrapeTimeout(r)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	probeRegistry, metrics := e.newProbeRegistry()
+	results := newRemoteListResults()
+	prometheus.WrapRegistererWith(e.config.ConstLabels, probeRegistry).MustRegister(results)
+
+	fanOut := make(chan struct{}, MaxProbeAllConcurrency)
+	var wg sync.WaitGroup
+	for _, remote := range remotes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// Cap this request's fan-out, then wait for a free global slot
+			select {
+			case fanOut <- struct{}{}:
+				defer func() { <-fanOut }()
+			case <-ctx.Done():
+				return
+			}
+
+			if !e.acquireProbeSlot(ctx.Done()) {
+				return
+			}
+			defer e.releaseProbeSlot()
+
+			m := e.newProbeMetrics()
+			if err := e.probeRemote(m, remote, opts); err != nil {
+				e.scrapeErrorsTotal.Inc()
+				opts.log().Warn().
+					Err(err).
+					Str("client", r.RemoteAddr).
+					Str("remote", remote).
+					Msg("rclone probe failed")
+			}
+			if !results.add(remote, m) {
+				opts.log().Debug().
+					Str("remote", remote).
+					Msg("Discarding probe result that finished after the scrape timeout")
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	if r.Context().Err() != nil {
+		opts.log().Warn().
+			Err(r.Context().Err()).
+			Str("client", r.RemoteAddr).
+			Msg("Probe of multiple remotes cancelled by client")
+		return
+	}
+
+	// rclone cannot be interrupted, so late remotes keep running in the background
+	if pending := results.expire(remotes); len(pending) > 0 {
+		for _, remote := range pending {
+			e.scrapeErrorsTotal.Inc()
+			remoteName, remoteType := e.cachedRemoteLabels(remote)
+			metrics.probeSuccess.WithLabelValues(remote, remoteName, remoteType).Set(0)
+			metrics.report.add(&probeResult{
+				Remote:     remote,
+				RemoteName: remoteName,
+				RemoteType: remoteType,
+				Command:    opts.mode,
+				Error:      fmt.Sprintf("probe did not finish within the scrape timeout of %s", timeout),
+			})
+		}
+		opts.log().Warn().
+			Strs("remotes", pending).
+			Dur("timeout", timeout).
+			Str("client", r.RemoteAddr).
+			Msg("Serving partial results, remotes did not finish within the scrape timeout")
+	}
+	for _, result := range results.results() {
+		metrics.report.add(result)
+	}
+
+	e.serveProbe(w, r, probeRegistry, metrics, opts)
+}
@@ -0,0 +1,1158 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/crazyuploader/rclone_exporter/internal/rclone"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestProbeAllRemotesReportsIndividualFailures(t *testing.T) {
+	client := &fakeClient{
+		remotes: []rclone.RemoteInfo{{Name: "good", Type: "s3"}, {Name: "bad", Type: "drive"}},
+		sizes:   map[string]*rclone.RcloneSizeOutput{"good:": {Count: 3, Bytes: 42}},
+		types:   map[string]string{"good": "s3", "bad": "drive"},
+	}
+	e := NewExporter(client)
+	defer e.Close()
+
+	rec := httptest.NewRecorder()
+	e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=all", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	body, _ := io.ReadAll(rec.Body)
+	for _, want := range []string{
+		`rclone_probe_success{remote="bad:",remote_name="bad",remote_type="drive"} 0`,
+		`rclone_probe_success{remote="good:",remote_name="good",remote_type="s3"} 1`,
+		`rclone_remote_size_bytes{path="/",remote="good:",remote_name="good",remote_type="s3"} 42`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("probe output missing %q\n%s", want, body)
+		}
+	}
+}
+
+func TestProbeAllRemotesCapsFanOut(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	client := &fakeClient{
+		sizes: map[string]*rclone.RcloneSizeOutput{},
+		onSize: func(string) {
+			current := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				seen := maxInFlight.Load()
+				if current <= seen || maxInFlight.CompareAndSwap(seen, current) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+		},
+	}
+	for i := 0; i < 3*MaxConcurrentProbes; i++ {
+		name := fmt.Sprintf("remote%d", i)
+		client.remotes = append(client.remotes, rclone.RemoteInfo{Name: name})
+		client.sizes[name+":"] = &rclone.RcloneSizeOutput{}
+	}
+	e := NewExporter(client)
+	defer e.Close()
+
+	rec := httptest.NewRecorder()
+	e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=all", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := maxInFlight.Load(); got > MaxProbeAllConcurrency {
+		t.Errorf("max in-flight probes = %d, want at most %d", got, MaxProbeAllConcurrency)
+	}
+	if MaxProbeAllConcurrency >= MaxConcurrentProbes {
+		t.Errorf("MaxProbeAllConcurrency = %d leaves no slots for single probes", MaxProbeAllConcurrency)
+	}
+}
+
+func TestProbeDirsModeSkipsSize(t *testing.T) {
+	client := &fakeClient{
+		dirs:  map[string]int64{"remote:": 7},
+		types: map[string]string{"remote": "s3"},
+	}
+	e := NewExporter(client)
+	defer e.Close()
+
+	rec := httptest.NewRecorder()
+	e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=remote:&mode=dirs", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if client.sizeCalls != 0 {
+		t.Errorf("size called %d times in dirs mode, want 0", client.sizeCalls)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `rclone_remote_dirs_count{path="/",remote="remote:",remote_name="remote",remote_type="s3"} 7`) {
+		t.Errorf("probe output missing dirs count:\n%s", body)
+	}
+	if strings.Contains(body, "rclone_remote_size_bytes{") {
+		t.Errorf("dirs mode unexpectedly emitted size metrics:\n%s", body)
+	}
+}
+
+func TestProbeLargeObjects(t *testing.T) {
+	client := &fakeClient{
+		sizes: map[string]*rclone.RcloneSizeOutput{"remote:": {Count: 2, Bytes: 3 << 30}},
+		types: map[string]string{"remote": "s3"},
+	}
+	e := NewExporter(client)
+	defer e.Close()
+
+	rec := httptest.NewRecorder()
+	e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=remote:&command=large&minsize=1G", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if client.sizeOpts.MinSize != "1G" {
+		t.Errorf("size called with MinSize %q, want 1G", client.sizeOpts.MinSize)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`rclone_remote_large_objects_count{min_size="1G",path="/",remote="remote:",remote_name="remote",remote_type="s3"} 2`,
+		`rclone_remote_large_objects_bytes{min_size="1G",path="/",remote="remote:",remote_name="remote",remote_type="s3"} 3.221225472e+09`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("probe output missing %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "rclone_remote_size_bytes{") {
+		t.Errorf("large mode unexpectedly emitted total size metrics:\n%s", body)
+	}
+}
+
+func TestProbeLargeObjectsValidation(t *testing.T) {
+	e := NewExporter(&fakeClient{})
+	defer e.Close()
+
+	for _, query := range []string{
+		"remote=remote:&command=large",
+		"remote=remote:&command=large&minsize=huge",
+		"remote=remote:&minsize=1G",
+	} {
+		rec := httptest.NewRecorder()
+		e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?"+query, nil))
+		if probeStatus(rec) != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", query, probeStatus(rec), http.StatusBadRequest)
+		}
+	}
+}
+
+func TestProbeTraversal(t *testing.T) {
+	client := &fakeClient{sizes: map[string]*rclone.RcloneSizeOutput{"remote:": {Count: 1, Bytes: 2}}}
+	e := NewExporter(client)
+	defer e.Close()
+
+	rec := httptest.NewRecorder()
+	e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=remote:&traversal=no-traverse", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if client.sizeOpts.Traversal != rclone.TraversalNoTraverse {
+		t.Errorf("size called with Traversal %q, want %s", client.sizeOpts.Traversal, rclone.TraversalNoTraverse)
+	}
+
+	for _, query := range []string{
+		"remote=remote:&traversal=walk",
+		"remote=remote:&traversal=fast-list&command=about",
+		"remote=remote:&traversal=no-traverse&command=dedupe",
+	} {
+		rec := httptest.NewRecorder()
+		e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?"+query, nil))
+		if probeStatus(rec) != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", query, probeStatus(rec), http.StatusBadRequest)
+		}
+	}
+}
+
+func TestProbeCountOnly(t *testing.T) {
+	tests := []struct {
+		name      string
+		countOnly bool
+		query     string
+		wantCount bool
+	}{
+		{"size=false", false, "&size=false", true},
+		{"flag", true, "", true},
+		{"size=true overrides flag", true, "&size=true", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeClient{
+				sizes:   map[string]*rclone.RcloneSizeOutput{"remote:": {Count: 5, Bytes: 42}},
+				objects: map[string]int64{"remote:": 5},
+				types:   map[string]string{"remote": "s3"},
+			}
+			e := NewExporterWithConfig(client, Config{CountOnly: tt.countOnly})
+			defer e.Close()
+
+			rec := httptest.NewRecorder()
+			e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=remote:"+tt.query, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+
+			body := rec.Body.String()
+			if !strings.Contains(body, `rclone_remote_objects_count{path="/",remote="remote:",remote_name="remote",remote_type="s3"} 5`) {
+				t.Errorf("probe output missing object count:\n%s", body)
+			}
+			if !strings.Contains(body, `rclone_probe_success{remote="remote:",remote_name="remote",remote_type="s3"} 1`) {
+				t.Errorf("probe output missing probe success:\n%s", body)
+			}
+			if got := strings.Contains(body, "rclone_remote_size_bytes{"); got == tt.wantCount {
+				t.Errorf("size bytes emitted = %v, want %v:\n%s", got, !tt.wantCount, body)
+			}
+			if tt.wantCount && client.sizeCalls != 0 {
+				t.Errorf("size called %d times in count-only mode, want 0", client.sizeCalls)
+			}
+		})
+	}
+}
+
+func TestProbeCountOnlyValidation(t *testing.T) {
+	e := NewExporter(&fakeClient{})
+	defer e.Close()
+
+	for _, query := range []string{
+		"remote=remote:&size=maybe",
+		"remote=remote:&size=false&command=dirs",
+		"remote=remote:&size=false&upstreams=true",
+		"remote=remote:&size=false&cache=only",
+	} {
+		rec := httptest.NewRecorder()
+		e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?"+query, nil))
+		if probeStatus(rec) != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", query, probeStatus(rec), http.StatusBadRequest)
+		}
+	}
+}
+
+func TestProbeDedupe(t *testing.T) {
+	client := &fakeClient{
+		duplicates: map[string]int64{"gdrive:": 4},
+		types:      map[string]string{"gdrive": "drive"},
+	}
+	e := NewExporter(client)
+	defer e.Close()
+
+	rec := httptest.NewRecorder()
+	e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=gdrive:&command=dedupe", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if client.sizeCalls != 0 {
+		t.Errorf("size called %d times in dedupe mode, want 0", client.sizeCalls)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `rclone_remote_duplicates{path="/",remote="gdrive:",remote_name="gdrive",remote_type="drive"} 4`) {
+		t.Errorf("probe output missing duplicates:\n%s", body)
+	}
+}
+
+func TestProbeDefaultRemote(t *testing.T) {
+	client := &fakeClient{
+		sizes: map[string]*rclone.RcloneSizeOutput{"default:": {Count: 1, Bytes: 10}, "other:": {Count: 2, Bytes: 20}},
+	}
+	e := NewExporterWithConfig(client, Config{DefaultRemote: "default:"})
+	defer e.Close()
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", `rclone_remote_size_bytes{path="/",remote="default:",remote_name="default",remote_type="unknown"} 10`},
+		{"?remote=other:", `rclone_remote_size_bytes{path="/",remote="other:",remote_name="other",remote_type="unknown"} 20`},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe"+tt.query, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("%q: status = %d, want %d", tt.query, rec.Code, http.StatusOK)
+		}
+		if body := rec.Body.String(); !strings.Contains(body, tt.want) {
+			t.Errorf("%q: probe output missing %q:\n%s", tt.query, tt.want, body)
+		}
+	}
+
+	// Without a default the parameter stays required
+	e = NewExporter(client)
+	defer e.Close()
+	rec := httptest.NewRecorder()
+	e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe", nil))
+	if probeStatus(rec) != http.StatusBadRequest {
+		t.Errorf("status without default = %d, want %d", probeStatus(rec), http.StatusBadRequest)
+	}
+}
+
+func TestProbeSizeDelta(t *testing.T) {
+	client := &fakeClient{sizes: map[string]*rclone.RcloneSizeOutput{"remote:": {Count: 1, Bytes: 100}}}
+	e := NewExporter(client)
+	defer e.Close()
+
+	probe := func() string {
+		rec := httptest.NewRecorder()
+		e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=remote:", nil))
+		return rec.Body.String()
+	}
+
+	if body := probe(); strings.Contains(body, "rclone_remote_size_delta_bytes{") {
+		t.Errorf("first probe emitted a size delta:\n%s", body)
+	}
+
+	client.mu.Lock()
+	client.sizes["remote:"] = &rclone.RcloneSizeOutput{Count: 1, Bytes: 70}
+	client.mu.Unlock()
+	want := `rclone_remote_size_delta_bytes{path="/",remote="remote:",remote_name="remote",remote_type="unknown"} -30`
+	if body := probe(); !strings.Contains(body, want) {
+		t.Errorf("probe output missing %q:\n%s", want, body)
+	}
+}
+
+func TestObjectCountHistogram(t *testing.T) {
+	client := &fakeClient{sizes: map[string]*rclone.RcloneSizeOutput{"small:": {Count: 5}, "large:": {Count: 50000}}}
+
+	disabled := NewExporter(client)
+	if body := scrapeMetrics(disabled); strings.Contains(body, "rclone_exporter_objects_count_distribution") {
+		t.Errorf("histogram exported without ObjectCountHistogram\n%s", body)
+	}
+	disabled.Close()
+
+	e := NewExporterWithConfig(client, Config{ObjectCountHistogram: true})
+	defer e.Close()
+	for _, remote := range []string{"small:", "large:", "missing:"} {
+		e.ProbeHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/probe?remote="+remote, nil))
+	}
+
+	body := scrapeMetrics(e)
+	for _, want := range []string{
+		`rclone_exporter_objects_count_distribution_bucket{le="10"} 1`,
+		`rclone_exporter_objects_count_distribution_bucket{le="100000"} 2`,
+		`rclone_exporter_objects_count_distribution_count 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("/metrics missing %q\n%s", want, body)
+		}
+	}
+}
+
+func TestProbeInfoBackendType(t *testing.T) {
+	client := &fakeClient{
+		sizes:    map[string]*rclone.RcloneSizeOutput{"secret:": {}, "plain:": {}},
+		types:    map[string]string{"secret": "crypt", "plain": "s3"},
+		backends: map[string]string{"secret": "s3"},
+	}
+	e := NewExporter(client)
+	defer e.Close()
+
+	tests := map[string]string{
+		"secret:": `rclone_probe_info{backend_type="s3",path="/",remote="secret:",remote_name="secret",remote_type="crypt"} 1`,
+		"plain:":  `rclone_probe_info{backend_type="s3",path="/",remote="plain:",remote_name="plain",remote_type="s3"} 1`,
+	}
+	for remote, want := range tests {
+		rec := httptest.NewRecorder()
+		e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote="+remote, nil))
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("probe of %s missing %q\n%s", remote, want, rec.Body)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=secret:&format=json", nil))
+	if !strings.Contains(rec.Body.String(), `"backend_type":"s3"`) {
+		t.Errorf("JSON report missing backend_type\n%s", rec.Body)
+	}
+}
+
+func TestProbeRejectsUnknownMode(t *testing.T) {
+	e := NewExporter(&fakeClient{})
+	defer e.Close()
+
+	rec := httptest.NewRecorder()
+	e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=remote:&mode=bogus", nil))
+
+	if probeStatus(rec) != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", probeStatus(rec), http.StatusBadRequest)
+	}
+}
+
+func TestConcurrentIdenticalProbesShareOneRcloneRun(t *testing.T) {
+	gate := make(chan struct{})
+	client := &fakeClient{
+		sizes:  map[string]*rclone.RcloneSizeOutput{"remote:": {Count: 1, Bytes: 10}},
+		onSize: func(string) { <-gate },
+	}
+	e := NewExporter(client)
+	defer e.Close()
+
+	const requests = 5
+	codes := make(chan int, requests)
+	for i := 0; i < requests; i++ {
+		go func() {
+			rec := httptest.NewRecorder()
+			e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=remote:", nil))
+			codes <- rec.Code
+		}()
+	}
+
+	// Give every request time to join the in-flight rclone run before releasing it
+	time.Sleep(100 * time.Millisecond)
+	close(gate)
+
+	for i := 0; i < requests; i++ {
+		if code := <-codes; code != http.StatusOK {
+			t.Errorf("status = %d, want %d", code, http.StatusOK)
+		}
+	}
+
+	if client.sizeCalls != 1 {
+		t.Errorf("rclone size ran %d times, want 1", client.sizeCalls)
+	}
+}
+
+func TestConcurrentProbesWithDifferentTimeoutsRunSeparately(t *testing.T) {
+	gate := make(chan struct{})
+	client := &fakeClient{
+		sizes:  map[string]*rclone.RcloneSizeOutput{"remote:": {Count: 1, Bytes: 10}},
+		onSize: func(string) { <-gate },
+	}
+	e := NewExporterWithConfig(client, Config{ProbeTimeout: time.Minute})
+	defer e.Close()
+
+	scrapeTimeouts := []string{"2", "30"}
+	codes := make(chan int, len(scrapeTimeouts))
+	for _, timeout := range scrapeTimeouts {
+		req := httptest.NewRequest(http.MethodGet, "/probe?remote=remote:", nil)
+		req.Header.Set(ScrapeTimeoutHeader, timeout)
+		go func() {
+			rec := httptest.NewRecorder()
+			e.ProbeHandler(rec, req)
+			codes <- rec.Code
+		}()
+	}
+
+	// A short-timeout run must not be shared with the caller that can wait longer
+	time.Sleep(100 * time.Millisecond)
+	close(gate)
+
+	for range scrapeTimeouts {
+		if code := <-codes; code != http.StatusOK {
+			t.Errorf("status = %d, want %d", code, http.StatusOK)
+		}
+	}
+
+	if client.sizeCalls != 2 {
+		t.Errorf("rclone size ran %d times, want 2", client.sizeCalls)
+	}
+}
+
+func TestProbeAboutModeReportsFreeSpace(t *testing.T) {
+	total, used, free := int64(1000), int64(900), int64(100)
+	client := &fakeClient{
+		abouts: map[string]*rclone.RcloneAboutOutput{
+			"quota:":   {Total: &total, Used: &used, Free: &free},
+			"noquota:": {Used: &used},
+		},
+		types: map[string]string{"quota": "drive", "noquota": "s3"},
+	}
+	e := NewExporterWithConfig(client, Config{FreePercentThreshold: 20})
+	defer e.Close()
+
+	rec := httptest.NewRecorder()
+	e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=quota:&mode=about", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		`rclone_remote_quota_total_bytes{remote="quota:",remote_name="quota",remote_type="drive"} 1000`,
+		`rclone_remote_free_percent{remote="quota:",remote_name="quota",remote_type="drive"} 10`,
+		`rclone_remote_space_low{remote="quota:",remote_name="quota",remote_type="drive"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("probe output missing %q\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "rclone_remote_size_bytes{") {
+		t.Errorf("about mode should not emit size metrics\n%s", body)
+	}
+
+	// Backends without a known total must not emit the computed gauges
+	rec = httptest.NewRecorder()
+	e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=noquota:&mode=about", nil))
+	body = rec.Body.String()
+	for _, unwanted := range []string{"rclone_remote_free_percent{", "rclone_remote_space_low{"} {
+		if strings.Contains(body, unwanted) {
+			t.Errorf("probe output unexpectedly contains %q\n%s", unwanted, body)
+		}
+	}
+}
+
+func TestProbeReportsConcurrencyGauges(t *testing.T) {
+	client := &fakeClient{sizes: map[string]*rclone.RcloneSizeOutput{"remote:": {Count: 1, Bytes: 1}}}
+	e := NewExporter(client)
+	defer e.Close()
+
+	rec := httptest.NewRecorder()
+	e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=remote:", nil))
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		fmt.Sprintf("rclone_exporter_max_concurrent_probes %d", MaxConcurrentProbes),
+		// The probe itself holds a slot while its metrics are served
+		"rclone_exporter_probes_in_flight 1",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("probe output missing %q\n%s", want, body)
+		}
+	}
+}
+
+func TestProbeConnectionStringUsesBackendType(t *testing.T) {
+	remote := ":sftp,host=x:backups"
+	client := &fakeClient{sizes: map[string]*rclone.RcloneSizeOutput{remote: {Count: 1, Bytes: 7}}}
+	e := NewExporter(client)
+	defer e.Close()
+
+	rec := httptest.NewRecorder()
+	e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote="+url.QueryEscape(remote), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d\n%s", rec.Code, http.StatusOK, rec.Body)
+	}
+
+	want := `rclone_remote_size_bytes{path="backups",remote=":sftp,host=x:backups",remote_name=":sftp",remote_type="sftp"} 7`
+	if body := rec.Body.String(); !strings.Contains(body, want) {
+		t.Errorf("probe output missing %q\n%s", want, body)
+	}
+}
+
+func TestProbeTracksConsecutiveFailures(t *testing.T) {
+	client := &fakeClient{sizes: map[string]*rclone.RcloneSizeOutput{}}
+	e := NewExporter(client)
+	defer e.Close()
+
+	probe := func() string {
+		rec := httptest.NewRecorder()
+		e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=all", nil))
+		return rec.Body.String()
+	}
+	client.remotes = []rclone.RemoteInfo{{Name: "flaky"}}
+
+	probe()
+	body := probe()
+	want := `rclone_remote_consecutive_failures{remote="flaky:",remote_name="flaky",remote_type="unknown"} 2`
+	if !strings.Contains(body, want) {
+		t.Errorf("probe output missing %q\n%s", want, body)
+	}
+
+	client.mu.Lock()
+	client.sizes["flaky:"] = &rclone.RcloneSizeOutput{}
+	client.mu.Unlock()
+
+	body = probe()
+	want = `rclone_remote_consecutive_failures{remote="flaky:",remote_name="flaky",remote_type="unknown"} 0`
+	if !strings.Contains(body, want) {
+		t.Errorf("probe output missing %q after success\n%s", want, body)
+	}
+
+	// The persistent gauge on /metrics keeps the streak between scrapes
+	rec := httptest.NewRecorder()
+	promhttp.HandlerFor(e.Registry(), promhttp.HandlerOpts{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if want := `rclone_remote_consecutive_failures{remote="flaky:",remote_name="flaky",remote_type="unknown"} 0`; !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("/metrics missing %q\n%s", want, rec.Body)
+	}
+}
+
+func TestProbeUnionUpstreamBreakdown(t *testing.T) {
+	client := &fakeClient{
+		sizes: map[string]*rclone.RcloneSizeOutput{
+			"pool:":         {Count: 3, Bytes: 30},
+			"gdrive:backup": {Count: 1, Bytes: 10},
+		},
+		types:     map[string]string{"pool": "union"},
+		upstreams: map[string][]rclone.Upstream{"pool": {{Name: "gdrive:backup", Remote: "gdrive:backup"}, {Name: "broken:", Remote: "broken:"}}},
+	}
+	e := NewExporter(client)
+	defer e.Close()
+
+	// Without the parameter only the merged total is probed
+	rec := httptest.NewRecorder()
+	e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=pool:", nil))
+	if strings.Contains(rec.Body.String(), "rclone_remote_upstream_size_bytes{") || client.sizeCalls != 1 {
+		t.Fatalf("upstreams probed without upstreams=true (size calls %d)\n%s", client.sizeCalls, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=pool:&upstreams=true", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		`rclone_remote_upstream_size_bytes{remote="pool:",remote_name="pool",upstream="gdrive:backup"} 10`,
+		`rclone_probe_success{remote="pool:",remote_name="pool",remote_type="union"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("probe output missing %q\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, `upstream="broken:"`) {
+		t.Errorf("failed upstream should be skipped\n%s", body)
+	}
+}
+
+func TestProbeAboutTrashedPresence(t *testing.T) {
+	zero, trashed := int64(0), int64(512)
+	client := &fakeClient{abouts: map[string]*rclone.RcloneAboutOutput{
+		"drive:":    {Trashed: &zero},
+		"onedrive:": {Trashed: &trashed},
+		"s3:":       {},
+	}}
+	e := NewExporter(client)
+	defer e.Close()
+
+	tests := []struct {
+		remote      string
+		wantPresent string
+		wantTrashed string // Empty when the gauge must be absent
+	}{
+		{"drive:", "1", "0"},
+		{"onedrive:", "1", "512"},
+		{"s3:", "0", ""},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?command=about&remote="+tt.remote, nil))
+		body := rec.Body.String()
+		name := strings.TrimSuffix(tt.remote, ":")
+		labels := fmt.Sprintf(`{remote=%q,remote_name=%q,remote_type="unknown"}`, tt.remote, name)
+
+		if want := "rclone_remote_trashed_bytes_present" + labels + " " + tt.wantPresent; !strings.Contains(body, want) {
+			t.Errorf("%s: missing %q\n%s", tt.remote, want, body)
+		}
+		if tt.wantTrashed == "" {
+			if strings.Contains(body, "rclone_remote_trashed_bytes{") {
+				t.Errorf("%s: trashed_bytes emitted for backend without trash\n%s", tt.remote, body)
+			}
+		} else if want := "rclone_remote_trashed_bytes" + labels + " " + tt.wantTrashed; !strings.Contains(body, want) {
+			t.Errorf("%s: missing %q\n%s", tt.remote, want, body)
+		}
+	}
+}
+
+func TestProbeFailureStatus(t *testing.T) {
+	client := &fakeClient{sizes: map[string]*rclone.RcloneSizeOutput{}}
+
+	probe := func(config Config) *httptest.ResponseRecorder {
+		e := NewExporterWithConfig(client, config)
+		defer e.Close()
+
+		rec := httptest.NewRecorder()
+		e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=bad:", nil))
+		return rec
+	}
+
+	if rec := probe(Config{}); rec.Code != http.StatusInternalServerError {
+		t.Errorf("default status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+
+	rec := probe(Config{ProbeFailureStatusOK: true})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d\n%s", rec.Code, http.StatusOK, rec.Body)
+	}
+	want := `rclone_probe_success{remote="bad:",remote_name="bad",remote_type="unknown"} 0`
+	if !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("probe output missing %q\n%s", want, rec.Body)
+	}
+}
+
+// probeStatus returns the status of a probe response, or for a rejected probe served as
+// 200 the status it was rejected with
+func probeStatus(rec *httptest.ResponseRecorder) int {
+	if status, err := strconv.Atoi(rec.Header().Get(ProbeRejectedHeader)); err == nil {
+		return status
+	}
+	return rec.Code
+}
+
+func TestProbeEarlyErrorsReportProbeSuccess(t *testing.T) {
+	client := &fakeClient{sizes: map[string]*rclone.RcloneSizeOutput{}}
+	e := NewExporter(client)
+	defer e.Close()
+
+	probe := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?"+query, nil))
+		return rec
+	}
+
+	// Prometheus drops the body of non-2xx scrapes, so rejections are served as 200
+	rec := probe("remote=gdrive:&depth=bogus")
+	if rec.Code != http.StatusOK || probeStatus(rec) != http.StatusBadRequest {
+		t.Errorf("status = %d (rejected with %d), want %d (rejected with %d)", rec.Code, probeStatus(rec), http.StatusOK, http.StatusBadRequest)
+	}
+	want := `rclone_probe_success{remote="gdrive:",remote_name="gdrive",remote_type="unknown"} 0`
+	if !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("invalid depth output missing %q\n%s", want, rec.Body)
+	}
+
+	// An invalid remote is never used as a label value
+	rec = probe("remote=" + url.QueryEscape("bad remote;"))
+	if !strings.Contains(rec.Body.String(), `rclone_probe_success{remote="",remote_name="",remote_type=""} 0`) {
+		t.Errorf("invalid remote output missing unlabeled probe_success\n%s", rec.Body)
+	}
+
+	for i := 0; i < MaxConcurrentProbes; i++ {
+		if !e.tryAcquireProbeSlot() {
+			t.Fatal("failed to fill the probe slots")
+		}
+		defer e.releaseProbeSlot()
+	}
+
+	rec = probe("remote=gdrive:")
+	if probeStatus(rec) != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want %d", probeStatus(rec), http.StatusTooManyRequests)
+	}
+	if !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("rate-limited output missing %q\n%s", want, rec.Body)
+	}
+	if body := scrapeMetrics(e); !strings.Contains(body, `rclone_exporter_probe_rejected_total{reason="concurrency",remote="gdrive:"} 1`) {
+		t.Errorf("/metrics missing the rejection of gdrive:\n%s", body)
+	}
+}
+
+func TestProbeDurationHistogram(t *testing.T) {
+	for _, native := range []bool{false, true} {
+		client := &fakeClient{sizes: map[string]*rclone.RcloneSizeOutput{"remote:": {}}}
+		e := NewExporterWithConfig(client, Config{NativeHistograms: native})
+
+		e.ProbeHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/probe?remote=remote:", nil))
+
+		families, err := e.Registry().Gather()
+		e.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var found bool
+		for _, family := range families {
+			if family.GetName() != "rclone_exporter_probe_duration_seconds" {
+				continue
+			}
+			found = true
+
+			histogram := family.GetMetric()[0].GetHistogram()
+			if histogram.GetSampleCount() != 1 {
+				t.Errorf("native=%v: sample count = %d, want 1", native, histogram.GetSampleCount())
+			}
+			if hasClassic := len(histogram.GetBucket()) > 0; hasClassic == native {
+				t.Errorf("native=%v: classic buckets present = %v", native, hasClassic)
+			}
+			if hasNative := histogram.Schema != nil; hasNative != native {
+				t.Errorf("native=%v: native schema present = %v", native, hasNative)
+			}
+		}
+		if !found {
+			t.Errorf("native=%v: rclone_exporter_probe_duration_seconds not registered", native)
+		}
+	}
+}
+
+func TestProbeReportsTotalDuration(t *testing.T) {
+	client := &fakeClient{
+		sizes:  map[string]*rclone.RcloneSizeOutput{"remote:": {}},
+		onSize: func(string) { time.Sleep(20 * time.Millisecond) },
+	}
+	e := NewExporter(client)
+	defer e.Close()
+
+	rec := httptest.NewRecorder()
+	e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=remote:", nil))
+
+	var total float64
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		if value, ok := strings.CutPrefix(line, "rclone_probe_total_duration_seconds "); ok {
+			if _, err := fmt.Sscan(value, &total); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if total < 0.02 {
+		t.Errorf("rclone_probe_total_duration_seconds = %v, want at least the 20ms rclone run\n%s", total, rec.Body)
+	}
+}
+
+func TestProbeRejectedWhileDraining(t *testing.T) {
+	client := &fakeClient{sizes: map[string]*rclone.RcloneSizeOutput{"remote:": {}}}
+	e := NewExporterWithConfig(client, Config{ProbeFailureStatusOK: true})
+	defer e.Close()
+
+	e.StartDraining()
+
+	rec := httptest.NewRecorder()
+	e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=remote:", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if strings.Contains(rec.Body.String(), "rclone_probe_success") {
+		t.Errorf("draining response reports probe_success\n%s", rec.Body)
+	}
+	if client.sizeCalls != 0 {
+		t.Errorf("rclone ran %d times while draining", client.sizeCalls)
+	}
+}
+
+func TestProbeMaintenanceServesCachedResults(t *testing.T) {
+	client := &fakeClient{sizes: map[string]*rclone.RcloneSizeOutput{"cached:": {Count: 2, Bytes: 10}, "fresh:": {}}}
+	e := NewExporterWithConfig(client, Config{SizeCacheTTL: 20 * time.Millisecond})
+	defer e.Close()
+
+	probe := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?"+query, nil))
+		return rec
+	}
+
+	probe("remote=cached:")
+	time.Sleep(30 * time.Millisecond)
+
+	e.SetMaintenance(true)
+	if body := scrapeMetrics(e); !strings.Contains(body, "rclone_exporter_maintenance_mode 1") {
+		t.Errorf("/metrics missing maintenance mode gauge\n%s", body)
+	}
+
+	// The expired result is still served as the last known value
+	rec := probe("remote=cached:")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("cached probe status = %d, want %d\n%s", rec.Code, http.StatusOK, rec.Body)
+	}
+	want := `rclone_remote_size_bytes{path="/",remote="cached:",remote_name="cached",remote_type="unknown"} 10`
+	if !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("probe output missing %q\n%s", want, rec.Body)
+	}
+
+	for _, query := range []string{"remote=fresh:", "remote=all", "remote=cached:&command=about", "remote=cached:&size=false"} {
+		if rec := probe(query); probeStatus(rec) != http.StatusServiceUnavailable {
+			t.Errorf("%s: status = %d, want %d", query, probeStatus(rec), http.StatusServiceUnavailable)
+		}
+	}
+	if client.sizeCalls != 1 {
+		t.Errorf("rclone size ran %d times, want only the probe before maintenance", client.sizeCalls)
+	}
+
+	e.SetMaintenance(false)
+	if rec := probe("remote=fresh:"); rec.Code != http.StatusOK {
+		t.Errorf("status after maintenance = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestProbeMaintenanceRejectionReportsProbeSuccess(t *testing.T) {
+	client := &fakeClient{abouts: map[string]*rclone.RcloneAboutOutput{"gdrive:": {}}}
+	e := NewExporter(client)
+	defer e.Close()
+	e.SetMaintenance(true)
+
+	rec := httptest.NewRecorder()
+	e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=gdrive:&command=about", nil))
+
+	// The rejected probe keeps the series continuous instead of a body Prometheus drops
+	if rec.Code != http.StatusOK || probeStatus(rec) != http.StatusServiceUnavailable {
+		t.Errorf("status = %d (rejected with %d), want %d (rejected with %d)", rec.Code, probeStatus(rec), http.StatusOK, http.StatusServiceUnavailable)
+	}
+	want := `rclone_probe_success{remote="gdrive:",remote_name="gdrive",remote_type="unknown"} 0`
+	if !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("maintenance rejection missing %q\n%s", want, rec.Body)
+	}
+}
+
+func TestProbeRemoteMetaLabels(t *testing.T) {
+	client := &fakeClient{sizes: map[string]*rclone.RcloneSizeOutput{"gdrive:": {}, "s3:": {}, "plain:": {}}}
+	e := NewExporterWithConfig(client, Config{RemoteLabels: map[string]map[string]string{
+		"gdrive": {"team": "data", "cost_center": "42"},
+		"s3":     {"team": "infra"},
+	}})
+	defer e.Close()
+
+	probe := func(remote string) string {
+		rec := httptest.NewRecorder()
+		e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote="+remote, nil))
+		return rec.Body.String()
+	}
+
+	want := `rclone_remote_meta{cost_center="42",remote="gdrive:",remote_name="gdrive",team="data"} 1`
+	if body := probe("gdrive:"); !strings.Contains(body, want) {
+		t.Errorf("probe output missing %q\n%s", want, body)
+	}
+
+	// Labels another remote defines are present but empty
+	want = `rclone_remote_meta{cost_center="",remote="s3:",remote_name="s3",team="infra"} 1`
+	if body := probe("s3:"); !strings.Contains(body, want) {
+		t.Errorf("probe output missing %q\n%s", want, body)
+	}
+
+	if body := probe("plain:"); strings.Contains(body, "rclone_remote_meta{") {
+		t.Errorf("remote without labels reports rclone_remote_meta\n%s", body)
+	}
+}
+
+func TestProbeReportsWarnings(t *testing.T) {
+	client := &fakeClient{sizes: map[string]*rclone.RcloneSizeOutput{"remote:": {Count: 1, Warnings: 3}}}
+	e := NewExporter(client)
+	defer e.Close()
+
+	rec := httptest.NewRecorder()
+	e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=remote:", nil))
+
+	want := `rclone_remote_probe_warnings{remote="remote:",remote_name="remote",remote_type="unknown"} 3`
+	if !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("probe output missing %q\n%s", want, rec.Body)
+	}
+}
+
+func TestProbeReportsSeriesCount(t *testing.T) {
+	client := &fakeClient{
+		sizes: map[string]*rclone.RcloneSizeOutput{"remote:": {Count: 1, Bytes: 2}},
+		types: map[string]string{"remote": "s3"},
+	}
+	e := NewExporter(client)
+	defer e.Close()
+
+	rec := httptest.NewRecorder()
+	e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=remote:", nil))
+
+	series := 0
+	reported := ""
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		series++
+		if value, ok := strings.CutPrefix(line, "rclone_exporter_probe_series_count "); ok {
+			reported = value
+		}
+	}
+
+	if reported != fmt.Sprint(series) {
+		t.Errorf("rclone_exporter_probe_series_count = %q, want %d\n%s", reported, series, rec.Body)
+	}
+}
+
+func TestProbeSelectsBinary(t *testing.T) {
+	stable := &fakeClient{sizes: map[string]*rclone.RcloneSizeOutput{"remote:": {Bytes: 100}}}
+	beta := &fakeClient{sizes: map[string]*rclone.RcloneSizeOutput{"remote:": {Bytes: 101}}}
+	e := NewExporterWithConfig(stable, Config{
+		SizeCacheTTL: time.Hour,
+		Binaries:     map[string]rclone.Client{"beta": beta},
+	})
+	defer e.Close()
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"remote=remote:", `rclone_remote_size_bytes{path="/",remote="remote:",remote_name="remote",remote_type="unknown"} 100`},
+		// Cached results are kept per binary
+		{"remote=remote:&binary=beta", `rclone_remote_size_bytes{path="/",remote="remote:",remote_name="remote",remote_type="unknown"} 101`},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?"+tt.query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want %d", tt.query, rec.Code, http.StatusOK)
+		}
+		if !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("%s: output missing %q\n%s", tt.query, tt.want, rec.Body)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=remote:&binary=nightly", nil))
+	if probeStatus(rec) != http.StatusBadRequest {
+		t.Errorf("unknown binary: status = %d, want %d", probeStatus(rec), http.StatusBadRequest)
+	}
+	if stable.sizeCalls != 1 || beta.sizeCalls != 1 {
+		t.Errorf("size calls = %d (stable), %d (beta), want 1 each", stable.sizeCalls, beta.sizeCalls)
+	}
+}
+
+func TestProbeTimeoutRatio(t *testing.T) {
+	client := &fakeClient{
+		sizes:  map[string]*rclone.RcloneSizeOutput{"remote:": {Count: 1, Bytes: 1}},
+		onSize: func(string) { time.Sleep(100 * time.Millisecond) },
+	}
+	e := NewExporterWithConfig(client, Config{ProbeTimeout: time.Second, SizeCacheTTL: time.Hour})
+	defer e.Close()
+
+	probe := func(remote string) {
+		rec := httptest.NewRecorder()
+		e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote="+remote, nil))
+	}
+	ratio := func() float64 {
+		return testutil.ToFloat64(e.probeTimeoutRatio.WithLabelValues("remote:"))
+	}
+
+	probe("remote:")
+	first := ratio()
+	if first < 0.1 || first >= 1 {
+		t.Fatalf("timeout ratio = %v, want about 0.1", first)
+	}
+
+	// Cache hits and failures leave the last measurement in place
+	probe("remote:")
+	probe("missing:")
+	if got := ratio(); got != first {
+		t.Errorf("timeout ratio = %v after a cache hit, want %v", got, first)
+	}
+	if body := scrapeMetrics(e); strings.Contains(body, `rclone_remote_probe_timeout_ratio{remote="missing:"}`) {
+		t.Errorf("/metrics reports a timeout ratio for a failed probe:\n%s", body)
+	}
+}
+
+func TestProbeTimeoutRatioUsesScrapeTimeout(t *testing.T) {
+	client := &fakeClient{
+		sizes:  map[string]*rclone.RcloneSizeOutput{"remote:": {Count: 1, Bytes: 1}},
+		onSize: func(string) { time.Sleep(100 * time.Millisecond) },
+	}
+	e := NewExporterWithConfig(client, Config{ProbeTimeout: time.Minute})
+	defer e.Close()
+
+	// A 0.2s scrape timeout bounds rclone to 0.2s, so 100ms is about half of it
+	req := httptest.NewRequest(http.MethodGet, "/probe?remote=remote:", nil)
+	req.Header.Set(ScrapeTimeoutHeader, "0.2")
+	e.ProbeHandler(httptest.NewRecorder(), req)
+
+	if got := testutil.ToFloat64(e.probeTimeoutRatio.WithLabelValues("remote:")); got < 0.4 || got >= 1 {
+		t.Errorf("timeout ratio = %v, want about 0.5 of the scrape timeout", got)
+	}
+}
+
+func TestProbeTokenInvalid(t *testing.T) {
+	client := &fakeClient{
+		sizes: map[string]*rclone.RcloneSizeOutput{"ok:": {Count: 1, Bytes: 1}},
+		sizeErrs: map[string]error{
+			"expired:": &rclone.CommandError{Operation: "size", Remote: "expired:", ExitCode: 1,
+				Stderr: `couldn't fetch token: invalid_grant: maybe token is expired? - try refreshing with "rclone config reconnect expired:"`},
+			"offline:": &rclone.CommandError{Operation: "size", Remote: "offline:", ExitCode: 1, Stderr: "dial tcp: i/o timeout"},
+		},
+		types: map[string]string{"ok": "drive", "expired": "drive", "offline": "drive"},
+	}
+	e := NewExporterWithConfig(client, Config{ProbeFailureStatusOK: true})
+	defer e.Close()
+
+	for remote, want := range map[string]string{
+		"expired:": `rclone_remote_token_invalid{remote="expired:",remote_name="expired",remote_type="drive"} 1`,
+		"offline:": `rclone_remote_token_invalid{remote="offline:",remote_name="offline",remote_type="drive"} 0`,
+		"ok:":      `rclone_remote_token_invalid{remote="ok:",remote_name="ok",remote_type="drive"} 0`,
+	} {
+		rec := httptest.NewRecorder()
+		e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote="+remote, nil))
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("%s: probe output missing %q\n%s", remote, want, rec.Body)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=expired:&format=json", nil))
+	if !strings.Contains(rec.Body.String(), `"token_invalid":true`) {
+		t.Errorf("JSON report missing token_invalid\n%s", rec.Body)
+	}
+}
+
+func TestProbeCountsStderrBytes(t *testing.T) {
+	client := &fakeClient{
+		sizes: map[string]*rclone.RcloneSizeOutput{"noisy:": {Count: 1, Bytes: 1, StderrBytes: 40}},
+		sizeErrs: map[string]error{
+			"failing:": &rclone.CommandError{Operation: "size", Remote: "failing:", ExitCode: 1, Stderr: "retrying"},
+		},
+	}
+	e := NewExporterWithConfig(client, Config{SizeCacheTTL: time.Hour})
+	defer e.Close()
+
+	for _, remote := range []string{"noisy:", "noisy:", "failing:", "failing:"} {
+		e.ProbeHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/probe?remote="+remote, nil))
+	}
+
+	// The second noisy: probe is served from the cache and runs no rclone
+	if got := testutil.ToFloat64(e.stderrBytesTotal.WithLabelValues("noisy:")); got != 40 {
+		t.Errorf("noisy: stderr bytes = %v, want 40", got)
+	}
+	if got := testutil.ToFloat64(e.stderrBytesTotal.WithLabelValues("failing:")); got != 16 {
+		t.Errorf("failing: stderr bytes = %v, want 16", got)
+	}
+}
+
+func TestProbeStorageClasses(t *testing.T) {
+	client := &fakeClient{
+		sizes: map[string]*rclone.RcloneSizeOutput{
+			"archive:": {Count: 3, Bytes: 30},
+			"gdrive:":  {Count: 1, Bytes: 10},
+		},
+		objects: map[string]int64{"archive:": 3},
+		classes: map[string]map[string]int64{
+			"archive:": {"STANDARD": 1, "GLACIER": 2},
+			"gdrive:":  {"unknown": 1},
+		},
+		types: map[string]string{"archive": "s3", "gdrive": "drive"},
+	}
+	e := NewExporter(client)
+	defer e.Close()
+
+	probe := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?"+query, nil))
+		return rec
+	}
+
+	if body := probe("remote=archive:").Body.String(); strings.Contains(body, "rclone_remote_objects_by_storage_class{") {
+		t.Errorf("storage classes listed without storageclass=true\n%s", body)
+	}
+
+	for _, query := range []string{"remote=archive:&storageclass=true", "remote=archive:&storageclass=true&size=false"} {
+		body := probe(query).Body.String()
+		for _, want := range []string{
+			`rclone_remote_objects_by_storage_class{class="GLACIER",remote="archive:",remote_name="archive"} 2`,
+			`rclone_remote_objects_by_storage_class{class="STANDARD",remote="archive:",remote_name="archive"} 1`,
+		} {
+			if !strings.Contains(body, want) {
+				t.Errorf("%s: probe output missing %q\n%s", query, want, body)
+			}
+		}
+	}
+
+	// Only S3 remotes have storage classes
+	if body := probe("remote=gdrive:&storageclass=true").Body.String(); strings.Contains(body, "rclone_remote_objects_by_storage_class{") {
+		t.Errorf("storage classes listed for a drive remote\n%s", body)
+	}
+
+	if body := probe("remote=archive:&storageclass=true&format=json").Body.String(); !strings.Contains(body, `"storage_classes":{"GLACIER":2,"STANDARD":1}`) {
+		t.Errorf("JSON report missing storage_classes\n%s", body)
+	}
+
+	for _, query := range []string{"remote=archive:&storageclass=maybe", "remote=archive:&storageclass=true&command=dirs"} {
+		if rec := probe(query); probeStatus(rec) != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", query, probeStatus(rec), http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package exporter
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
+)
+
+// ReachableHandler handles /reachable requests, a cheap liveness check that
+// verifies a remote can be listed without computing its size.
+func (e *Exporter) ReachableHandler(w http.ResponseWriter, r *http.Request) {
+	e.reachableTotal.Inc()
+
+	remote := strings.TrimSpace(r.URL.Query().Get("remote"))
+	if err := e.validateRemote(remote); err != nil {
+		e.handleError(w, r, remote, fmt.Sprintf("Invalid remote parameter: %v", err), http.StatusBadRequest, err)
+		return
+	}
+
+	// Reachability checks always run rclone
+	if e.Maintenance() {
+		e.handleError(w, r, remote, "Exporter is in maintenance mode", http.StatusServiceUnavailable, nil)
+		return
+	}
+
+	// Share the probe concurrency limit
+	if !e.tryAcquireProbeSlot() {
+		e.handleError(w, r, remote, "Too many concurrent requests", http.StatusTooManyRequests, nil)
+		return
+	}
+	defer e.releaseProbeSlot()
+
+	remoteName, _ := parseRemoteName(remote)
+	registry := prometheus.NewRegistry()
+
+	reachable := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "remote",
+			Name:      "reachable",
+			Help:      e.help("remote", "reachable"),
+		},
+		[]string{"remote", "remote_name"},
+	)
+
+	reachableDuration := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "remote",
+			Name:      "reachable_duration_seconds",
+			Help:      e.help("remote", "reachable_duration_seconds"),
+		},
+		[]string{"remote", "remote_name"},
+	)
+
+	prometheus.WrapRegistererWith(e.config.ConstLabels, registry).MustRegister(reachable, reachableDuration)
+
+	start := time.Now()
+	err := e.rcloneClient.CheckReachable(remote)
+	duration := time.Since(start).Seconds()
+
+	reachableDuration.WithLabelValues(remote, remoteName).Set(duration)
+	if err != nil {
+		e.scrapeErrorsTotal.Inc()
+		reachable.WithLabelValues(remote, remoteName).Set(0)
+		log.Warn().
+			Err(err).
+			Str("client", r.RemoteAddr).
+			Str("remote", remote).
+			Float64("duration_seconds", duration).
+			Msg("Remote reachability check failed")
+	} else {
+		reachable.WithLabelValues(remote, remoteName).Set(1)
+		log.Debug().
+			Str("remote", remote).
+			Float64("duration_seconds", duration).
+			Msg("Remote reachability check succeeded")
+	}
+
+	promhttp.HandlerFor(registry, e.HandlerOpts()).ServeHTTP(w, r)
+}
@@ -0,0 +1,56 @@
+package exporter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// readRemotesFile reads the remotes listed in a --rclone.remotes-file
+func (e *Exporter) readRemotesFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open remotes file: %w", err)
+	}
+	defer f.Close()
+
+	remotes, err := e.parseRemotesList(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remotes file %s: %w", path, err)
+	}
+	return remotes, nil
+}
+
+// parseRemotesList parses one remote per line, skipping blank lines and # comments.
+// A single invalid line rejects the whole list, so a half-edited file never drops remotes.
+func (e *Exporter) parseRemotesList(r io.Reader) ([]string, error) {
+	var remotes []string
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		remote := strings.TrimSpace(scanner.Text())
+		if remote == "" || strings.HasPrefix(remote, "#") {
+			continue
+		}
+
+		if err := e.validateRemote(remote); err != nil {
+			return nil, fmt.Errorf("line %d: invalid remote %q: %w", line, remote, err)
+		}
+		if remote == ProbeAllRemotes {
+			return nil, fmt.Errorf("line %d: %q is not supported, list the remotes instead", line, remote)
+		}
+
+		if !seen[remote] {
+			seen[remote] = true
+			remotes = append(remotes, remote)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return remotes, nil
+}
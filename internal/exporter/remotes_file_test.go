@@ -0,0 +1,96 @@
+package exporter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crazyuploader/rclone_exporter/internal/rclone"
+)
+
+func TestParseRemotesList(t *testing.T) {
+	e := NewExporter(&fakeClient{})
+	defer e.Close()
+
+	input := `
+# Production remotes
+gdrive:
+  s3:bucket/path  
+
+gdrive:
+# dev:
+`
+	remotes, err := e.parseRemotesList(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parseRemotesList() error = %v", err)
+	}
+	if want := []string{"gdrive:", "s3:bucket/path"}; !slices.Equal(remotes, want) {
+		t.Errorf("parseRemotesList() = %v, want %v", remotes, want)
+	}
+
+	for _, input := range []string{"gdrive:\nbad remote;\n", "all\n"} {
+		if _, err := e.parseRemotesList(strings.NewReader(input)); err == nil {
+			t.Errorf("parseRemotesList(%q) error = nil, want error", input)
+		} else if !strings.Contains(err.Error(), "line ") {
+			t.Errorf("parseRemotesList(%q) error = %v, want the line number", input, err)
+		}
+	}
+}
+
+func TestBackgroundRoundRereadsRemotesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "remotes")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client := &fakeClient{
+		sizes: map[string]*rclone.RcloneSizeOutput{"a:": {Bytes: 1}, "b:": {Bytes: 2}},
+	}
+	e := NewExporterWithConfig(client, Config{RemotesFile: path})
+	defer e.Close()
+
+	results := newBackgroundResults()
+	e.registerer.MustRegister(results)
+
+	write("a:\n")
+	e.backgroundRound(context.Background(), results, nil, 0)
+	if body := scrapeMetrics(e); !strings.Contains(body, `remote="a:"`) || strings.Contains(body, `remote="b:"`) {
+		t.Fatalf("/metrics after the first round:\n%s", body)
+	}
+
+	// Edits take effect on the next round without a restart
+	write("# a: was removed\nb:\n")
+	e.backgroundRound(context.Background(), results, nil, 0)
+	body := scrapeMetrics(e)
+	if strings.Contains(body, `rclone_remote_size_bytes{path="/",remote="a:"`) || !strings.Contains(body, `remote="b:"`) {
+		t.Fatalf("/metrics after editing the file:\n%s", body)
+	}
+
+	// A broken file keeps the previous results
+	write("b:\nbad remote;\n")
+	e.backgroundRound(context.Background(), results, nil, 0)
+	if body := scrapeMetrics(e); !strings.Contains(body, `rclone_remote_size_bytes{path="/",remote="b:"`) {
+		t.Errorf("/metrics dropped results after an invalid edit:\n%s", body)
+	}
+}
+
+func TestStartBackgroundProbesRemotesFile(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	e := NewExporterWithConfig(&fakeClient{}, Config{RemotesFile: filepath.Join(t.TempDir(), "missing")})
+	defer e.Close()
+	if err := e.StartBackgroundProbes(ctx, nil, time.Minute, 0); err == nil {
+		t.Error("StartBackgroundProbes() error = nil for a missing remotes file")
+	}
+	if err := e.StartBackgroundProbes(ctx, []string{"a:"}, time.Minute, 0); err == nil {
+		t.Error("StartBackgroundProbes() error = nil with both a remotes file and remotes")
+	}
+}
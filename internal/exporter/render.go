@@ -0,0 +1,123 @@
+package exporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"github.com/rs/zerolog/log"
+)
+
+// renderedMetrics holds the last rendered /metrics exposition, plain and gzipped,
+// until new probe data invalidates it
+type renderedMetrics struct {
+	mu      sync.Mutex
+	valid   bool
+	plain   []byte
+	gzipped []byte
+}
+
+// invalidate drops the rendered exposition so the next scrape renders it again
+func (c *renderedMetrics) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.valid, c.plain, c.gzipped = false, nil, nil
+}
+
+// get returns the rendered exposition, rendering it from gatherer if it was invalidated.
+// Concurrent scrapes after an invalidation wait for a single render.
+func (c *renderedMetrics) get(gatherer prometheus.Gatherer) (plain, gzipped []byte, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.valid {
+		return c.plain, c.gzipped, nil
+	}
+
+	plain, gzipped, err = renderExposition(gatherer)
+	if err != nil {
+		return nil, nil, err
+	}
+	c.valid, c.plain, c.gzipped = true, plain, gzipped
+	return plain, gzipped, nil
+}
+
+// encodeExposition encodes every metric family of gatherer in the text format
+func encodeExposition(gatherer prometheus.Gatherer) ([]byte, error) {
+	families, err := gatherer.Gather()
+	if err != nil {
+		return nil, fmt.Errorf("failed to gather metrics: %w", err)
+	}
+
+	var buf bytes.Buffer
+	encoder := expfmt.NewEncoder(&buf, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for _, family := range families {
+		if err := encoder.Encode(family); err != nil {
+			return nil, fmt.Errorf("failed to encode metrics: %w", err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// renderExposition encodes every metric family of gatherer in the text format, plain and gzipped
+func renderExposition(gatherer prometheus.Gatherer) (plain, gzipped []byte, err error) {
+	plain, err = encodeExposition(gatherer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	if _, err := gz.Write(plain); err != nil {
+		return nil, nil, fmt.Errorf("failed to compress metrics: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, nil, fmt.Errorf("failed to compress metrics: %w", err)
+	}
+
+	return plain, compressed.Bytes(), nil
+}
+
+// invalidateRenderedMetrics makes the next /metrics scrape render fresh output
+func (e *Exporter) invalidateRenderedMetrics() {
+	if e.config.PrerenderMetrics {
+		e.rendered.invalidate()
+	}
+}
+
+// serveRenderedMetrics serves the pre-rendered exposition, gzipped when the client
+// accepts it. It reports false when rendering failed and the caller should serve live.
+func (e *Exporter) serveRenderedMetrics(w http.ResponseWriter, r *http.Request) bool {
+	plain, gzipped, err := e.rendered.get(e.registry)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to pre-render metrics, serving them live")
+		return false
+	}
+
+	w.Header().Set("Content-Type", string(expfmt.NewFormat(expfmt.TypeTextPlain)))
+	w.Header().Add("Vary", "Accept-Encoding")
+	body := plain
+	if acceptsGzip(r) {
+		w.Header().Set("Content-Encoding", "gzip")
+		body = gzipped
+	}
+	w.Write(body)
+	return true
+}
+
+// acceptsGzip reports whether the request's Accept-Encoding allows gzip
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.EqualFold(strings.TrimSpace(coding), "gzip") && strings.ReplaceAll(strings.TrimSpace(params), " ", "") != "q=0" {
+			return true
+		}
+	}
+	return false
+}
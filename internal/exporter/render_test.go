@@ -0,0 +1,129 @@
+package exporter
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/crazyuploader/rclone_exporter/internal/rclone"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog"
+)
+
+func TestPrerenderedMetrics(t *testing.T) {
+	client := &fakeClient{sizes: map[string]*rclone.RcloneSizeOutput{"a:": {Bytes: 1}}}
+	e := NewExporterWithConfig(client, Config{PrerenderMetrics: true})
+	defer e.Close()
+
+	results := newBackgroundResults()
+	e.registerer.MustRegister(results)
+	e.backgroundRound(context.Background(), results, []string{"a:"}, 0)
+
+	handler := e.MetricsHandler(promhttp.HandlerOpts{})
+	scrape := func() string {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+			t.Fatalf("Content-Encoding = %q, want gzip", got)
+		}
+		gz, err := gzip.NewReader(rec.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(gz)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body)
+	}
+
+	want := `rclone_remote_size_bytes{path="/",remote="a:",remote_name="a",remote_type="unknown"} 1`
+	if body := scrape(); !strings.Contains(body, want) {
+		t.Fatalf("/metrics missing %q\n%s", want, body)
+	}
+
+	// The blob is served until new probe data arrives
+	e.probeRequestsTotal.Inc()
+	if body := scrape(); !strings.Contains(body, "rclone_exporter_probe_requests_total 0") {
+		t.Errorf("/metrics was rendered again without new probe data\n%s", body)
+	}
+
+	client.mu.Lock()
+	client.sizes["a:"] = &rclone.RcloneSizeOutput{Bytes: 2}
+	client.mu.Unlock()
+	e.backgroundRound(context.Background(), results, []string{"a:"}, 0)
+	want = `rclone_remote_size_bytes{path="/",remote="a:",remote_name="a",remote_type="unknown"} 2`
+	if body := scrape(); !strings.Contains(body, want) {
+		t.Errorf("/metrics missing %q after a new round\n%s", want, body)
+	}
+
+	// Clients without gzip get the plain text
+	if body := scrapeMetrics(e); !strings.Contains(body, want) {
+		t.Errorf("plain /metrics missing %q\n%s", want, body)
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := map[string]bool{
+		"":                  false,
+		"gzip":              true,
+		"deflate, gzip;q=1": true,
+		"GZIP":              true,
+		"gzip;q=0":          false,
+		"identity":          false,
+	}
+	for header, want := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Accept-Encoding", header)
+		if got := acceptsGzip(req); got != want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
+
+// benchmarkMetricsScrape scrapes /metrics with background results for many remotes
+func benchmarkMetricsScrape(b *testing.B, prerender bool) {
+	// Probe logs would drown the results
+	level := zerolog.GlobalLevel()
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	defer zerolog.SetGlobalLevel(level)
+
+	client := &fakeClient{sizes: map[string]*rclone.RcloneSizeOutput{}}
+	var remotes []string
+	for i := 0; i < 1000; i++ {
+		remote := fmt.Sprintf("remote%d:", i)
+		remotes = append(remotes, remote)
+		client.sizes[remote] = &rclone.RcloneSizeOutput{Count: int64(i), Bytes: int64(i) * 1024}
+	}
+	e := NewExporterWithConfig(client, Config{PrerenderMetrics: prerender})
+	defer e.Close()
+
+	results := newBackgroundResults()
+	e.registerer.MustRegister(results)
+	e.backgroundRound(context.Background(), results, remotes, 0)
+
+	handler := e.MetricsHandler(promhttp.HandlerOpts{})
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
+
+func BenchmarkMetricsScrapeLive(b *testing.B) {
+	benchmarkMetricsScrape(b, false)
+}
+
+func BenchmarkMetricsScrapePrerendered(b *testing.B) {
+	benchmarkMetricsScrape(b, true)
+}
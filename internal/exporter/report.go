@@ -0,0 +1,151 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Probe output formats selectable via the format query parameter
+const (
+	ProbeFormatPrometheus = "prometheus" // Prometheus text exposition (default)
+	ProbeFormatJSON       = "json"       // Human-oriented JSON report
+)
+
+// byteUnits are the IEC units used by humanizeBytes
+var byteUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+
+// probeResult is the JSON report of a single remote probe
+type probeResult struct {
+	Remote          string   `json:"remote"`
+	RemoteName      string   `json:"remote_name"`
+	Path            string   `json:"path"`
+	RemoteType      string   `json:"remote_type"`
+	BackendType     string   `json:"backend_type,omitempty"`
+	Command         string   `json:"command"`
+	Success         bool     `json:"success"`
+	Error           string   `json:"error,omitempty"`
+	TokenInvalid    bool     `json:"token_invalid,omitempty"`
+	DurationSeconds float64  `json:"duration_seconds"`
+	Bytes           *int64   `json:"bytes,omitempty"`
+	BytesHuman      string   `json:"bytes_human,omitempty"`
+	Objects         *int64   `json:"objects,omitempty"`
+	Dirs            *int64   `json:"dirs,omitempty"`
+	Duplicates      *int64   `json:"duplicates,omitempty"`
+	QuotaTotalBytes *int64   `json:"quota_total_bytes,omitempty"`
+	QuotaUsedBytes  *int64   `json:"quota_used_bytes,omitempty"`
+	QuotaFreeBytes  *int64   `json:"quota_free_bytes,omitempty"`
+	TrashedBytes    *int64   `json:"trashed_bytes,omitempty"`
+	FreePercent     *float64 `json:"free_percent,omitempty"`
+	Warnings        *int     `json:"warnings,omitempty"`
+
+	StorageClasses map[string]int64 `json:"storage_classes,omitempty"`
+
+	cached bool // The result was served from the size cache without running rclone
+}
+
+// setBytes records a byte total together with its human-readable form
+func (r *probeResult) setBytes(bytes int64) {
+	r.Bytes = &bytes
+	r.BytesHuman = humanizeBytes(bytes)
+}
+
+// probeReport collects the results of the remotes probed by one request
+type probeReport struct {
+	mu      sync.Mutex
+	results []*probeResult
+}
+
+// add appends a result to the report
+func (p *probeReport) add(result *probeResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.results = append(p.results, result)
+}
+
+// parseFormat validates the optional format parameter, defaulting to Prometheus output
+func parseFormat(value string) (string, error) {
+	switch value {
+	case "", ProbeFormatPrometheus:
+		return ProbeFormatPrometheus, nil
+	case ProbeFormatJSON:
+		return ProbeFormatJSON, nil
+	default:
+		return "", fmt.Errorf("format must be %q or %q", ProbeFormatPrometheus, ProbeFormatJSON)
+	}
+}
+
+// negotiateFormat picks the probe output format from an Accept header. JSON is only
+// chosen when application/json is preferred over every Prometheus exposition type;
+// a missing, wildcard-only or tied header keeps the Prometheus default.
+func negotiateFormat(accept string) string {
+	jsonQ, promQ := 0.0, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(key, "q") {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+
+		switch strings.ToLower(strings.TrimSpace(mediaType)) {
+		case "application/json":
+			jsonQ = math.Max(jsonQ, q)
+		case "text/plain", "application/openmetrics-text", "application/vnd.google.protobuf":
+			promQ = math.Max(promQ, q)
+		}
+	}
+
+	if jsonQ > promQ {
+		return ProbeFormatJSON
+	}
+	return ProbeFormatPrometheus
+}
+
+// writeJSONReport serves the collected probe results as JSON
+func writeJSONReport(w http.ResponseWriter, report *probeReport, total time.Duration) {
+	report.mu.Lock()
+	results := append([]*probeResult(nil), report.results...)
+	report.mu.Unlock()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Remote < results[j].Remote })
+
+	resp := map[string]interface{}{
+		"results":                results,
+		"timestamp":              time.Now().UTC().Format(time.RFC3339),
+		"total_duration_seconds": total.Seconds(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		http.Error(w, "Failed to encode probe results as JSON", http.StatusInternalServerError)
+	}
+}
+
+// humanizeBytes formats a byte count with IEC units, e.g. "1.5 TiB"
+func humanizeBytes(bytes int64) string {
+	if bytes < 1024 && bytes > -1024 {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	value := float64(bytes)
+	unit := 0
+	// Move up a unit whenever the rounded value would reach 1024
+	for unit < len(byteUnits)-1 && math.Abs(math.Round(value*10)/10) >= 1024 {
+		value /= 1024
+		unit++
+	}
+
+	return fmt.Sprintf("%.1f %s", value, byteUnits[unit])
+}
@@ -0,0 +1,135 @@
+package exporter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/crazyuploader/rclone_exporter/internal/rclone"
+)
+
+func TestHumanizeBytes(t *testing.T) {
+	tests := []struct {
+		bytes int64
+		want  string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{1<<20 - 1, "1.0 MiB"},
+		{1 << 20, "1.0 MiB"},
+		{1<<30 - 1, "1.0 GiB"},
+		{5 * 1 << 30, "5.0 GiB"},
+		{1 << 40, "1.0 TiB"},
+		{3 << 39, "1.5 TiB"},
+		{1 << 50, "1.0 PiB"},
+	}
+
+	for _, tt := range tests {
+		if got := humanizeBytes(tt.bytes); got != tt.want {
+			t.Errorf("humanizeBytes(%d) = %q, want %q", tt.bytes, got, tt.want)
+		}
+	}
+}
+
+func TestProbeJSONFormat(t *testing.T) {
+	client := &fakeClient{
+		sizes: map[string]*rclone.RcloneSizeOutput{"remote:": {Count: 4, Bytes: 3 << 39}},
+		types: map[string]string{"remote": "s3"},
+	}
+	e := NewExporter(client)
+	defer e.Close()
+
+	rec := httptest.NewRecorder()
+	e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=remote:&format=json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+
+	var resp struct {
+		Results []probeResult `json:"results"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON response: %v\n%s", err, rec.Body)
+	}
+	if len(resp.Results) != 1 {
+		t.Fatalf("got %d results, want 1", len(resp.Results))
+	}
+
+	got := resp.Results[0]
+	if !got.Success || got.RemoteType != "s3" || got.BytesHuman != "1.5 TiB" || got.Objects == nil || *got.Objects != 4 {
+		t.Errorf("unexpected result %+v", got)
+	}
+}
+
+func TestProbeRejectsUnknownFormat(t *testing.T) {
+	e := NewExporter(&fakeClient{})
+	defer e.Close()
+
+	rec := httptest.NewRecorder()
+	e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=remote:&format=xml", nil))
+	if probeStatus(rec) != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", probeStatus(rec), http.StatusBadRequest)
+	}
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", ProbeFormatPrometheus},
+		{"*/*", ProbeFormatPrometheus},
+		{"application/json", ProbeFormatJSON},
+		{"Application/JSON; charset=utf-8", ProbeFormatJSON},
+		{"text/plain;version=0.0.4", ProbeFormatPrometheus},
+		{"application/openmetrics-text;version=1.0.0,text/plain;q=0.5", ProbeFormatPrometheus},
+		{"application/json,text/plain", ProbeFormatPrometheus}, // Tie
+		{"text/plain;q=0.5,application/json;q=0.9", ProbeFormatJSON},
+		{"application/json;q=0.2,text/plain;q=0.8", ProbeFormatPrometheus},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", ProbeFormatPrometheus}, // Browser
+	}
+	for _, tt := range tests {
+		if got := negotiateFormat(tt.accept); got != tt.want {
+			t.Errorf("negotiateFormat(%q) = %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestProbeAcceptNegotiation(t *testing.T) {
+	client := &fakeClient{sizes: map[string]*rclone.RcloneSizeOutput{"remote:": {Count: 1, Bytes: 2}}}
+	e := NewExporter(client)
+	defer e.Close()
+
+	tests := []struct {
+		url    string
+		accept string
+		want   string
+	}{
+		{"/probe?remote=remote:", "application/json", "application/json"},
+		{"/probe?remote=remote:", "", "text/plain"},
+		{"/probe?remote=remote:&format=prometheus", "application/json", "text/plain"}, // Parameter wins
+		{"/probe?remote=remote:&format=json", "text/plain", "application/json"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		rec := httptest.NewRecorder()
+		e.ProbeHandler(rec, req)
+
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, tt.want) {
+			t.Errorf("%s with Accept %q: Content-Type = %q, want %s", tt.url, tt.accept, ct, tt.want)
+		}
+		if vary := rec.Header().Get("Vary"); vary != "Accept" {
+			t.Errorf("%s: Vary = %q, want Accept", tt.url, vary)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package exporter
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ScrapeTimeoutHeader carries the scrape timeout Prometheus is willing to wait
+const ScrapeTimeoutHeader = "X-Prometheus-Scrape-Timeout-Seconds"
+
+// scrapeTimeoutOffset is kept off the Prometheus scrape timeout so the response,
+// partial or not, arrives before Prometheus gives up
+const scrapeTimeoutOffset = 500 * time.Millisecond
+
+// scrapeTimeout returns how long a request may take before partial results are served:
+// the Prometheus scrape timeout minus scrapeTimeoutOffset, capped by Config.ScrapeTimeout.
+// Zero means no limit.
+func (e *Exporter) scrapeTimeout(r *http.Request) time.Duration {
+	timeout := e.config.ScrapeTimeout
+
+	value := strings.TrimSpace(r.Header.Get(ScrapeTimeoutHeader))
+	if value == "" {
+		return timeout
+	}
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds <= 0 {
+		return timeout
+	}
+
+	// Very short scrape timeouts are used as is rather than dropping to nothing
+	header := time.Duration(seconds * float64(time.Second))
+	if header > scrapeTimeoutOffset {
+		header -= scrapeTimeoutOffset
+	}
+	if timeout <= 0 || header < timeout {
+		return header
+	}
+	return timeout
+}
+
+// remoteListResults collects the metrics of the remotes of one multi-remote probe,
+// each probed into its own probeMetrics, so remotes still running at the scrape
+// timeout cannot change a response that is already being served
+type remoteListResults struct {
+	mu      sync.Mutex
+	expired bool
+	remotes map[string]*probeMetrics
+}
+
+// newRemoteListResults creates an empty result set
+func newRemoteListResults() *remoteListResults {
+	return &remoteListResults{remotes: make(map[string]*probeMetrics)}
+}
+
+// add stores the metrics of a finished remote. It reports false, dropping them,
+// once the results have expired.
+func (l *remoteListResults) add(remote string, m *probeMetrics) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.expired {
+		return false
+	}
+	l.remotes[remote] = m
+	return true
+}
+
+// expire stops accepting results and returns the remotes that have not finished
+func (l *remoteListResults) expire(remotes []string) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.expired = true
+	var pending []string
+	for _, remote := range remotes {
+		if _, ok := l.remotes[remote]; !ok {
+			pending = append(pending, remote)
+		}
+	}
+	return pending
+}
+
+// results returns the JSON report entries of the finished remotes
+func (l *remoteListResults) results() []*probeResult {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var results []*probeResult
+	for _, m := range l.remotes {
+		m.report.mu.Lock()
+		results = append(results, m.report.results...)
+		m.report.mu.Unlock()
+	}
+	return results
+}
+
+// Describe implements prometheus.Collector
+func (l *remoteListResults) Describe(chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector
+func (l *remoteListResults) Collect(ch chan<- prometheus.Metric) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, m := range l.remotes {
+		for _, c := range m.remoteCollectors() {
+			c.Collect(ch)
+		}
+		m.consecutiveFailures.Collect(ch)
+	}
+}
@@ -0,0 +1,188 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crazyuploader/rclone_exporter/internal/rclone"
+	"github.com/crazyuploader/rclone_exporter/internal/syncstats"
+)
+
+func TestScrapeTimeout(t *testing.T) {
+	tests := []struct {
+		name   string
+		config time.Duration
+		header string
+		want   time.Duration
+	}{
+		{"no limit", 0, "", 0},
+		{"flag only", 30 * time.Second, "", 30 * time.Second},
+		{"header minus offset", 0, "10", 9500 * time.Millisecond},
+		{"fractional header", 0, "2.5", 2 * time.Second},
+		{"tiny header used as is", 0, "0.2", 200 * time.Millisecond},
+		{"shorter header wins", 30 * time.Second, "10", 9500 * time.Millisecond},
+		{"shorter flag wins", 5 * time.Second, "10", 5 * time.Second},
+		{"invalid header ignored", 5 * time.Second, "soon", 5 * time.Second},
+		{"zero header ignored", 0, "0", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewExporterWithConfig(&fakeClient{}, Config{ScrapeTimeout: tt.config})
+			defer e.Close()
+
+			r := httptest.NewRequest(http.MethodGet, "/probe", nil)
+			if tt.header != "" {
+				r.Header.Set(ScrapeTimeoutHeader, tt.header)
+			}
+			if got := e.scrapeTimeout(r); got != tt.want {
+				t.Errorf("scrapeTimeout() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProbeAllServesPartialResultsAtScrapeTimeout(t *testing.T) {
+	release := make(chan struct{})
+	client := &fakeClient{
+		remotes: []rclone.RemoteInfo{{Name: "fast", Type: "s3"}, {Name: "slow", Type: "drive"}},
+		sizes: map[string]*rclone.RcloneSizeOutput{
+			"fast:": {Count: 1, Bytes: 10},
+			"slow:": {Count: 2, Bytes: 20},
+		},
+		types: map[string]string{"fast": "s3", "slow": "drive"},
+		onSize: func(remote string) {
+			if remote == "slow:" {
+				<-release
+			}
+		},
+	}
+	e := NewExporter(client)
+	defer e.Close()
+	defer close(release)
+
+	req := httptest.NewRequest(http.MethodGet, "/probe?remote=all", nil)
+	req.Header.Set(ScrapeTimeoutHeader, "0.6")
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	e.ProbeHandler(rec, req)
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("probe took %s, want it to return at the scrape timeout", elapsed)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`rclone_probe_success{remote="fast:",remote_name="fast",remote_type="s3"} 1`,
+		`rclone_remote_size_bytes{path="/",remote="fast:",remote_name="fast",remote_type="s3"} 10`,
+		`rclone_probe_success{remote="slow:",remote_name="slow",remote_type="drive"} 0`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("probe output missing %q\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, `remote="slow:",remote_name="slow",remote_type="drive"} 20`) {
+		t.Errorf("probe output reports the unfinished remote's size\n%s", body)
+	}
+}
+
+func TestProbeAllJSONReportsScrapeTimeout(t *testing.T) {
+	release := make(chan struct{})
+	client := &fakeClient{
+		remotes: []rclone.RemoteInfo{{Name: "slow", Type: "drive"}},
+		sizes:   map[string]*rclone.RcloneSizeOutput{"slow:": {}},
+		onSize:  func(string) { <-release },
+	}
+	e := NewExporterWithConfig(client, Config{ScrapeTimeout: 100 * time.Millisecond})
+	defer e.Close()
+	defer close(release)
+
+	rec := httptest.NewRecorder()
+	e.ProbeHandler(rec, httptest.NewRequest(http.MethodGet, "/probe?remote=all&format=json", nil))
+
+	body := rec.Body.String()
+	if !strings.Contains(body, `"remote":"slow:"`) || !strings.Contains(body, "did not finish within the scrape timeout") {
+		t.Errorf("JSON report missing the timed out remote\n%s", body)
+	}
+}
+
+// blockingSyncSource is a sync stats source that only answers once its context ends
+type blockingSyncSource struct{}
+
+func (blockingSyncSource) Stats(ctx context.Context) (*syncstats.Stats, error) {
+	<-ctx.Done()
+	return nil, errors.New("sync stats timed out")
+}
+
+func TestSyncStatsHandlerScrapeTimeout(t *testing.T) {
+	e := NewExporter(&fakeClient{})
+	defer e.Close()
+
+	req := httptest.NewRequest(http.MethodGet, "/sync", nil)
+	req.Header.Set(ScrapeTimeoutHeader, "0.6")
+	rec := httptest.NewRecorder()
+	e.SyncStatsHandler(blockingSyncSource{}).ServeHTTP(rec, req)
+
+	if !strings.Contains(rec.Body.String(), "rclone_sync_stats_up 0") {
+		t.Errorf("sync stats output missing stats_up 0\n%s", rec.Body)
+	}
+}
+
+func TestProbeScrapeTimeoutHeaderBoundsRclone(t *testing.T) {
+	tests := []struct {
+		name         string
+		probeTimeout time.Duration
+		header       string
+		want         time.Duration
+	}{
+		{"no header keeps the rclone timeout", 2 * time.Minute, "", 0},
+		{"shorter header", 2 * time.Minute, "10", 9500 * time.Millisecond},
+		{"longer header keeps the rclone timeout", 5 * time.Second, "10", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeClient{sizes: map[string]*rclone.RcloneSizeOutput{"remote:": {Count: 1, Bytes: 2}}}
+			e := NewExporterWithConfig(client, Config{ProbeTimeout: tt.probeTimeout})
+			defer e.Close()
+
+			req := httptest.NewRequest(http.MethodGet, "/probe?remote=remote:", nil)
+			if tt.header != "" {
+				req.Header.Set(ScrapeTimeoutHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			e.ProbeHandler(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+			if client.sizeOpts.Timeout != tt.want {
+				t.Errorf("rclone timeout = %s, want %s", client.sizeOpts.Timeout, tt.want)
+			}
+		})
+	}
+}
+
+func TestScrapeTimeoutSharesCachedResults(t *testing.T) {
+	client := &fakeClient{sizes: map[string]*rclone.RcloneSizeOutput{"remote:": {Count: 1, Bytes: 2}}}
+	e := NewExporterWithConfig(client, Config{SizeCacheTTL: time.Hour})
+	defer e.Close()
+
+	for _, header := range []string{"10", "30", ""} {
+		req := httptest.NewRequest(http.MethodGet, "/probe?remote=remote:", nil)
+		if header != "" {
+			req.Header.Set(ScrapeTimeoutHeader, header)
+		}
+		e.ProbeHandler(httptest.NewRecorder(), req)
+	}
+
+	// Probes differing only in their deadline share one cached result
+	if client.sizeCalls != 1 {
+		t.Errorf("rclone size ran %d times, want 1", client.sizeCalls)
+	}
+}
@@ -0,0 +1,294 @@
+package exporter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// MaxSnapshots caps how many named snapshots are kept in memory. Taking another one
+// drops the oldest.
+const MaxSnapshots = 10
+
+// snapshotNameRegex limits snapshot names to short identifiers such as before-migration
+var snapshotNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_.\-]{1,64}$`)
+
+// errSnapshotNotFound is returned for diffs against a snapshot that was never taken or was dropped
+var errSnapshotNotFound = errors.New("snapshot not found")
+
+// SnapshotEntry is the size of one remote at the time of a snapshot
+type SnapshotEntry struct {
+	Bytes   int64 `json:"bytes"`
+	Objects int64 `json:"objects"`
+}
+
+// Snapshot holds the sizes of every configured remote at one point in time. Remotes
+// whose size probe failed are listed in Failed with the error.
+type Snapshot struct {
+	Name    string                   `json:"name"`
+	TakenAt time.Time                `json:"taken_at"`
+	Remotes map[string]SnapshotEntry `json:"remotes"`
+	Failed  map[string]string        `json:"failed,omitempty"`
+}
+
+// SnapshotDelta is the change of one remote between two snapshots. From or To is
+// nil when the remote is missing from that snapshot, and counts as zero in the deltas.
+type SnapshotDelta struct {
+	Remote       string         `json:"remote"`
+	From         *SnapshotEntry `json:"from"`
+	To           *SnapshotEntry `json:"to"`
+	BytesDelta   int64          `json:"bytes_delta"`
+	ObjectsDelta int64          `json:"objects_delta"`
+}
+
+// snapshotStore keeps the most recent named snapshots, oldest first
+type snapshotStore struct {
+	mu        sync.Mutex
+	snapshots []*Snapshot
+}
+
+// put stores a snapshot, replacing one with the same name and dropping the oldest
+// beyond MaxSnapshots
+func (s *snapshotStore) put(snapshot *Snapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.snapshots = slices.DeleteFunc(s.snapshots, func(existing *Snapshot) bool { return existing.Name == snapshot.Name })
+	s.snapshots = append(s.snapshots, snapshot)
+	if len(s.snapshots) > MaxSnapshots {
+		s.snapshots = s.snapshots[len(s.snapshots)-MaxSnapshots:]
+	}
+}
+
+// get returns the snapshot with the given name
+func (s *snapshotStore) get(name string) (*Snapshot, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, snapshot := range s.snapshots {
+		if snapshot.Name == name {
+			return snapshot, true
+		}
+	}
+	return nil, false
+}
+
+// names returns the names of the stored snapshots, oldest first
+func (s *snapshotStore) names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.snapshots))
+	for _, snapshot := range s.snapshots {
+		names = append(names, snapshot.Name)
+	}
+	return names
+}
+
+// takeSnapshot runs a fresh rclone size of every configured remote, with the fan-out
+// cap of remote=all, and stores the results under name. The size cache is bypassed so
+// the snapshot reflects the remotes right now.
+func (e *Exporter) takeSnapshot(w http.ResponseWriter, r *http.Request, name string) (*Snapshot, error) {
+	remotes, err := e.rcloneClient.ListRemotes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remotes: %w", err)
+	}
+	names := remoteNames(remotes)
+
+	batches := (len(names) + MaxProbeAllConcurrency - 1) / MaxProbeAllConcurrency
+	e.extendWriteDeadline(w, batches)
+
+	snapshot := &Snapshot{
+		Name:    name,
+		TakenAt: time.Now().UTC(),
+		Remotes: make(map[string]SnapshotEntry, len(names)),
+		Failed:  make(map[string]string),
+	}
+	var mu sync.Mutex
+	fanOut := make(chan struct{}, MaxProbeAllConcurrency)
+	var wg sync.WaitGroup
+	for _, remote := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			select {
+			case fanOut <- struct{}{}:
+				defer func() { <-fanOut }()
+			case <-r.Context().Done():
+				return
+			}
+			if !e.acquireProbeSlot(r.Context().Done()) {
+				return
+			}
+			defer e.releaseProbeSlot()
+
+			output, err := e.rcloneClient.GetRemoteSize(remote)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				e.scrapeErrorsTotal.Inc()
+				snapshot.Failed[remote] = err.Error()
+				return
+			}
+			snapshot.Remotes[remote] = SnapshotEntry{Bytes: output.Bytes, Objects: output.Count}
+		}()
+	}
+	wg.Wait()
+
+	if err := r.Context().Err(); err != nil {
+		return nil, err
+	}
+	if len(snapshot.Failed) == 0 {
+		snapshot.Failed = nil
+	}
+
+	e.snapshots.put(snapshot)
+	return snapshot, nil
+}
+
+// diffSnapshots returns the per-remote change from one snapshot to another, sorted by
+// remote. Remotes that failed in either snapshot have no delta and are returned as skipped.
+func (e *Exporter) diffSnapshots(from, to string) (deltas []SnapshotDelta, skipped []string, err error) {
+	fromSnapshot, ok := e.snapshots.get(from)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", errSnapshotNotFound, from)
+	}
+	toSnapshot, ok := e.snapshots.get(to)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", errSnapshotNotFound, to)
+	}
+
+	remotes := make(map[string]bool)
+	for remote := range fromSnapshot.Remotes {
+		remotes[remote] = true
+	}
+	for remote := range toSnapshot.Remotes {
+		remotes[remote] = true
+	}
+
+	for remote := range fromSnapshot.Failed {
+		remotes[remote] = true
+	}
+	for remote := range toSnapshot.Failed {
+		remotes[remote] = true
+	}
+
+	deltas = make([]SnapshotDelta, 0, len(remotes))
+	for remote := range remotes {
+		_, fromFailed := fromSnapshot.Failed[remote]
+		_, toFailed := toSnapshot.Failed[remote]
+		if fromFailed || toFailed {
+			skipped = append(skipped, remote)
+			continue
+		}
+
+		delta := SnapshotDelta{Remote: remote}
+		if entry, ok := fromSnapshot.Remotes[remote]; ok {
+			delta.From = &entry
+			delta.BytesDelta -= entry.Bytes
+			delta.ObjectsDelta -= entry.Objects
+		}
+		if entry, ok := toSnapshot.Remotes[remote]; ok {
+			delta.To = &entry
+			delta.BytesDelta += entry.Bytes
+			delta.ObjectsDelta += entry.Objects
+		}
+		deltas = append(deltas, delta)
+	}
+	sort.Slice(deltas, func(i, j int) bool { return deltas[i].Remote < deltas[j].Remote })
+	sort.Strings(skipped)
+	return deltas, skipped, nil
+}
+
+// SnapshotHandler records the sizes of all configured remotes under ?name= on POST and
+// lists the stored snapshot names on GET
+func (e *Exporter) SnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeSnapshotJSON(w, map[string]interface{}{
+			"snapshots": e.snapshots.names(),
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	case http.MethodPost:
+	default:
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimSpace(r.URL.Query().Get("name"))
+	if !snapshotNameRegex.MatchString(name) {
+		e.handleError(w, r, "", "Invalid name parameter: use 1 to 64 letters, digits, '.', '_' or '-'", http.StatusBadRequest, nil)
+		return
+	}
+	if e.Maintenance() {
+		e.handleError(w, r, "", "Exporter is in maintenance mode", http.StatusServiceUnavailable, nil)
+		return
+	}
+
+	snapshot, err := e.takeSnapshot(w, r, name)
+	if err != nil {
+		e.handleError(w, r, "", "Failed to take snapshot", http.StatusInternalServerError, err)
+		return
+	}
+
+	log.Info().
+		Str("client", r.RemoteAddr).
+		Str("snapshot", name).
+		Int("remotes", len(snapshot.Remotes)).
+		Int("failed", len(snapshot.Failed)).
+		Msg("Snapshot taken via admin endpoint")
+
+	writeSnapshotJSON(w, snapshot)
+}
+
+// SnapshotDiffHandler serves the per-remote byte and object deltas between the
+// snapshots named by ?from= and ?to=
+func (e *Exporter) SnapshotDiffHandler(w http.ResponseWriter, r *http.Request) {
+	from := strings.TrimSpace(r.URL.Query().Get("from"))
+	to := strings.TrimSpace(r.URL.Query().Get("to"))
+	if from == "" || to == "" {
+		e.handleError(w, r, "", "Missing from or to parameter", http.StatusBadRequest, nil)
+		return
+	}
+
+	deltas, skipped, err := e.diffSnapshots(from, to)
+	if errors.Is(err, errSnapshotNotFound) {
+		e.handleError(w, r, "", err.Error(), http.StatusNotFound, err)
+		return
+	}
+
+	var bytesDelta, objectsDelta int64
+	for _, delta := range deltas {
+		bytesDelta += delta.BytesDelta
+		objectsDelta += delta.ObjectsDelta
+	}
+	writeSnapshotJSON(w, map[string]interface{}{
+		"from":                from,
+		"to":                  to,
+		"remotes":             deltas,
+		"skipped":             skipped,
+		"total_bytes_delta":   bytesDelta,
+		"total_objects_delta": objectsDelta,
+		"timestamp":           time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// writeSnapshotJSON serves a snapshot response as JSON
+func writeSnapshotJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error().Err(err).Msg("Failed to encode snapshot response")
+	}
+}
@@ -0,0 +1,154 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crazyuploader/rclone_exporter/internal/rclone"
+)
+
+// snapshotDiff is the decoded response of SnapshotDiffHandler
+type snapshotDiff struct {
+	Remotes           []SnapshotDelta `json:"remotes"`
+	Skipped           []string        `json:"skipped"`
+	TotalBytesDelta   int64           `json:"total_bytes_delta"`
+	TotalObjectsDelta int64           `json:"total_objects_delta"`
+}
+
+func TestSnapshotDiff(t *testing.T) {
+	client := &fakeClient{
+		remotes: []rclone.RemoteInfo{{Name: "src"}, {Name: "dst"}, {Name: "flaky"}},
+		sizes: map[string]*rclone.RcloneSizeOutput{
+			"src:":   {Count: 10, Bytes: 1000},
+			"dst:":   {Count: 0, Bytes: 0},
+			"flaky:": {Count: 1, Bytes: 1},
+		},
+	}
+	e := NewExporter(client)
+	defer e.Close()
+
+	snapshot := func(name string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.SnapshotHandler(rec, httptest.NewRequest(http.MethodPost, "/admin/snapshot?name="+name, nil))
+		return rec
+	}
+
+	if rec := snapshot("before"); rec.Code != http.StatusOK {
+		t.Fatalf("snapshot status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+
+	// The migration moves everything from src to dst while flaky stops answering
+	client.mu.Lock()
+	client.sizes["src:"] = &rclone.RcloneSizeOutput{}
+	client.sizes["dst:"] = &rclone.RcloneSizeOutput{Count: 10, Bytes: 1000}
+	delete(client.sizes, "flaky:")
+	client.mu.Unlock()
+
+	rec := snapshot("after")
+	var after Snapshot
+	if err := json.Unmarshal(rec.Body.Bytes(), &after); err != nil {
+		t.Fatalf("decoding snapshot: %v\n%s", err, rec.Body)
+	}
+	if _, ok := after.Failed["flaky:"]; !ok || len(after.Remotes) != 2 {
+		t.Errorf("snapshot = %+v, want src: and dst: with flaky: failed", after)
+	}
+
+	rec = httptest.NewRecorder()
+	e.SnapshotDiffHandler(rec, httptest.NewRequest(http.MethodGet, "/admin/snapshot/diff?from=before&to=after", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("diff status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var diff snapshotDiff
+	if err := json.Unmarshal(rec.Body.Bytes(), &diff); err != nil {
+		t.Fatalf("decoding diff: %v\n%s", err, rec.Body)
+	}
+
+	if len(diff.Remotes) != 2 || diff.Remotes[0].Remote != "dst:" || diff.Remotes[1].Remote != "src:" {
+		t.Fatalf("diff remotes = %+v, want dst: and src:", diff.Remotes)
+	}
+	if d := diff.Remotes[0]; d.BytesDelta != 1000 || d.ObjectsDelta != 10 {
+		t.Errorf("dst: delta = %+v, want +1000 bytes and +10 objects", d)
+	}
+	if d := diff.Remotes[1]; d.BytesDelta != -1000 || d.ObjectsDelta != -10 {
+		t.Errorf("src: delta = %+v, want -1000 bytes and -10 objects", d)
+	}
+	if diff.TotalBytesDelta != 0 || diff.TotalObjectsDelta != 0 {
+		t.Errorf("total delta = %d bytes, %d objects, want 0 for a complete move", diff.TotalBytesDelta, diff.TotalObjectsDelta)
+	}
+	if len(diff.Skipped) != 1 || diff.Skipped[0] != "flaky:" {
+		t.Errorf("skipped = %v, want [flaky:]", diff.Skipped)
+	}
+}
+
+func TestSnapshotDiffHandlerErrors(t *testing.T) {
+	e := NewExporter(&fakeClient{})
+	defer e.Close()
+	e.snapshots.put(&Snapshot{Name: "only"})
+
+	for query, want := range map[string]int{
+		"from=only":            http.StatusBadRequest,
+		"from=only&to=missing": http.StatusNotFound,
+		"from=missing&to=only": http.StatusNotFound,
+		"from=only&to=only":    http.StatusOK,
+		"from=&to=only":        http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		e.SnapshotDiffHandler(rec, httptest.NewRequest(http.MethodGet, "/admin/snapshot/diff?"+query, nil))
+		if rec.Code != want {
+			t.Errorf("%s: status = %d, want %d", query, rec.Code, want)
+		}
+	}
+}
+
+func TestSnapshotHandlerValidation(t *testing.T) {
+	e := NewExporter(&fakeClient{})
+	defer e.Close()
+
+	for _, tt := range []struct {
+		method, query string
+		want          int
+	}{
+		{http.MethodGet, "", http.StatusOK},
+		{http.MethodPost, "", http.StatusBadRequest},
+		{http.MethodPost, "name=bad/name", http.StatusBadRequest},
+		{http.MethodDelete, "name=ok", http.StatusMethodNotAllowed},
+	} {
+		rec := httptest.NewRecorder()
+		e.SnapshotHandler(rec, httptest.NewRequest(tt.method, "/admin/snapshot?"+tt.query, nil))
+		if rec.Code != tt.want {
+			t.Errorf("%s %s: status = %d, want %d", tt.method, tt.query, rec.Code, tt.want)
+		}
+	}
+
+	e.SetMaintenance(true)
+	rec := httptest.NewRecorder()
+	e.SnapshotHandler(rec, httptest.NewRequest(http.MethodPost, "/admin/snapshot?name=ok", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("snapshot in maintenance: status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestSnapshotStoreBounded(t *testing.T) {
+	var store snapshotStore
+	for i := 0; i < MaxSnapshots+3; i++ {
+		store.put(&Snapshot{Name: fmt.Sprintf("s%d", i)})
+	}
+
+	names := store.names()
+	if len(names) != MaxSnapshots || names[0] != "s3" {
+		t.Fatalf("names = %v, want the %d newest starting at s3", names, MaxSnapshots)
+	}
+
+	// Retaking a name replaces it and makes it the newest
+	store.put(&Snapshot{Name: "s3", Remotes: map[string]SnapshotEntry{"a:": {Bytes: 1}}})
+	names = store.names()
+	if len(names) != MaxSnapshots || names[len(names)-1] != "s3" || names[0] != "s4" {
+		t.Errorf("names = %v, want s3 moved to the end", names)
+	}
+	if snapshot, _ := store.get("s3"); snapshot.Remotes["a:"].Bytes != 1 {
+		t.Errorf("get(s3) = %+v, want the replacement", snapshot)
+	}
+}
@@ -0,0 +1,84 @@
+package exporter
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/crazyuploader/rclone_exporter/internal/syncstats"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
+)
+
+// syncStatsCollector exports the statistics of a separately running rclone sync
+type syncStatsCollector struct {
+	ctx    context.Context
+	source syncstats.Source
+
+	up        *prometheus.Desc
+	transfers *prometheus.Desc
+	errors    *prometheus.Desc
+	checks    *prometheus.Desc
+	bytes     *prometheus.Desc
+}
+
+// newSyncStatsCollector creates a collector reading from source on every collection
+func (e *Exporter) newSyncStatsCollector(ctx context.Context, source syncstats.Source) *syncStatsCollector {
+	desc := func(name string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "sync", name), e.help("sync", name), nil, nil)
+	}
+
+	return &syncStatsCollector{
+		ctx:       ctx,
+		source:    source,
+		up:        desc("stats_up"),
+		transfers: desc("transfers_total"),
+		errors:    desc("errors_total"),
+		checks:    desc("checks_total"),
+		bytes:     desc("bytes_transferred"),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *syncStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.up
+	ch <- c.transfers
+	ch <- c.errors
+	ch <- c.checks
+	ch <- c.bytes
+}
+
+// Collect implements prometheus.Collector
+func (c *syncStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats, err := c.source.Stats(c.ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to read rclone sync stats")
+		ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 0)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 1)
+	ch <- prometheus.MustNewConstMetric(c.transfers, prometheus.CounterValue, float64(stats.Transfers))
+	ch <- prometheus.MustNewConstMetric(c.errors, prometheus.CounterValue, float64(stats.Errors))
+	ch <- prometheus.MustNewConstMetric(c.checks, prometheus.CounterValue, float64(stats.Checks))
+	ch <- prometheus.MustNewConstMetric(c.bytes, prometheus.CounterValue, float64(stats.Bytes))
+}
+
+// SyncStatsHandler serves the statistics of a separately scheduled rclone sync read from
+// source. It only reads already-produced stats and never starts a sync itself.
+func (e *Exporter) SyncStatsHandler(source syncstats.Source) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// A source that does not answer in time is reported with stats_up 0
+		ctx := r.Context()
+		if timeout := e.scrapeTimeout(r); timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		registry := prometheus.NewRegistry()
+		prometheus.WrapRegistererWith(e.config.ConstLabels, registry).MustRegister(e.newSyncStatsCollector(ctx, source))
+
+		promhttp.HandlerFor(registry, e.HandlerOpts()).ServeHTTP(w, r)
+	}
+}
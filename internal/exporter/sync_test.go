@@ -0,0 +1,48 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/crazyuploader/rclone_exporter/internal/syncstats"
+)
+
+// fakeSyncSource returns fixed sync stats or an error
+type fakeSyncSource struct {
+	stats *syncstats.Stats
+	err   error
+}
+
+func (f *fakeSyncSource) Stats(context.Context) (*syncstats.Stats, error) { return f.stats, f.err }
+
+func TestSyncStatsHandler(t *testing.T) {
+	e := NewExporter(&fakeClient{})
+	defer e.Close()
+
+	source := &fakeSyncSource{stats: &syncstats.Stats{Bytes: 100, Errors: 2, Transfers: 5, Checks: 9}}
+	rec := httptest.NewRecorder()
+	e.SyncStatsHandler(source)(rec, httptest.NewRequest(http.MethodGet, "/sync", nil))
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		"rclone_sync_stats_up 1",
+		"rclone_sync_transfers_total 5",
+		"rclone_sync_errors_total 2",
+		"rclone_sync_checks_total 9",
+		"rclone_sync_bytes_transferred 100",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("sync output missing %q\n%s", want, body)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	e.SyncStatsHandler(&fakeSyncSource{err: errors.New("rc unreachable")})(rec, httptest.NewRequest(http.MethodGet, "/sync", nil))
+	if body := rec.Body.String(); !strings.Contains(body, "rclone_sync_stats_up 0") || strings.Contains(body, "rclone_sync_transfers_total") {
+		t.Errorf("unexpected sync output on failure\n%s", body)
+	}
+}
@@ -0,0 +1,93 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// StartTextfileOutput writes the /metrics exposition to path every interval until the
+// returned stop function is called, for node_exporter's textfile collector in setups
+// where Prometheus cannot reach the exporter. Stop waits for a running write and
+// removes the file, so a stopped exporter leaves no stale metrics behind.
+func (e *Exporter) StartTextfileOutput(ctx context.Context, path string, interval time.Duration) (stop func(), err error) {
+	if path == "" {
+		return nil, fmt.Errorf("output file path must not be empty")
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive, got %s", interval)
+	}
+	if info, err := os.Stat(filepath.Dir(path)); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("output directory %s does not exist", filepath.Dir(path))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := e.writeTextfile(path); err != nil {
+				log.Error().Err(err).Str("path", path).Msg("Failed to write metrics file")
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	log.Info().
+		Str("path", path).
+		Dur("interval", interval).
+		Msg("Writing metrics to file")
+
+	return func() {
+		cancel()
+		<-done
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Warn().Err(err).Str("path", path).Msg("Failed to remove metrics file")
+		}
+	}, nil
+}
+
+// writeTextfile renders the exposition into a temporary file next to path and renames
+// it into place, so readers never see a partially written file
+func (e *Exporter) writeTextfile(path string) error {
+	exposition, err := encodeExposition(e.registry)
+	if err != nil {
+		return err
+	}
+
+	// The textfile collector only reads *.prom files, so the temporary name is skipped
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary metrics file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op after a successful rename
+
+	if _, err := tmp.Write(exposition); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary metrics file: %w", err)
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set metrics file permissions: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary metrics file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to move metrics file into place: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package exporter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTextfileOutput(t *testing.T) {
+	e := NewExporter(&fakeClient{})
+	defer e.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rclone.prom")
+	stop, err := e.StartTextfileOutput(context.Background(), path, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("StartTextfileOutput() error = %v", err)
+	}
+
+	waitFor := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			data, _ := os.ReadFile(path)
+			if strings.Contains(string(data), want) {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("metrics file never contained %q\n%s", want, data)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	// The file is written right away and refreshed every interval
+	waitFor("rclone_exporter_probe_requests_total 0")
+	e.probeRequestsTotal.Inc()
+	waitFor("rclone_exporter_probe_requests_total 1")
+
+	stop()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("metrics file still exists after stop, err = %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("output directory holds leftover files: %v", entries)
+	}
+}
+
+func TestStartTextfileOutputValidation(t *testing.T) {
+	e := NewExporter(&fakeClient{})
+	defer e.Close()
+
+	tests := []struct {
+		name     string
+		path     string
+		interval time.Duration
+	}{
+		{"empty path", "", time.Minute},
+		{"zero interval", filepath.Join(t.TempDir(), "rclone.prom"), 0},
+		{"missing directory", filepath.Join(t.TempDir(), "missing", "rclone.prom"), time.Minute},
+	}
+	for _, tt := range tests {
+		if _, err := e.StartTextfileOutput(context.Background(), tt.path, tt.interval); err == nil {
+			t.Errorf("%s: StartTextfileOutput() error = nil, want error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// loggerKey is the request context key holding a path-scoped logger
+type loggerKey struct{}
+
+// ParsePathLevels parses path=level pairs such as "/probe=debug" into a map of
+// minimum log levels keyed by request path
+func ParsePathLevels(pairs []string) (map[string]zerolog.Level, error) {
+	levels := make(map[string]zerolog.Level, len(pairs))
+	for _, pair := range pairs {
+		path, value, ok := strings.Cut(pair, "=")
+		path = strings.TrimSpace(path)
+		if !ok || !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("invalid path level %q, expected /path=level", pair)
+		}
+
+		level, err := zerolog.ParseLevel(strings.TrimSpace(value))
+		if err != nil || level == zerolog.NoLevel {
+			return nil, fmt.Errorf("invalid log level in %q", pair)
+		}
+
+		if _, exists := levels[path]; exists {
+			return nil, fmt.Errorf("duplicate path level for %s", path)
+		}
+		levels[path] = level
+	}
+
+	return levels, nil
+}
+
+// PathLevelHandler attaches a logger with the configured minimum level to the
+// context of requests whose path has one. Handlers read it with FromContext.
+func PathLevelHandler(levels map[string]zerolog.Level, next http.Handler) http.Handler {
+	if len(levels) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if level, ok := levels[r.URL.Path]; ok {
+			logger := log.Logger.Level(level)
+			r = r.WithContext(context.WithValue(r.Context(), loggerKey{}, &logger))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// FromContext returns the path-scoped logger of a request, or the global
+// logger when none is attached
+func FromContext(ctx context.Context) *zerolog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*zerolog.Logger); ok {
+		return logger
+	}
+	return &log.Logger
+}
@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func TestParsePathLevels(t *testing.T) {
+	levels, err := ParsePathLevels([]string{"/probe=debug", " /health = warn "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if levels["/probe"] != zerolog.DebugLevel || levels["/health"] != zerolog.WarnLevel {
+		t.Errorf("unexpected levels: %v", levels)
+	}
+
+	for _, bad := range [][]string{{"probe=debug"}, {"/probe"}, {"/probe=loud"}, {"/probe="}, {"/probe=debug", "/probe=info"}} {
+		if _, err := ParsePathLevels(bad); err == nil {
+			t.Errorf("ParsePathLevels(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestPathLevelHandler(t *testing.T) {
+	var buf bytes.Buffer
+	oldLogger, oldLevel := log.Logger, zerolog.GlobalLevel()
+	defer func() {
+		log.Logger = oldLogger
+		zerolog.SetGlobalLevel(oldLevel)
+	}()
+
+	// Mirror InitLogging: the global level admits debug, the default logger stays at info
+	zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	log.Logger = zerolog.New(&buf).Level(zerolog.InfoLevel)
+
+	handler := PathLevelHandler(map[string]zerolog.Level{"/probe": zerolog.DebugLevel}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).Debug().Str("path", r.URL.Path).Msg("handled")
+	}))
+
+	for _, path := range []string{"/probe", "/metrics"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if !strings.Contains(buf.String(), `"path":"/probe"`) {
+		t.Errorf("debug log for /probe missing: %s", buf.String())
+	}
+	if strings.Contains(buf.String(), `"path":"/metrics"`) {
+		t.Errorf("debug log for /metrics written at info level: %s", buf.String())
+	}
+}
@@ -1,6 +1,7 @@
 package logging
 
 import (
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -57,17 +58,31 @@ func InitLogging(cmd *cli.Command) error {
 
 	// Configure log level first
 	level := getLogLevel(cmd)
-	zerolog.SetGlobalLevel(level)
+
+	pathLevels, err := ParsePathLevels(cmd.StringSlice("log.path-levels"))
+	if err != nil {
+		return fmt.Errorf("invalid --log.path-levels: %w", err)
+	}
+
+	// The global level must let through the most verbose path level; the
+	// default logger keeps filtering at the configured level
+	minLevel := level
+	for _, pathLevel := range pathLevels {
+		if pathLevel < minLevel {
+			minLevel = pathLevel
+		}
+	}
+	zerolog.SetGlobalLevel(minLevel)
 
 	// Create logger with conditional caller information
 	logContext := zerolog.New(output).With().Timestamp()
 
 	// Only add caller information in debug or trace mode
-	if level <= zerolog.DebugLevel {
+	if minLevel <= zerolog.DebugLevel {
 		logContext = logContext.Caller()
 	}
 
-	log.Logger = logContext.Logger()
+	log.Logger = logContext.Logger().Level(level)
 
 	// Configure zerolog global settings
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
@@ -85,7 +100,7 @@ func InitLogging(cmd *cli.Command) error {
 	log.Info().
 		Str("level", level.String()).
 		Bool("pretty", cmd.Bool("log.pretty")).
-		Bool("caller_enabled", level <= zerolog.DebugLevel).
+		Bool("caller_enabled", minLevel <= zerolog.DebugLevel).
 		Msg("Logging initialized")
 
 	switch level {
@@ -0,0 +1,53 @@
+package rclone
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RcloneAboutOutput represents the JSON output of `rclone about --json`.
+// Fields are nil when the backend does not report them.
+type RcloneAboutOutput struct {
+	Total   *int64 `json:"total,omitempty"`   // Quota in bytes
+	Used    *int64 `json:"used,omitempty"`    // Bytes in use
+	Trashed *int64 `json:"trashed,omitempty"` // Bytes in trash
+	Other   *int64 `json:"other,omitempty"`   // Bytes used by other services
+	Free    *int64 `json:"free,omitempty"`    // Bytes available
+	Objects *int64 `json:"objects,omitempty"` // Number of objects
+}
+
+// FreePercent returns free space as a percentage of the total quota.
+// ok is false when the backend does not report both values.
+func (a *RcloneAboutOutput) FreePercent() (percent float64, ok bool) {
+	if a.Total == nil || a.Free == nil || *a.Total <= 0 {
+		return 0, false
+	}
+	return float64(*a.Free) / float64(*a.Total) * 100, true
+}
+
+// aboutArgs builds the arguments for `rclone about` against the given remote.
+func (c *rcloneClient) aboutArgs(remote string) []string {
+	args := []string{"about", remote, "--json"}
+	return append(args, c.options.args()...)
+}
+
+// GetRemoteAbout runs `rclone about --json` and parses the quota information.
+func (c *rcloneClient) GetRemoteAbout(remote string) (*RcloneAboutOutput, error) {
+	if remote == "" {
+		return nil, fmt.Errorf("remote name cannot be empty")
+	}
+
+	var result RcloneAboutOutput
+	run, err := c.runJSON(remote, c.aboutArgs(remote), c.timeout, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug().
+		Str("remote", remote).
+		Dur("duration", run.duration).
+		Msg("Rclone about successful")
+
+	return &result, nil
+}
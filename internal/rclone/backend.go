@@ -0,0 +1,100 @@
+package rclone
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// wrappingBackends are the backends that store their data on another remote, named
+// by their remote option, e.g. crypt over s3
+var wrappingBackends = map[string]bool{
+	"alias":    true,
+	"chunker":  true,
+	"compress": true,
+	"crypt":    true,
+	"hasher":   true,
+}
+
+// IsWrappingBackend reports whether remotes of the type store their data on another remote
+func IsWrappingBackend(remoteType string) bool {
+	return wrappingBackends[remoteType]
+}
+
+// resolveBackendType follows the remote option of wrapping backends until it reaches
+// the backend that stores the data. Paths without a remote name are on local disk.
+func resolveBackendType(configs map[string]map[string]interface{}, remoteName string) (string, error) {
+	visited := make(map[string]bool)
+	for name := remoteName; ; {
+		if visited[name] {
+			return "", fmt.Errorf("remote '%s' wraps itself through '%s'", remoteName, name)
+		}
+		visited[name] = true
+
+		remoteConfig, exists := configs[name]
+		if !exists {
+			return "", fmt.Errorf("remote '%s' not found in config", name)
+		}
+		remoteType, _ := remoteConfig["type"].(string)
+		if remoteType == "" {
+			return "", fmt.Errorf("remote '%s' has no type field", name)
+		}
+		if !wrappingBackends[remoteType] {
+			return remoteType, nil
+		}
+
+		wrapped, _ := remoteConfig["remote"].(string)
+		if wrapped == "" {
+			return "", fmt.Errorf("remote '%s' of type %s has no remote option", name, remoteType)
+		}
+
+		// Connection strings such as :s3,provider=AWS:bucket name their backend directly
+		if rest, ok := strings.CutPrefix(wrapped, ":"); ok {
+			backend, _, _ := strings.Cut(rest, ":")
+			backend, _, _ = strings.Cut(backend, ",")
+			return backend, nil
+		}
+
+		next, _, hasColon := strings.Cut(wrapped, ":")
+		if !hasColon || strings.ContainsAny(next, `/\`) || len(next) == 1 {
+			// A plain path or a Windows drive letter
+			return "local", nil
+		}
+		name = next
+	}
+}
+
+// GetBackendType returns the backend that stores a remote's data, following crypt
+// and other wrapping remotes through `rclone config dump`. Remotes that do not wrap
+// another remote return their own type.
+func (c *rcloneClient) GetBackendType(remoteName string) (string, error) {
+	if backendType, ok := c.CachedBackendType(remoteName); ok {
+		return backendType, nil
+	}
+
+	remoteName = strings.TrimSuffix(remoteName, ":")
+	configs, err := c.configDump(remoteName)
+	if err != nil {
+		return "unknown", err
+	}
+
+	backendType, err := resolveBackendType(configs, remoteName)
+	if err != nil {
+		return "unknown", err
+	}
+	return backendType, nil
+}
+
+// CachedBackendType returns the cached backend type of a remote without running rclone
+func (c *rcloneClient) CachedBackendType(remoteName string) (string, bool) {
+	remoteName = strings.TrimSuffix(remoteName, ":")
+
+	c.cacheMu.RLock()
+	defer c.cacheMu.RUnlock()
+
+	backendType, exists := c.backendTypeCache[remoteName]
+	if !exists || time.Since(c.cacheTimestamps[remoteName]) >= c.cacheExpiry {
+		return "", false
+	}
+	return backendType, true
+}
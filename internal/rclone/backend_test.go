@@ -0,0 +1,73 @@
+package rclone
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveBackendType(t *testing.T) {
+	configs := map[string]map[string]interface{}{
+		"s3":        {"type": "s3"},
+		"secret":    {"type": "crypt", "remote": "s3:bucket/private"},
+		"nested":    {"type": "crypt", "remote": "short:"},
+		"short":     {"type": "alias", "remote": "secret:sub"},
+		"disk":      {"type": "crypt", "remote": "/mnt/backup"},
+		"windows":   {"type": "crypt", "remote": `C:\backup`},
+		"inline":    {"type": "crypt", "remote": ":sftp,host=example.com:backup"},
+		"loop-a":    {"type": "crypt", "remote": "loop-b:"},
+		"loop-b":    {"type": "alias", "remote": "loop-a:"},
+		"dangling":  {"type": "crypt", "remote": "missing:"},
+		"no-remote": {"type": "crypt"},
+	}
+
+	tests := []struct {
+		remote  string
+		want    string
+		wantErr bool
+	}{
+		{"s3", "s3", false},
+		{"secret", "s3", false},
+		{"nested", "s3", false},
+		{"disk", "local", false},
+		{"windows", "local", false},
+		{"inline", "sftp", false},
+		{"loop-a", "", true},
+		{"dangling", "", true},
+		{"no-remote", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := resolveBackendType(configs, tt.remote)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("resolveBackendType(%s) = %q, %v, want %q (error %v)", tt.remote, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestGetBackendTypeUsesCache(t *testing.T) {
+	calls := filepath.Join(t.TempDir(), "calls")
+	path := fakeBinary(t, `echo x >> "`+calls+`"; echo '{"s3":{"type":"s3"},"secret":{"type":"crypt","remote":"s3:bucket"}}'`)
+	c := NewRcloneClientWithConfig(path, 5*time.Second)
+
+	if _, ok := c.CachedBackendType("secret:"); ok {
+		t.Fatal("CachedBackendType() hit before any config dump")
+	}
+	for i := 0; i < 2; i++ {
+		if backendType, err := c.GetBackendType("secret:"); err != nil || backendType != "s3" {
+			t.Fatalf("GetBackendType() = %q, %v, want s3", backendType, err)
+		}
+	}
+	if backendType, ok := c.CachedBackendType("secret:"); !ok || backendType != "s3" {
+		t.Errorf("CachedBackendType() = %q, %v, want s3 from the cache", backendType, ok)
+	}
+	data, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "x"); n != 1 {
+		t.Errorf("rclone config dump ran %d times, want 1", n)
+	}
+}
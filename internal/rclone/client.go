@@ -1,21 +1,36 @@
 package rclone
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os/exec"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog/log"
 )
 
+// metadataTimeout bounds quick local commands such as `rclone version` and `rclone config dump`
+const metadataTimeout = 10 * time.Second
+
 // RcloneSizeOutput represents the JSON output of `rclone size --json`.
 type RcloneSizeOutput struct {
 	Count int64 `json:"count"` // Total number of objects
 	Bytes int64 `json:"bytes"` // Total size in bytes
+
+	// Warnings is the number of lines rclone logged to stderr while still succeeding
+	Warnings int `json:"-"`
+
+	// StderrBytes is the length of everything rclone logged to stderr while still succeeding
+	StderrBytes int64 `json:"-"`
 }
 
 // RemoteInfo contains metadata about an rclone remote
@@ -32,43 +47,130 @@ type RemoteSizeWithType struct {
 	RemoteType string
 }
 
+// Options holds optional rclone flags passed through to remote commands.
+// Zero values are omitted so rclone falls back to its own defaults.
+type Options struct {
+	DisableHTTP2    bool          // Pass --disable-http2
+	ConnectTimeout  time.Duration // Pass --contimeout
+	LowLevelRetries int           // Pass --low-level-retries
+	UserAgent       string        // Pass --user-agent
+
+	// Checkers and Transfers tune rclone's concurrency for `rclone size` only,
+	// passed as --checkers and --transfers
+	Checkers  int
+	Transfers int
+
+	// Traversal is the default traversal strategy of recursive listings, overridable
+	// per probe; empty means TraversalFastList
+	Traversal string
+
+	// SizeExtraArgs are passed through to `rclone size` only, after the exporter's own
+	// flags. Check them with ValidateSizeExtraArgs.
+	SizeExtraArgs []string
+
+	// Nice and IOPriority lower the CPU and I/O priority of every rclone process.
+	// They are applied right after the process starts; zero values leave them unchanged.
+	Nice       int
+	IOPriority IOPriority
+
+	// RemoteEnv holds extra environment variables per remote name, added only to
+	// rclone runs against that remote, e.g. RCLONE_SFTP_* proxy settings
+	RemoteEnv map[string]map[string]string
+
+	// PasswordCommand is exported to every rclone run as RCLONE_PASSWORD_COMMAND so
+	// rclone can decrypt an encrypted config. rclone runs it; its output never
+	// passes through the exporter.
+	PasswordCommand string
+
+	// SuccessExitCodes are rclone size exit codes reported as an empty remote instead
+	// of a failure, e.g. 3 (directory not found) for a path that does not exist yet
+	SuccessExitCodes []int
+
+	// ObserveTypeDetection, if set, receives the duration of every GetRemoteType call,
+	// cache hits included
+	ObserveTypeDetection func(time.Duration)
+}
+
+// ProbeOptions holds per-probe settings for `rclone size` and listing commands.
+type ProbeOptions struct {
+	MaxDepth int    // Pass --max-depth to limit traversal (0 means unlimited)
+	MinSize  string // Pass --min-size to only count larger objects (empty means all)
+
+	// Traversal selects --fast-list or --no-traverse, empty uses Options.Traversal
+	Traversal string
+
+	// Timeout replaces the client timeout when it is shorter, e.g. to finish within a
+	// Prometheus scrape timeout. Zero uses the client timeout.
+	Timeout time.Duration
+}
+
 // Client defines the interface for interacting with the rclone binary.
 type Client interface {
 	GetRemoteSize(remoteName string) (*RcloneSizeOutput, error)
+	GetRemoteSizeWithOptions(remoteName string, opts ProbeOptions) (*RcloneSizeOutput, error)
+	GetRemoteDirCount(remoteName string, opts ProbeOptions) (int64, error)
+	GetRemoteObjectCount(remoteName string, opts ProbeOptions) (int64, error)
+	GetRemoteStorageClasses(remoteName string, opts ProbeOptions) (map[string]int64, error)
+	GetRemoteAbout(remoteName string) (*RcloneAboutOutput, error)
+	GetRemoteDuplicates(remoteName string) (int64, error)
+	GetUpstreams(remoteName string) ([]Upstream, error)
+	GetRemoteOption(remoteName, key string) (string, error)
+	GetRedactedConfig() (map[string]map[string]interface{}, error)
 	GetRemoteSizeWithType(remoteName string) (*RemoteSizeWithType, error)
-	CheckBinaryAvailable() error
+	CheckBinaryAvailable(ctx context.Context) error
+	CheckReachable(remoteName string) error
 	GetVersion() (string, error)
 	ListRemotes() ([]RemoteInfo, error)
 	GetRemoteType(remoteName string) (string, error)
-	InvalidateCache(remoteName string)
-	ClearCache()
+	CachedRemoteType(remoteName string) (string, bool)
+	GetBackendType(remoteName string) (string, error)
+	CachedBackendType(remoteName string) (string, bool)
+	CacheExpiry() time.Duration
+	ConfigFile() (string, error)
+	InvalidateCache(remoteName string) bool
+	ClearCache() int
 }
 
 // rcloneClient implements the Client interface.
 type rcloneClient struct {
 	binaryPath string
 	timeout    time.Duration
+	options    Options
 
 	// Cache for remote types to avoid repeated config lookups
 	remoteTypeCache map[string]string
 	cacheMu         sync.RWMutex
-	cacheExpiry     time.Duration
-	cacheTimestamps map[string]time.Time
+
+	// Backend types of wrapping remotes, resolved from the same config dump and
+	// sharing the remote type timestamps
+	backendTypeCache map[string]string
+	cacheExpiry      time.Duration
+	cacheTimestamps  map[string]time.Time
+
+	// Cached `rclone version` output, refreshed after cacheExpiry
+	versionCache     string
+	versionFetchedAt time.Time
 }
 
 // NewRcloneClient returns a default rclone client with standard settings.
 func NewRcloneClient() Client {
 	return &rcloneClient{
-		binaryPath:      "rclone",
-		timeout:         2 * time.Minute,
-		remoteTypeCache: make(map[string]string),
-		cacheTimestamps: make(map[string]time.Time),
-		cacheExpiry:     5 * time.Minute, // Cache remote types for 5 minutes
+		binaryPath:       "rclone",
+		timeout:          2 * time.Minute,
+		remoteTypeCache:  make(map[string]string),
+		backendTypeCache: make(map[string]string),
+		cacheTimestamps:  make(map[string]time.Time),
+		cacheExpiry:      5 * time.Minute, // Cache remote types for 5 minutes
 	}
 }
 
 // NewRcloneClientWithConfig returns a customizable rclone client.
 func NewRcloneClientWithConfig(path string, timeout time.Duration) Client {
+	return NewRcloneClientWithOptions(path, timeout, Options{})
+}
+
+// NewRcloneClientWithOptions returns a customizable rclone client with extra rclone flags.
+func NewRcloneClientWithOptions(path string, timeout time.Duration, options Options) Client {
 	if path == "" {
 		path = "rclone"
 	}
@@ -78,16 +180,28 @@ func NewRcloneClientWithConfig(path string, timeout time.Duration) Client {
 	}
 
 	return &rcloneClient{
-		binaryPath:      path,
-		timeout:         timeout,
-		remoteTypeCache: make(map[string]string),
-		cacheTimestamps: make(map[string]time.Time),
-		cacheExpiry:     5 * time.Minute,
+		binaryPath:       path,
+		timeout:          timeout,
+		options:          options,
+		remoteTypeCache:  make(map[string]string),
+		backendTypeCache: make(map[string]string),
+		cacheTimestamps:  make(map[string]time.Time),
+		cacheExpiry:      5 * time.Minute,
 	}
 }
 
 // GetRemoteType retrieves the type of a remote from rclone config
-func (c *rcloneClient) GetRemoteType(remoteName string) (string, error) {
+func (c *rcloneClient) GetRemoteType(remoteName string) (_ string, err error) {
+	if observe := c.options.ObserveTypeDetection; observe != nil {
+		start := time.Now()
+		defer func() { observe(time.Since(start)) }()
+	}
+	defer func() {
+		if err != nil {
+			typeDetectionFailures.Add(1)
+		}
+	}()
+
 	// Remove trailing colon if present
 	remoteName = strings.TrimSuffix(remoteName, ":")
 
@@ -105,51 +219,11 @@ func (c *rcloneClient) GetRemoteType(remoteName string) (string, error) {
 	}
 	c.cacheMu.RUnlock()
 
-	// Fetch from rclone config
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	// Use `rclone config dump` to get all remote configurations in JSON format
-	cmd := exec.CommandContext(ctx, c.binaryPath, "config", "dump")
-	output, err := cmd.CombinedOutput()
+	configs, err := c.configDump(remoteName)
 	if err != nil {
-		log.Error().
-			Err(err).
-			Str("remote", remoteName).
-			Str("output", string(output)).
-			Msg("Failed to dump rclone config")
-		return "unknown", fmt.Errorf("failed to get rclone config: %w", err)
+		return "unknown", err
 	}
 
-	// Handle empty config
-	if len(output) == 0 || string(output) == "{}\n" || string(output) == "{}" {
-		log.Warn().
-			Str("remote", remoteName).
-			Msg("Rclone config is empty")
-		return "unknown", fmt.Errorf("rclone config is empty")
-	}
-
-	// Parse the JSON output
-	var configs map[string]map[string]interface{}
-	if err := json.Unmarshal(output, &configs); err != nil {
-		log.Error().
-			Err(err).
-			Str("raw_output", string(output)).
-			Msg("Failed to parse rclone config dump")
-		return "unknown", fmt.Errorf("invalid rclone config JSON: %w", err)
-	}
-
-	// Warm cache for all remotes
-	c.cacheMu.Lock()
-	now := time.Now()
-	for name, cfg := range configs {
-		if t, ok := cfg["type"].(string); ok && t != "" {
-			c.remoteTypeCache[name] = t
-			c.cacheTimestamps[name] = now
-		}
-	}
-	c.cacheMu.Unlock()
-
 	// Look up the requested remote
 	remoteConfig, exists := configs[remoteName]
 	if !exists {
@@ -188,6 +262,74 @@ func (c *rcloneClient) GetRemoteType(remoteName string) (string, error) {
 	return remoteType, nil
 }
 
+// configDump runs `rclone config dump` and warms the type cache for every remote.
+// remoteName is only used for logging.
+func (c *rcloneClient) configDump(remoteName string) (map[string]map[string]interface{}, error) {
+	// Use `rclone config dump` to get all remote configurations in JSON format
+	result, err := c.run(remoteName, []string{"config", "dump"}, metadataTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rclone config: %w", err)
+	}
+	output := result.stdout
+
+	// Handle empty config
+	if len(output) == 0 || string(output) == "{}\n" || string(output) == "{}" {
+		log.Warn().
+			Str("remote", remoteName).
+			Msg("Rclone config is empty")
+		return nil, fmt.Errorf("rclone config is empty")
+	}
+
+	// Parse the JSON output
+	var configs map[string]map[string]interface{}
+	if err := json.Unmarshal(output, &configs); err != nil {
+		configParseErrors.Add(1)
+		log.Error().
+			Err(err).
+			Str("raw_output", string(output)).
+			Msg("Failed to parse rclone config dump")
+		return nil, fmt.Errorf("invalid rclone config JSON: %w", err)
+	}
+
+	// Warm cache for all remotes
+	c.cacheMu.Lock()
+	now := time.Now()
+	for name, cfg := range configs {
+		if t, ok := cfg["type"].(string); ok && t != "" {
+			c.remoteTypeCache[name] = t
+			c.cacheTimestamps[name] = now
+
+			if backendType, err := resolveBackendType(configs, name); err == nil {
+				c.backendTypeCache[name] = backendType
+			} else {
+				delete(c.backendTypeCache, name)
+			}
+		}
+	}
+	c.cacheMu.Unlock()
+
+	return configs, nil
+}
+
+// CacheExpiry returns how long a detected remote type is cached
+func (c *rcloneClient) CacheExpiry() time.Duration {
+	return c.cacheExpiry
+}
+
+// CachedRemoteType returns the cached type of a remote without running rclone
+func (c *rcloneClient) CachedRemoteType(remoteName string) (string, bool) {
+	remoteName = strings.TrimSuffix(remoteName, ":")
+
+	c.cacheMu.RLock()
+	defer c.cacheMu.RUnlock()
+
+	remoteType, exists := c.remoteTypeCache[remoteName]
+	if !exists || time.Since(c.cacheTimestamps[remoteName]) >= c.cacheExpiry {
+		return "", false
+	}
+	return remoteType, true
+}
+
 // GetRemoteSizeWithType combines size information with remote type
 func (c *rcloneClient) GetRemoteSizeWithType(remoteName string) (*RemoteSizeWithType, error) {
 	// Get size
@@ -212,49 +354,50 @@ func (c *rcloneClient) GetRemoteSizeWithType(remoteName string) (*RemoteSizeWith
 	}, nil
 }
 
-// InvalidateCache removes a specific remote from the type cache
-func (c *rcloneClient) InvalidateCache(remoteName string) {
+// InvalidateCache removes a specific remote from the type cache and reports whether it was cached
+func (c *rcloneClient) InvalidateCache(remoteName string) bool {
 	remoteName = strings.TrimSuffix(remoteName, ":")
 	c.cacheMu.Lock()
 	defer c.cacheMu.Unlock()
 
+	_, existed := c.remoteTypeCache[remoteName]
 	delete(c.remoteTypeCache, remoteName)
+	delete(c.backendTypeCache, remoteName)
 	delete(c.cacheTimestamps, remoteName)
 
 	log.Debug().
 		Str("remote", remoteName).
+		Bool("existed", existed).
 		Msg("Invalidated cache for remote")
+
+	return existed
 }
 
-// ClearCache clears the entire remote type cache
-func (c *rcloneClient) ClearCache() {
+// ClearCache clears the entire remote type cache and returns the number of entries removed
+func (c *rcloneClient) ClearCache() int {
 	c.cacheMu.Lock()
 	defer c.cacheMu.Unlock()
 
+	cleared := len(c.remoteTypeCache)
 	c.remoteTypeCache = make(map[string]string)
+	c.backendTypeCache = make(map[string]string)
 	c.cacheTimestamps = make(map[string]time.Time)
 
-	log.Debug().Msg("Cleared entire remote type cache")
+	log.Debug().
+		Int("cleared", cleared).
+		Msg("Cleared entire remote type cache")
+
+	return cleared
 }
 
 // ListRemotes runs `rclone listremotes --json` and returns the list of remotes with details.
 func (c *rcloneClient) ListRemotes() ([]RemoteInfo, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
 	// Get list of rclone remotes
-	cmd := exec.CommandContext(ctx, c.binaryPath, "listremotes", "--json")
-	output, err := cmd.CombinedOutput()
-
-	// Check for errors
+	result, err := c.run("", []string{"listremotes", "--json"}, metadataTimeout)
 	if err != nil {
-		log.Error().
-			Err(err).
-			Str("output", string(output)).
-			Str("path", c.binaryPath).
-			Msg("Failed to list rclone remotes")
 		return nil, fmt.Errorf("failed to list rclone remotes: %w", err)
 	}
+	output := result.stdout
 
 	// Handle empty output
 	if len(output) == 0 || string(output) == "[]\n" || string(output) == "[]" {
@@ -267,6 +410,7 @@ func (c *rcloneClient) ListRemotes() ([]RemoteInfo, error) {
 		// Try parsing as simple string array (older rclone versions)
 		var remoteNames []string
 		if err := json.Unmarshal(output, &remoteNames); err != nil {
+			configParseErrors.Add(1)
 			log.Error().
 				Err(err).
 				Str("raw_output", string(output)).
@@ -301,11 +445,9 @@ func (c *rcloneClient) ListRemotes() ([]RemoteInfo, error) {
 	return remotes, nil
 }
 
-// CheckBinaryAvailable verifies that rclone is executable and accessible.
-func (c *rcloneClient) CheckBinaryAvailable() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
+// CheckBinaryAvailable verifies that rclone is executable and accessible. It returns
+// early with ctx's error when ctx is done, e.g. on a shutdown signal during startup.
+func (c *rcloneClient) CheckBinaryAvailable(ctx context.Context) error {
 	// Resolve the full path to the rclone binary
 	resolvedPath, lookErr := exec.LookPath(c.binaryPath)
 	if lookErr != nil {
@@ -319,18 +461,15 @@ func (c *rcloneClient) CheckBinaryAvailable() error {
 	// Update internal binary path to the resolved absolute path
 	c.binaryPath = resolvedPath
 
-	cmd := exec.CommandContext(ctx, c.binaryPath, "version")
-	output, err := cmd.CombinedOutput()
+	result, err := c.runContext(ctx, "", []string{"version"}, metadataTimeout)
 	if err != nil {
-		log.Error().
-			Err(err).
-			Str("output", string(output)).
-			Str("path", c.binaryPath).
-			Msg("Rclone binary check failed")
+		if ctx.Err() != nil {
+			return err
+		}
 		return fmt.Errorf("rclone not available or not executable at '%s': %w", c.binaryPath, err)
 	}
 
-	version := extractFirstLine(string(output))
+	version := extractFirstLine(string(result.stdout))
 	log.Info().
 		Str("version", version).
 		Str("path", c.binaryPath).
@@ -339,23 +478,29 @@ func (c *rcloneClient) CheckBinaryAvailable() error {
 	return nil
 }
 
-// GetVersion returns the first line from `rclone version` output.
+// GetVersion returns the first line from `rclone version` output, cached for the cache expiry.
 func (c *rcloneClient) GetVersion() (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	c.cacheMu.RLock()
+	if c.versionCache != "" && time.Since(c.versionFetchedAt) < c.cacheExpiry {
+		cached := c.versionCache
+		c.cacheMu.RUnlock()
+		return cached, nil
+	}
+	c.cacheMu.RUnlock()
 
-	cmd := exec.CommandContext(ctx, c.binaryPath, "version")
-	output, err := cmd.CombinedOutput()
+	result, err := c.run("", []string{"version"}, metadataTimeout)
 	if err != nil {
-		log.Error().
-			Err(err).
-			Str("path", c.binaryPath).
-			Str("output", string(output)).
-			Msg("Failed to get rclone version")
 		return "", fmt.Errorf("failed to get rclone version from '%s': %w", c.binaryPath, err)
 	}
 
-	return extractFirstLine(string(output)), nil
+	version := extractFirstLine(string(result.stdout))
+
+	c.cacheMu.Lock()
+	c.versionCache = version
+	c.versionFetchedAt = time.Now()
+	c.cacheMu.Unlock()
+
+	return version, nil
 }
 
 // extractFirstLine returns the first line of a string (used for version output).
@@ -368,75 +513,240 @@ func extractFirstLine(s string) string {
 	return s
 }
 
-// GetRemoteSize runs `rclone size --json` and parses the output.
-func (c *rcloneClient) GetRemoteSize(remote string) (*RcloneSizeOutput, error) {
+// reachableArgs builds the arguments for a cheap `rclone lsd` listing of the given remote.
+func (c *rcloneClient) reachableArgs(remote string) []string {
+	args := []string{"lsd", remote, "--max-depth", "1"}
+	return append(args, c.options.args()...)
+}
+
+// CheckReachable runs a top-level `rclone lsd` to verify the remote is reachable and authenticated.
+func (c *rcloneClient) CheckReachable(remote string) error {
 	if remote == "" {
-		return nil, fmt.Errorf("remote name cannot be empty")
+		return fmt.Errorf("remote name cannot be empty")
+	}
+
+	result, err := c.run(remote, c.reachableArgs(remote), c.timeout)
+	if err != nil {
+		return err
+	}
+
+	log.Debug().
+		Str("remote", remote).
+		Dur("duration", result.duration).
+		Msg("Remote is reachable")
+
+	return nil
+}
+
+// dirCountArgs builds the arguments for a recursive directory-only `rclone lsf` listing.
+// lsf prints one entry per line, so directories can be counted without buffering the listing.
+func (c *rcloneClient) dirCountArgs(remote string, opts ProbeOptions) []string {
+	args := []string{"lsf", remote, "--dirs-only", "-R", c.options.traversalFlag(opts)}
+	if opts.MaxDepth > 0 {
+		args = append(args, "--max-depth", strconv.Itoa(opts.MaxDepth))
 	}
+	return append(args, c.options.args()...)
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
-	defer cancel()
+// lineCounter is an io.Writer counting newline-terminated lines
+type lineCounter struct {
+	lines int64
+}
 
-	// Use --fast-list for better performance on recursive listings
-	cmd := exec.CommandContext(ctx, c.binaryPath, "size", remote, "--json", "--fast-list")
+// Write implements io.Writer
+func (l *lineCounter) Write(p []byte) (int, error) {
+	l.lines += int64(bytes.Count(p, []byte{'\n'}))
+	return len(p), nil
+}
+
+// GetRemoteDirCount runs `rclone lsf --dirs-only -R` and returns the number of directories.
+func (c *rcloneClient) GetRemoteDirCount(remote string, opts ProbeOptions) (int64, error) {
+	if remote == "" {
+		return 0, fmt.Errorf("remote name cannot be empty")
+	}
+
+	var counter lineCounter
+	result, err := c.runTo(remote, c.dirCountArgs(remote, opts), c.probeTimeout(opts), &counter)
+	if err != nil {
+		return 0, err
+	}
 
 	log.Debug().
 		Str("remote", remote).
-		Str("command", cmd.String()).
-		Dur("timeout", c.timeout).
-		Msg("Executing rclone size command")
+		Int64("dirs", counter.lines).
+		Dur("duration", result.duration).
+		Msg("Rclone directory count successful")
+
+	return counter.lines, nil
+}
+
+// objectCountArgs builds the arguments for a recursive file-only `rclone lsf` listing.
+// Unlike rclone size it never sums object sizes, which some backends can only compute slowly.
+func (c *rcloneClient) objectCountArgs(remote string, opts ProbeOptions) []string {
+	args := []string{"lsf", remote, "--files-only", "-R", c.options.traversalFlag(opts)}
+	if opts.MaxDepth > 0 {
+		args = append(args, "--max-depth", strconv.Itoa(opts.MaxDepth))
+	}
+	return append(args, c.options.args()...)
+}
 
-	startTime := time.Now()
-	output, err := cmd.CombinedOutput()
-	duration := time.Since(startTime)
+// GetRemoteObjectCount runs `rclone lsf --files-only -R` and returns the number of objects.
+func (c *rcloneClient) GetRemoteObjectCount(remote string, opts ProbeOptions) (int64, error) {
+	if remote == "" {
+		return 0, fmt.Errorf("remote name cannot be empty")
+	}
 
+	var counter lineCounter
+	result, err := c.runTo(remote, c.objectCountArgs(remote, opts), c.probeTimeout(opts), &counter)
 	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
-			log.Error().
-				Str("remote", remote).
-				Dur("timeout", c.timeout).
-				Dur("actual_duration", duration).
-				Msg("Rclone command timed out")
-			return nil, fmt.Errorf("rclone command timed out after %v for remote '%s'", c.timeout, remote)
-		}
+		return 0, err
+	}
 
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			log.Error().
-				Int("exit_code", exitErr.ExitCode()).
-				Str("remote", remote).
-				Str("stderr", string(output)).
-				Dur("duration", duration).
-				Msg("Rclone size command failed")
-			return nil, fmt.Errorf("rclone command failed for remote '%s' (exit code %d): %s",
-				remote, exitErr.ExitCode(), strings.TrimSpace(string(output)))
-		}
+	log.Debug().
+		Str("remote", remote).
+		Int64("objects", counter.lines).
+		Dur("duration", result.duration).
+		Msg("Rclone object count successful")
 
-		log.Error().
-			Err(err).
-			Str("remote", remote).
-			Dur("duration", duration).
-			Msg("Failed to start rclone command")
-		return nil, fmt.Errorf("failed to run rclone for remote '%s': %w", remote, err)
+	return counter.lines, nil
+}
+
+// probeTimeout returns the timeout of a probe command, the shorter of the client
+// timeout and the probe's own
+func (c *rcloneClient) probeTimeout(opts ProbeOptions) time.Duration {
+	if opts.Timeout > 0 && opts.Timeout < c.timeout {
+		return opts.Timeout
 	}
+	return c.timeout
+}
 
-	if len(output) == 0 {
-		log.Error().
-			Str("remote", remote).
-			Dur("duration", duration).
-			Msg("Rclone returned empty output")
-		return nil, fmt.Errorf("rclone returned empty output for remote '%s'", remote)
+// sizeArgs builds the arguments for `rclone size` against the given remote.
+func (c *rcloneClient) sizeArgs(remote string, opts ProbeOptions) []string {
+	// --fast-list by default for better performance on recursive listings
+	args := []string{"size", remote, "--json", c.options.traversalFlag(opts)}
+	if opts.MaxDepth > 0 {
+		args = append(args, "--max-depth", strconv.Itoa(opts.MaxDepth))
+	}
+	if opts.MinSize != "" {
+		args = append(args, "--min-size", opts.MinSize)
+	}
+	if c.options.Checkers > 0 {
+		args = append(args, "--checkers", strconv.Itoa(c.options.Checkers))
+	}
+	if c.options.Transfers > 0 {
+		args = append(args, "--transfers", strconv.Itoa(c.options.Transfers))
+	}
+	args = append(args, c.options.args()...)
+	return append(args, c.options.SizeExtraArgs...)
+}
+
+// args returns the rclone flags for the configured options.
+func (o Options) args() []string {
+	var args []string
+	if o.DisableHTTP2 {
+		args = append(args, "--disable-http2")
+	}
+	if o.ConnectTimeout > 0 {
+		args = append(args, "--contimeout", o.ConnectTimeout.String())
+	}
+	if o.LowLevelRetries > 0 {
+		args = append(args, "--low-level-retries", strconv.Itoa(o.LowLevelRetries))
+	}
+	if o.UserAgent != "" {
+		args = append(args, "--user-agent", o.UserAgent)
+	}
+	return args
+}
+
+// env returns the environment variables added to an rclone run against remote,
+// which may be a remote name, a remote:path or empty.
+func (o Options) env(remote string) []string {
+	var env []string
+	if o.PasswordCommand != "" {
+		env = append(env, "RCLONE_PASSWORD_COMMAND="+o.PasswordCommand)
+	}
+
+	name, _, _ := strings.Cut(remote, ":")
+	for _, key := range o.remoteEnvKeys(remote) {
+		env = append(env, key+"="+o.RemoteEnv[name][key])
+	}
+	return env
+}
+
+// remoteEnvKeys returns the names of the per-remote variables set for remote, for
+// logging without exposing their values
+func (o Options) remoteEnvKeys(remote string) []string {
+	name, _, _ := strings.Cut(remote, ":")
+	keys := make([]string, 0, len(o.RemoteEnv[name]))
+	for key := range o.RemoteEnv[name] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// configParseErrors counts rclone config dump and listremotes outputs that failed to parse
+var configParseErrors atomic.Int64
+
+// ConfigParseErrors returns the number of times rclone's config dump or listremotes
+// output could not be parsed. Failures to run rclone are not included.
+func ConfigParseErrors() int64 {
+	return configParseErrors.Load()
+}
+
+// typeDetectionFailures counts GetRemoteType calls that fell back to "unknown"
+var typeDetectionFailures atomic.Int64
+
+// TypeDetectionFailures returns the number of times a remote's type could not be
+// detected, e.g. because the remote is missing from the config or the config is encrypted
+func TypeDetectionFailures() int64 {
+	return typeDetectionFailures.Load()
+}
+
+// emptyOutputRetries counts the size probes retried after empty output
+var emptyOutputRetries atomic.Int64
+
+// EmptyOutputRetries returns the number of size probes retried because rclone
+// returned empty output
+func EmptyOutputRetries() int64 {
+	return emptyOutputRetries.Load()
+}
+
+// GetRemoteSize runs `rclone size --json` and parses the output.
+func (c *rcloneClient) GetRemoteSize(remote string) (*RcloneSizeOutput, error) {
+	return c.GetRemoteSizeWithOptions(remote, ProbeOptions{})
+}
+
+// GetRemoteSizeWithOptions runs `rclone size --json` with per-probe options and parses the output.
+func (c *rcloneClient) GetRemoteSizeWithOptions(remote string, opts ProbeOptions) (*RcloneSizeOutput, error) {
+	if remote == "" {
+		return nil, fmt.Errorf("remote name cannot be empty")
 	}
 
 	var result RcloneSizeOutput
-	if err := json.Unmarshal(output, &result); err != nil {
-		log.Error().
-			Err(err).
+	run, err := c.runJSON(remote, c.sizeArgs(remote, opts), c.probeTimeout(opts), &result)
+	if errors.Is(err, ErrEmptyOutput) {
+		// Some backends intermittently print nothing on the first call; retry exactly once
+		emptyOutputRetries.Add(1)
+		log.Warn().
 			Str("remote", remote).
-			Str("raw_output", string(output)).
-			Dur("duration", duration).
-			Msg("Failed to parse rclone JSON output")
-		return nil, fmt.Errorf("invalid rclone JSON output for remote '%s': %w", remote, err)
+			Msg("Rclone size returned empty output, retrying once")
+		run, err = c.runJSON(remote, c.sizeArgs(remote, opts), c.probeTimeout(opts), &result)
+	}
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) && slices.Contains(c.options.SuccessExitCodes, cmdErr.ExitCode) {
+		log.Info().
+			Str("remote", remote).
+			Int("exit_code", cmdErr.ExitCode).
+			Msg("Rclone size exit code configured as success, reporting zero size")
+		return &RcloneSizeOutput{}, nil
+	}
+	if err != nil {
+		return nil, err
 	}
+	duration, commandLine := run.duration, run.commandLine
+	result.Warnings = countLines(run.stderr)
+	result.StderrBytes = int64(len(run.stderr))
 
 	// Validate the result
 	if result.Bytes < 0 || result.Count < 0 {
@@ -446,7 +756,7 @@ func (c *rcloneClient) GetRemoteSize(remote string) (*RcloneSizeOutput, error) {
 			Int64("count", result.Count).
 			Dur("duration", duration).
 			Msg("Rclone returned negative values")
-		return nil, fmt.Errorf("rclone returned invalid negative values for remote '%s'", remote)
+		return nil, fmt.Errorf("rclone returned invalid negative values for remote '%s' (command: %s)", remote, commandLine)
 	}
 
 	log.Debug().
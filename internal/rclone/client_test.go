@@ -0,0 +1,293 @@
+package rclone
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// containsSequence reports whether args contains want as a contiguous run
+func containsSequence(args, want []string) bool {
+	for i := 0; i+len(want) <= len(args); i++ {
+		if slices.Equal(args[i:i+len(want)], want) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSizeArgsTransportOptions(t *testing.T) {
+	c := &rcloneClient{options: Options{
+		DisableHTTP2:    true,
+		ConnectTimeout:  5 * time.Second,
+		LowLevelRetries: 7,
+	}}
+	args := c.sizeArgs("remote:", ProbeOptions{})
+
+	for _, want := range [][]string{
+		{"--disable-http2"},
+		{"--contimeout", "5s"},
+		{"--low-level-retries", "7"},
+	} {
+		if !containsSequence(args, want) {
+			t.Errorf("sizeArgs() = %v, want it to contain %v", args, want)
+		}
+	}
+}
+
+func TestSizeArgsTransportOptionsOmittedWhenZero(t *testing.T) {
+	c := &rcloneClient{}
+	args := c.sizeArgs("remote:", ProbeOptions{})
+
+	want := []string{"size", "remote:", "--json", "--fast-list"}
+	if !slices.Equal(args, want) {
+		t.Errorf("sizeArgs() = %v, want %v", args, want)
+	}
+}
+
+func TestSizeArgsConcurrency(t *testing.T) {
+	c := &rcloneClient{options: Options{Checkers: 32, Transfers: 8}}
+
+	args := c.sizeArgs("remote:", ProbeOptions{})
+	for _, want := range [][]string{{"--checkers", "32"}, {"--transfers", "8"}} {
+		if !containsSequence(args, want) {
+			t.Errorf("sizeArgs() = %v, want it to contain %v", args, want)
+		}
+	}
+
+	// Listing and about commands keep rclone's defaults
+	for name, args := range map[string][]string{
+		"about": c.aboutArgs("remote:"),
+		"lsd":   c.reachableArgs("remote:"),
+		"lsf":   c.dirCountArgs("remote:", ProbeOptions{}),
+	} {
+		if slices.Contains(args, "--checkers") || slices.Contains(args, "--transfers") {
+			t.Errorf("%s args = %v, want no concurrency flags", name, args)
+		}
+	}
+}
+
+func TestSizeArgsMinSize(t *testing.T) {
+	c := &rcloneClient{}
+
+	args := c.sizeArgs("remote:", ProbeOptions{MinSize: "100M"})
+	if !containsSequence(args, []string{"--min-size", "100M"}) {
+		t.Errorf("sizeArgs() = %v, want it to contain --min-size 100M", args)
+	}
+
+	if args := c.sizeArgs("remote:", ProbeOptions{}); slices.Contains(args, "--min-size") {
+		t.Errorf("sizeArgs() = %v, want no --min-size without a threshold", args)
+	}
+}
+
+func TestObjectCountArgs(t *testing.T) {
+	c := &rcloneClient{}
+
+	args := c.objectCountArgs("remote:", ProbeOptions{MaxDepth: 2})
+	want := []string{"lsf", "remote:", "--files-only", "-R", "--fast-list", "--max-depth", "2"}
+	if !slices.Equal(args, want) {
+		t.Errorf("objectCountArgs() = %v, want %v", args, want)
+	}
+}
+
+func TestTraversalArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		fallback  string
+		traversal string
+		want      string
+	}{
+		{"default", "", "", "--fast-list"},
+		{"probe no-traverse", "", TraversalNoTraverse, "--no-traverse"},
+		{"client default", TraversalNoTraverse, "", "--no-traverse"},
+		{"probe overrides client default", TraversalNoTraverse, TraversalFastList, "--fast-list"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &rcloneClient{options: Options{Traversal: tt.fallback}}
+			opts := ProbeOptions{Traversal: tt.traversal}
+
+			for name, args := range map[string][]string{
+				"size":      c.sizeArgs("remote:", opts),
+				"lsf dirs":  c.dirCountArgs("remote:", opts),
+				"lsf files": c.objectCountArgs("remote:", opts),
+				"lsf tiers": c.storageClassArgs("remote:", opts),
+			} {
+				// The two strategies are mutually exclusive
+				var got []string
+				for _, arg := range args {
+					if slices.Contains(traversalFlags, arg) {
+						got = append(got, arg)
+					}
+				}
+				if !slices.Equal(got, []string{tt.want}) {
+					t.Errorf("%s args = %v, want exactly one traversal flag %s", name, args, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSizeArgsExtraArgs(t *testing.T) {
+	c := &rcloneClient{options: Options{
+		LowLevelRetries: 3,
+		SizeExtraArgs:   []string{"--max-backlog=100000", "--s3-list-version=2"},
+	}}
+
+	args := c.sizeArgs("remote:", ProbeOptions{Traversal: TraversalNoTraverse})
+	want := []string{"size", "remote:", "--json", "--no-traverse", "--low-level-retries", "3", "--max-backlog=100000", "--s3-list-version=2"}
+	if !slices.Equal(args, want) {
+		t.Errorf("sizeArgs() = %v, want %v", args, want)
+	}
+
+	// The passthrough only applies to rclone size
+	if args := c.objectCountArgs("remote:", ProbeOptions{}); slices.Contains(args, "--max-backlog=100000") {
+		t.Errorf("objectCountArgs() = %v, want no size passthrough flags", args)
+	}
+}
+
+func TestParseTraversal(t *testing.T) {
+	for _, value := range []string{"", TraversalFastList, " no-traverse "} {
+		if _, err := ParseTraversal(value); err != nil {
+			t.Errorf("ParseTraversal(%q) error = %v", value, err)
+		}
+	}
+	if _, err := ParseTraversal("walk"); err == nil {
+		t.Error("ParseTraversal(walk) error = nil, want error")
+	}
+}
+
+func TestValidateSizeExtraArgs(t *testing.T) {
+	if err := ValidateSizeExtraArgs([]string{"--max-backlog=1000", "--tpslimit=10", "--ignore-case"}); err != nil {
+		t.Errorf("ValidateSizeExtraArgs() error = %v", err)
+	}
+
+	for _, arg := range []string{"--fast-list", "--no-traverse", "--json", "--max-depth=2", "--checkers=4", "-v", "--", "1000"} {
+		if err := ValidateSizeExtraArgs([]string{arg}); err == nil {
+			t.Errorf("ValidateSizeExtraArgs(%q) error = nil, want error", arg)
+		}
+	}
+}
+
+func TestMaxDepthArg(t *testing.T) {
+	c := &rcloneClient{}
+	builders := map[string]func(string, ProbeOptions) []string{
+		"size": c.sizeArgs,
+		"lsf":  c.dirCountArgs,
+	}
+
+	for name, build := range builders {
+		t.Run(name, func(t *testing.T) {
+			if args := build("remote:", ProbeOptions{MaxDepth: 3}); !containsSequence(args, []string{"--max-depth", "3"}) {
+				t.Errorf("%s args = %v, want --max-depth 3", name, args)
+			}
+			if args := build("remote:", ProbeOptions{}); slices.Contains(args, "--max-depth") {
+				t.Errorf("%s args = %v, want no --max-depth at depth 0", name, args)
+			}
+		})
+	}
+}
+
+func TestUserAgentArg(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		want      bool
+	}{
+		{name: "set", userAgent: "rclone_exporter/1.0", want: true},
+		{name: "empty", userAgent: "", want: false},
+	}
+
+	for _, tt := range tests {
+		c := &rcloneClient{options: Options{UserAgent: tt.userAgent}}
+		builders := map[string][]string{
+			"size": c.sizeArgs("remote:", ProbeOptions{}),
+			"lsd":  c.reachableArgs("remote:"),
+			"lsf":  c.dirCountArgs("remote:", ProbeOptions{}),
+		}
+
+		for builder, args := range builders {
+			t.Run(tt.name+"/"+builder, func(t *testing.T) {
+				if tt.want && !containsSequence(args, []string{"--user-agent", tt.userAgent}) {
+					t.Errorf("%s args = %v, want --user-agent %s", builder, args, tt.userAgent)
+				}
+				if !tt.want && slices.Contains(args, "--user-agent") {
+					t.Errorf("%s args = %v, want no --user-agent", builder, args)
+				}
+			})
+		}
+	}
+}
+
+func TestLineCounter(t *testing.T) {
+	var counter lineCounter
+	for _, chunk := range []string{"a/\nb/", "\n", "c/d/\n"} {
+		if _, err := counter.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if counter.lines != 3 {
+		t.Errorf("lines = %d, want 3", counter.lines)
+	}
+}
+
+func TestParseUpstreams(t *testing.T) {
+	tests := []struct {
+		remoteType string
+		upstreams  string
+		want       []Upstream
+	}{
+		{"union", "gdrive:backup s3:bucket:ro local:/data:nc", []Upstream{
+			{Name: "gdrive:backup", Remote: "gdrive:backup"},
+			{Name: "s3:bucket", Remote: "s3:bucket"},
+			{Name: "local:/data", Remote: "local:/data"},
+		}},
+		{"combine", "photos=gdrive:Photos docs=s3:docs", []Upstream{
+			{Name: "photos", Remote: "gdrive:Photos"},
+			{Name: "docs", Remote: "s3:docs"},
+		}},
+		{"s3", "ignored:", nil},
+	}
+
+	for _, tt := range tests {
+		if got := parseUpstreams(tt.remoteType, tt.upstreams); !slices.Equal(got, tt.want) {
+			t.Errorf("parseUpstreams(%q, %q) = %v, want %v", tt.remoteType, tt.upstreams, got, tt.want)
+		}
+	}
+}
+
+func TestGetRemoteAboutTrashedFixtures(t *testing.T) {
+	tests := []struct {
+		fixture     string
+		wantTrashed *int64
+	}{
+		{"about_drive.json", int64Ptr(0)},
+		{"about_onedrive.json", int64Ptr(10737418240)},
+		{"about_s3.json", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			fixture, err := filepath.Abs(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := NewRcloneClientWithConfig(fakeBinary(t, `cat "`+fixture+`"`), 5*time.Second)
+
+			about, err := c.GetRemoteAbout("remote:")
+			if err != nil {
+				t.Fatalf("GetRemoteAbout() error = %v", err)
+			}
+
+			switch {
+			case tt.wantTrashed == nil && about.Trashed != nil:
+				t.Errorf("Trashed = %d, want nil", *about.Trashed)
+			case tt.wantTrashed != nil && (about.Trashed == nil || *about.Trashed != *tt.wantTrashed):
+				t.Errorf("Trashed = %v, want %d", about.Trashed, *tt.wantTrashed)
+			}
+		})
+	}
+}
+
+func int64Ptr(v int64) *int64 { return &v }
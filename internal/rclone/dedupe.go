@@ -0,0 +1,63 @@
+package rclone
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+)
+
+// dedupeFoundRegex matches the line rclone dedupe logs for each group of duplicates, e.g.
+// "NOTICE: docs/report.pdf: Found 3 files with duplicate names"
+var dedupeFoundRegex = regexp.MustCompile(`Found (\d+) files with duplicate ([a-z0-9]+ hashes|names)`)
+
+// dedupeArgs builds the arguments for a `rclone dedupe` run that only lists duplicates.
+// The list mode never prompts, and --dry-run guards against changes on any rclone version.
+func (c *rcloneClient) dedupeArgs(remote string) []string {
+	args := []string{"dedupe", "--dry-run", "--dedupe-mode", "list", remote}
+	return append(args, c.options.args()...)
+}
+
+// parseDedupeOutput returns the number of redundant copies in rclone dedupe's log output:
+// a group of n files with the same name counts as n-1 duplicates
+func parseDedupeOutput(output []byte) int64 {
+	var duplicates int64
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		match := dedupeFoundRegex.FindSubmatch(scanner.Bytes())
+		if match == nil {
+			continue
+		}
+		// The regex only matches digits, so only overflow can fail
+		if files, err := strconv.ParseInt(string(match[1]), 10, 64); err == nil && files > 1 {
+			duplicates += files - 1
+		}
+	}
+	return duplicates
+}
+
+// GetRemoteDuplicates runs `rclone dedupe --dry-run` and returns the number of duplicate files.
+// dedupe walks the whole remote, so it is as expensive as a size probe.
+func (c *rcloneClient) GetRemoteDuplicates(remote string) (int64, error) {
+	if remote == "" {
+		return 0, fmt.Errorf("remote name cannot be empty")
+	}
+
+	// rclone logs the duplicates it finds to stderr; stdout only lists them
+	result, err := c.run(remote, c.dedupeArgs(remote), c.timeout)
+	if err != nil {
+		return 0, err
+	}
+	duplicates := parseDedupeOutput(result.stderr)
+
+	log.Debug().
+		Str("remote", remote).
+		Int64("duplicates", duplicates).
+		Dur("duration", result.duration).
+		Msg("Rclone dedupe dry run successful")
+
+	return duplicates, nil
+}
@@ -0,0 +1,54 @@
+package rclone
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseDedupeOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   int64
+	}{
+		{"none", "", 0},
+		{
+			"names and hashes",
+			`2024/05/01 10:00:00 NOTICE: docs/report.pdf: Found 3 files with duplicate names
+2024/05/01 10:00:00 NOTICE: photos/a.jpg: Found 2 files with duplicate md5 hashes
+2024/05/01 10:00:01 NOTICE: docs/report.pdf: Not deleting, --dry-run set
+`,
+			3,
+		},
+		{"unrelated noise", "2024/05/01 10:00:00 NOTICE: Google drive root '': Looking for duplicates using interactive mode.\n", 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseDedupeOutput([]byte(tt.output)); got != tt.want {
+			t.Errorf("%s: parseDedupeOutput() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDedupeArgsNeverPrompt(t *testing.T) {
+	c := &rcloneClient{}
+	args := c.dedupeArgs("gdrive:")
+
+	if !slices.Contains(args, "--dry-run") || !containsSequence(args, []string{"--dedupe-mode", "list"}) {
+		t.Errorf("dedupeArgs() = %v, want a non-interactive dry run", args)
+	}
+}
+
+func TestGetRemoteDuplicates(t *testing.T) {
+	path := fakeBinary(t, `echo "2024/05/01 10:00:00 NOTICE: a.txt: Found 4 files with duplicate names" >&2`)
+	c := NewRcloneClientWithConfig(path, 5*time.Second)
+
+	duplicates, err := c.GetRemoteDuplicates("gdrive:")
+	if err != nil {
+		t.Fatalf("GetRemoteDuplicates() error = %v", err)
+	}
+	if duplicates != 3 {
+		t.Errorf("GetRemoteDuplicates() = %d, want 3", duplicates)
+	}
+}
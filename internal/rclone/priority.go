@@ -0,0 +1,125 @@
+package rclone
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// I/O scheduling classes accepted by ParseIOPriority, matching ionice(1)
+const (
+	IOClassNone       = 0 // Leave the I/O priority unchanged
+	IOClassRealtime   = 1
+	IOClassBestEffort = 2
+	IOClassIdle       = 3
+)
+
+// Niceness bounds accepted by setpriority(2)
+const (
+	MinNice = -20
+	MaxNice = 19
+)
+
+// IOPriority is an I/O scheduling class with its level (0 is highest, 7 lowest).
+// The zero value leaves the I/O priority unchanged.
+type IOPriority struct {
+	Class int
+	Level int
+}
+
+// ParseIOPriority parses an ionice-style priority: empty, "idle", or
+// "best-effort:N" and "realtime:N" with a level from 0 to 7
+func ParseIOPriority(value string) (IOPriority, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return IOPriority{}, nil
+	}
+
+	name, levelStr, hasLevel := strings.Cut(value, ":")
+	var class int
+	switch name {
+	case "idle":
+		if hasLevel {
+			return IOPriority{}, fmt.Errorf("the idle class takes no level, got %q", value)
+		}
+		return IOPriority{Class: IOClassIdle}, nil
+	case "best-effort":
+		class = IOClassBestEffort
+	case "realtime":
+		class = IOClassRealtime
+	default:
+		return IOPriority{}, fmt.Errorf("unknown I/O class %q (want idle, best-effort:N or realtime:N)", name)
+	}
+
+	if !hasLevel {
+		return IOPriority{}, fmt.Errorf("the %s class needs a level from 0 to 7, e.g. %s:7", name, name)
+	}
+	level, err := strconv.Atoi(levelStr)
+	if err != nil || level < 0 || level > 7 {
+		return IOPriority{}, fmt.Errorf("I/O priority level must be from 0 to 7, got %q", levelStr)
+	}
+
+	return IOPriority{Class: class, Level: level}, nil
+}
+
+// validateNice checks the niceness range
+func validateNice(nice int) error {
+	if nice < MinNice || nice > MaxNice {
+		return fmt.Errorf("niceness must be from %d to %d, got %d", MinNice, MaxNice, nice)
+	}
+	return nil
+}
+
+// ValidatePriority reports whether the niceness and I/O priority are valid and
+// supported on this platform
+func ValidatePriority(nice int, io IOPriority) error {
+	if err := validateNice(nice); err != nil {
+		return err
+	}
+	if nice != 0 && !niceSupported {
+		return fmt.Errorf("setting the niceness of rclone is not supported on this platform")
+	}
+	if io.Class != IOClassNone && !ioPrioritySupported {
+		return fmt.Errorf("setting the I/O priority of rclone is only supported on Linux")
+	}
+
+	for _, tool := range priorityTools(nice, io) {
+		if _, err := exec.LookPath(tool); err != nil {
+			return fmt.Errorf("%s is needed to run rclone at a lower priority: %w", tool, err)
+		}
+	}
+	return nil
+}
+
+// priorityTools returns the commands that wrap rclone to apply the priorities
+func priorityTools(nice int, io IOPriority) []string {
+	var tools []string
+	if nice != 0 {
+		tools = append(tools, "nice")
+	}
+	if io.Class != IOClassNone {
+		tools = append(tools, "ionice")
+	}
+	return tools
+}
+
+// priorityCommand returns the argv running binary with args at the configured
+// priorities. rclone is wrapped in nice(1) and ionice(1), which exec it with the
+// priority already set, so every thread it starts inherits the priority. Setting it
+// on the running process would only change its main thread.
+func (o Options) priorityCommand(binary string, args []string) []string {
+	var argv []string
+	if o.Nice != 0 {
+		argv = append(argv, "nice", "-n", strconv.Itoa(o.Nice))
+	}
+	if io := o.IOPriority; io.Class != IOClassNone {
+		// -t runs rclone even when the class needs privileges the exporter lacks
+		argv = append(argv, "ionice", "-t", "-c", strconv.Itoa(io.Class))
+		if io.Class != IOClassIdle {
+			argv = append(argv, "-n", strconv.Itoa(io.Level))
+		}
+	}
+	argv = append(argv, binary)
+	return append(argv, args...)
+}
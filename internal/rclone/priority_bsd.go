@@ -0,0 +1,9 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package rclone
+
+// I/O priorities are rejected by ValidatePriority on these platforms, which have no ionice
+const (
+	niceSupported       = true
+	ioPrioritySupported = false
+)
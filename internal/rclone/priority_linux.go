@@ -0,0 +1,8 @@
+//go:build linux
+
+package rclone
+
+const (
+	niceSupported       = true
+	ioPrioritySupported = true
+)
@@ -0,0 +1,41 @@
+//go:build linux
+
+package rclone
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunAppliesPriority(t *testing.T) {
+	// The priority is set before rclone runs, so it can be read back right away
+	script := `awk '{print $19}' /proc/$$/stat`
+	opts := Options{Nice: 10}
+	if _, err := exec.LookPath("ionice"); err == nil {
+		script += `; ionice -p $$`
+		opts.IOPriority = IOPriority{Class: IOClassIdle}
+	}
+	path := fakeBinary(t, script)
+	c := NewRcloneClientWithOptions(path, 5*time.Second, opts).(*rcloneClient)
+
+	result, err := c.run("", []string{"version"}, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(result.stdout)), "\n")
+	if lines[0] != "10" {
+		t.Errorf("niceness = %q, want 10", lines[0])
+	}
+	if len(lines) > 1 && lines[1] != "idle" {
+		t.Errorf("I/O priority = %q, want idle", lines[1])
+	}
+}
+
+func TestValidatePrioritySupportedOnLinux(t *testing.T) {
+	if err := ValidatePriority(5, IOPriority{Class: IOClassBestEffort, Level: 7}); err != nil {
+		t.Errorf("ValidatePriority() error = %v, want nil", err)
+	}
+}
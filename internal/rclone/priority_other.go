@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package rclone
+
+// Process priorities are not supported, so rclone is never wrapped
+const (
+	niceSupported       = false
+	ioPrioritySupported = false
+)
@@ -0,0 +1,77 @@
+package rclone
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestParseIOPriority(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    IOPriority
+		wantErr bool
+	}{
+		{"", IOPriority{}, false},
+		{"idle", IOPriority{Class: IOClassIdle}, false},
+		{"best-effort:7", IOPriority{Class: IOClassBestEffort, Level: 7}, false},
+		{"realtime:0", IOPriority{Class: IOClassRealtime}, false},
+		{"idle:3", IOPriority{}, true},
+		{"best-effort", IOPriority{}, true},
+		{"best-effort:8", IOPriority{}, true},
+		{"best-effort:-1", IOPriority{}, true},
+		{"best-effort:high", IOPriority{}, true},
+		{"low", IOPriority{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseIOPriority(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseIOPriority(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseIOPriority(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestValidatePriorityRange(t *testing.T) {
+	for _, nice := range []int{MinNice - 1, MaxNice + 1} {
+		if err := ValidatePriority(nice, IOPriority{}); err == nil {
+			t.Errorf("ValidatePriority(%d) error = nil, want error", nice)
+		}
+	}
+	if err := ValidatePriority(0, IOPriority{}); err != nil {
+		t.Errorf("ValidatePriority(0) error = %v, want nil", err)
+	}
+}
+
+func TestCommandWrapsPriority(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{"default priority", Options{}, []string{"/usr/bin/rclone", "size", "remote:"}},
+		{"nice", Options{Nice: 10}, []string{"nice", "-n", "10", "/usr/bin/rclone", "size", "remote:"}},
+		{
+			"idle I/O",
+			Options{IOPriority: IOPriority{Class: IOClassIdle}},
+			[]string{"ionice", "-t", "-c", "3", "/usr/bin/rclone", "size", "remote:"},
+		},
+		{
+			"nice and best-effort I/O",
+			Options{Nice: 5, IOPriority: IOPriority{Class: IOClassBestEffort, Level: 7}},
+			[]string{"nice", "-n", "5", "ionice", "-t", "-c", "2", "-n", "7", "/usr/bin/rclone", "size", "remote:"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &rcloneClient{binaryPath: "/usr/bin/rclone", options: tt.opts}
+			// The wrappers exec rclone with the priority set, before any thread starts
+			if got := c.command(context.Background(), "remote:", []string{"size", "remote:"}).Args; !slices.Equal(got, tt.want) {
+				t.Errorf("command args = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package rclone
+
+import (
+	"regexp"
+	"strings"
+)
+
+const redactedValue = "***"
+
+// sensitiveFlagRegex matches rclone flags whose values must never be logged,
+// e.g. --sftp-pass, --crypt-password2, --sftp-key-pem, --s3-secret-access-key or --drive-token
+var sensitiveFlagRegex = regexp.MustCompile(`^--([a-zA-Z0-9_]+-)*(pass(word)?\d*|key(-pem)?|secret|token)$`)
+
+// sensitiveOptionRegex matches rclone config option names holding credentials, e.g. pass,
+// password2, key, secret_access_key, session_token, client_secret, service_account_credentials,
+// sas_url, connection_string, key_pem, api_key or sse_customer_key. Identifiers such as
+// access_key_id are masked too; a false positive only hides a harmless value.
+var sensitiveOptionRegex = regexp.MustCompile(`(^|_)(pass(word|phrase)?\d*|secret|token|key|pem|credentials|sas_url|connection_string|api_?key|auth|cookie|otp)(_|$)`)
+
+// redactConfig returns a copy of a config dump with sensitive option values masked.
+// The remote type is always kept.
+func redactConfig(configs map[string]map[string]interface{}) map[string]map[string]interface{} {
+	redacted := make(map[string]map[string]interface{}, len(configs))
+	for name, options := range configs {
+		remote := make(map[string]interface{}, len(options))
+		for key, value := range options {
+			if key != "type" && sensitiveOptionRegex.MatchString(strings.ToLower(key)) {
+				value = redactedValue
+			}
+			remote[key] = value
+		}
+		redacted[name] = remote
+	}
+	return redacted
+}
+
+// redactArgs returns a copy of args with the values of sensitive flags masked.
+// Both "--flag value" and "--flag=value" forms are handled.
+func redactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if name, _, hasValue := strings.Cut(arg, "="); hasValue && sensitiveFlagRegex.MatchString(name) {
+			redacted[i] = name + "=" + redactedValue
+			continue
+		}
+
+		redacted[i] = arg
+		if sensitiveFlagRegex.MatchString(arg) && i+1 < len(args) {
+			i++
+			redacted[i] = redactedValue
+		}
+	}
+
+	return redacted
+}
+
+// redactedCommand renders a command line with sensitive flag values masked
+func redactedCommand(binaryPath string, args []string) string {
+	return strings.Join(append([]string{binaryPath}, redactArgs(args)...), " ")
+}
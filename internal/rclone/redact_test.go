@@ -0,0 +1,110 @@
+package rclone
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRedactArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "no sensitive flags",
+			args: []string{"size", "remote:", "--json", "--max-depth", "3", "--user-agent", "exporter"},
+			want: []string{"size", "remote:", "--json", "--max-depth", "3", "--user-agent", "exporter"},
+		},
+		{
+			name: "pass with separate value",
+			args: []string{"size", "remote:", "--sftp-pass", "hunter2"},
+			want: []string{"size", "remote:", "--sftp-pass", redactedValue},
+		},
+		{
+			name: "pass with inline value",
+			args: []string{"--sftp-pass=hunter2"},
+			want: []string{"--sftp-pass=" + redactedValue},
+		},
+		{
+			name: "crypt password",
+			args: []string{"--crypt-password", "one", "--crypt-password2=two"},
+			want: []string{"--crypt-password", redactedValue, "--crypt-password2=" + redactedValue},
+		},
+		{
+			name: "key and key pem",
+			args: []string{"--s3-secret-access-key", "abc", "--sftp-key-pem=-----BEGIN"},
+			want: []string{"--s3-secret-access-key", redactedValue, "--sftp-key-pem=" + redactedValue},
+		},
+		{
+			name: "secret and token",
+			args: []string{"--drive-client-secret", "s", "--drive-token={\"access\":1}"},
+			want: []string{"--drive-client-secret", redactedValue, "--drive-token=" + redactedValue},
+		},
+		{
+			name: "sensitive flag without value",
+			args: []string{"size", "--sftp-pass"},
+			want: []string{"size", "--sftp-pass"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactArgs(tt.args); !slices.Equal(got, tt.want) {
+				t.Errorf("redactArgs(%v) = %v, want %v", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactArgsDoesNotModifyInput(t *testing.T) {
+	args := []string{"--sftp-pass", "hunter2"}
+	redactArgs(args)
+	if args[1] != "hunter2" {
+		t.Errorf("redactArgs modified its input: %v", args)
+	}
+}
+
+func TestRedactedCommand(t *testing.T) {
+	got := redactedCommand("rclone", []string{"size", "remote:", "--sftp-pass", "hunter2"})
+	want := "rclone size remote: --sftp-pass ***"
+	if got != want {
+		t.Errorf("redactedCommand() = %q, want %q", got, want)
+	}
+}
+
+func TestRedactConfig(t *testing.T) {
+	secret := []string{
+		"pass", "password", "password2", "key", "secret_access_key", "access_key_id",
+		"session_token", "token", "client_secret", "service_account_credentials",
+		"sas_url", "connection_string", "key_pem", "key_file_pass", "api_key", "apikey",
+		"sse_customer_key", "sse_customer_key_base64", "bearer_token", "passphrase", "Password",
+	}
+	public := []string{
+		"type", "provider", "region", "endpoint", "user", "host", "port", "account",
+		"client_id", "chunk_size", "root_folder_id", "remote", "disable_http2", "keep_alive",
+	}
+
+	options := make(map[string]interface{})
+	for _, key := range append(secret, public...) {
+		options[key] = "value-of-" + key
+	}
+	original := map[string]map[string]interface{}{"remote": options}
+
+	redacted := redactConfig(original)["remote"]
+	for _, key := range secret {
+		if redacted[key] != redactedValue {
+			t.Errorf("option %q = %v, want it redacted", key, redacted[key])
+		}
+	}
+	for _, key := range public {
+		if redacted[key] != "value-of-"+key {
+			t.Errorf("option %q = %v, want it kept", key, redacted[key])
+		}
+	}
+
+	// The input is left untouched
+	if original["remote"]["pass"] != "value-of-pass" {
+		t.Error("redactConfig modified its input")
+	}
+}
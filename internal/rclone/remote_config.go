@@ -0,0 +1,35 @@
+package rclone
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GetRedactedConfig returns every remote from `rclone config dump` with the values
+// of secret-looking options masked
+func (c *rcloneClient) GetRedactedConfig() (map[string]map[string]interface{}, error) {
+	configs, err := c.configDump("")
+	if err != nil {
+		return nil, err
+	}
+	return redactConfig(configs), nil
+}
+
+// GetRemoteOption returns a single option of a remote from `rclone config dump`.
+// Missing or non-string options return an empty string.
+func (c *rcloneClient) GetRemoteOption(remoteName, key string) (string, error) {
+	remoteName = strings.TrimSuffix(remoteName, ":")
+
+	configs, err := c.configDump(remoteName)
+	if err != nil {
+		return "", err
+	}
+
+	remoteConfig, exists := configs[remoteName]
+	if !exists {
+		return "", fmt.Errorf("remote '%s' not found in config", remoteName)
+	}
+
+	value, _ := remoteConfig[key].(string)
+	return value, nil
+}
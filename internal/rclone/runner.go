@@ -0,0 +1,231 @@
+package rclone
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// CommandError describes a failed rclone invocation
+type CommandError struct {
+	Operation string        // rclone subcommand, e.g. "size"
+	Remote    string        // Remote the command ran against, empty for global commands
+	Command   string        // Redacted command line
+	ExitCode  int           // Process exit code, -1 if it did not exit normally
+	Stderr    string        // Trimmed stderr output
+	TimedOut  bool          // Whether the command was killed by its timeout
+	Timeout   time.Duration // Timeout the command ran with
+	Err       error         // Underlying exec error
+}
+
+// Error implements the error interface
+func (e *CommandError) Error() string {
+	target := ""
+	if e.Remote != "" {
+		target = fmt.Sprintf(" for remote '%s'", e.Remote)
+	}
+
+	switch {
+	case e.TimedOut:
+		return fmt.Sprintf("rclone command timed out after %v%s (command: %s)", e.Timeout, target, e.Command)
+	case e.ExitCode >= 0:
+		return fmt.Sprintf("rclone command failed%s (exit code %d, command: %s): %s",
+			target, e.ExitCode, e.Command, e.Stderr)
+	default:
+		return fmt.Sprintf("failed to run rclone%s (command: %s): %v", target, e.Command, e.Err)
+	}
+}
+
+// Unwrap returns the underlying exec error
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// ErrEmptyOutput is returned when rclone succeeds without printing the expected JSON
+var ErrEmptyOutput = errors.New("rclone returned empty output")
+
+// activeSubprocesses counts the rclone processes started and not yet reaped
+var activeSubprocesses atomic.Int64
+
+// ActiveSubprocesses returns the number of rclone processes currently running
+func ActiveSubprocesses() int64 {
+	return activeSubprocesses.Load()
+}
+
+// countLines returns the number of non-empty lines in output
+func countLines(output []byte) int {
+	count := 0
+	for _, line := range bytes.Split(output, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// commandResult holds the captured output of a successful rclone invocation
+type commandResult struct {
+	stdout      []byte // Empty when stdout was streamed via runTo
+	stderr      []byte
+	duration    time.Duration
+	commandLine string
+}
+
+// run executes rclone with args against remote within timeout. Stdout and stderr are
+// captured separately and failures are classified consistently as a *CommandError.
+func (c *rcloneClient) run(remote string, args []string, timeout time.Duration) (*commandResult, error) {
+	return c.runContext(context.Background(), remote, args, timeout)
+}
+
+// runContext is like run but also stops the command when ctx is done
+func (c *rcloneClient) runContext(ctx context.Context, remote string, args []string, timeout time.Duration) (*commandResult, error) {
+	var stdout bytes.Buffer
+	result, err := c.runToContext(ctx, remote, args, timeout, &stdout)
+	if err != nil {
+		return nil, err
+	}
+	result.stdout = stdout.Bytes()
+	return result, nil
+}
+
+// runJSON is like run but decodes stdout as JSON into v. Empty or malformed output is an error.
+func (c *rcloneClient) runJSON(remote string, args []string, timeout time.Duration, v interface{}) (*commandResult, error) {
+	result, err := c.run(remote, args, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bytes.TrimSpace(result.stdout)) == 0 {
+		log.Error().
+			Str("remote", remote).
+			Dur("duration", result.duration).
+			Msg("Rclone returned empty output")
+		return nil, fmt.Errorf("%w for remote '%s' (command: %s)", ErrEmptyOutput, remote, result.commandLine)
+	}
+
+	if err := json.Unmarshal(result.stdout, v); err != nil {
+		log.Error().
+			Err(err).
+			Str("remote", remote).
+			Str("raw_output", string(result.stdout)).
+			Dur("duration", result.duration).
+			Msg("Failed to parse rclone JSON output")
+		return nil, fmt.Errorf("invalid rclone JSON output for remote '%s' (command: %s): %w", remote, result.commandLine, err)
+	}
+
+	return result, nil
+}
+
+// command builds the rclone process for args against remote, wrapped to run at the
+// configured priorities and with the remote's environment
+func (c *rcloneClient) command(ctx context.Context, remote string, args []string) *exec.Cmd {
+	argv := c.options.priorityCommand(c.binaryPath, args)
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	if env := c.options.env(remote); env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	return cmd
+}
+
+// runTo is like run but streams stdout to the given writer instead of buffering it.
+func (c *rcloneClient) runTo(remote string, args []string, timeout time.Duration, stdout io.Writer) (*commandResult, error) {
+	return c.runToContext(context.Background(), remote, args, timeout, stdout)
+}
+
+// runToContext is like runTo but also stops the command when parent is done.
+// A cancelled command is not a failure of rclone, so it returns parent's error unlogged.
+func (c *rcloneClient) runToContext(parent context.Context, remote string, args []string, timeout time.Duration, stdout io.Writer) (*commandResult, error) {
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	operation := ""
+	if len(args) > 0 {
+		operation = args[0]
+	}
+
+	commandLine := redactedCommand(c.binaryPath, args)
+	cmd := c.command(ctx, remote, args)
+
+	var stderr bytes.Buffer
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+
+	log.Debug().
+		Str("remote", remote).
+		Str("command", commandLine).
+		Strs("remote_env", c.options.remoteEnvKeys(remote)).
+		Dur("timeout", timeout).
+		Msgf("Executing rclone %s command", operation)
+
+	startTime := time.Now()
+	activeSubprocesses.Add(1)
+	err := cmd.Run()
+	activeSubprocesses.Add(-1)
+	duration := time.Since(startTime)
+
+	if err != nil && parent.Err() != nil {
+		log.Debug().
+			Str("remote", remote).
+			Str("operation", operation).
+			Dur("duration", duration).
+			Msgf("Rclone %s command cancelled", operation)
+		return nil, fmt.Errorf("rclone %s command cancelled: %w", operation, parent.Err())
+	}
+
+	if err != nil {
+		cmdErr := &CommandError{
+			Operation: operation,
+			Remote:    remote,
+			Command:   commandLine,
+			ExitCode:  -1,
+			Stderr:    strings.TrimSpace(stderr.String()),
+			Timeout:   timeout,
+			Err:       err,
+		}
+
+		var exitErr *exec.ExitError
+		switch {
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			cmdErr.TimedOut = true
+		case errors.As(err, &exitErr):
+			cmdErr.ExitCode = exitErr.ExitCode()
+		}
+
+		log.Error().
+			Err(err).
+			Str("remote", remote).
+			Str("operation", operation).
+			Int("exit_code", cmdErr.ExitCode).
+			Bool("timed_out", cmdErr.TimedOut).
+			Str("stderr", cmdErr.Stderr).
+			Dur("duration", duration).
+			Msgf("Rclone %s command failed", operation)
+
+		return nil, cmdErr
+	}
+
+	// Warnings on stderr never reach the JSON parser, but are kept for debugging
+	if output := strings.TrimSpace(stderr.String()); output != "" {
+		log.Debug().
+			Str("remote", remote).
+			Str("operation", operation).
+			Str("stderr", output).
+			Msgf("Rclone %s command succeeded with stderr output", operation)
+	}
+
+	return &commandResult{
+		stderr:      stderr.Bytes(),
+		duration:    duration,
+		commandLine: commandLine,
+	}, nil
+}
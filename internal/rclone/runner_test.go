@@ -0,0 +1,538 @@
+package rclone
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// fakeBinary writes a shell script standing in for rclone and returns its path
+func fakeBinary(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rclone")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+		t.Fatalf("writing fake rclone: %v", err)
+	}
+	return path
+}
+
+func TestRunJSONErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		script     string
+		timeout    time.Duration
+		wantExit   int
+		wantStderr string
+		timedOut   bool
+		wantMsg    string
+	}{
+		{
+			name:       "non-zero exit",
+			script:     `echo "directory not found" >&2; exit 3`,
+			wantExit:   3,
+			wantStderr: "directory not found",
+		},
+		{
+			name:     "timeout",
+			script:   `exec sleep 5`,
+			timeout:  50 * time.Millisecond,
+			wantExit: -1,
+			timedOut: true,
+		},
+		{
+			name:    "empty output",
+			script:  `exit 0`,
+			wantMsg: "empty output",
+		},
+		{
+			name:    "invalid JSON",
+			script:  `echo "not json"`,
+			wantMsg: "invalid rclone JSON output",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeout := tt.timeout
+			if timeout == 0 {
+				timeout = 5 * time.Second
+			}
+			c := &rcloneClient{binaryPath: fakeBinary(t, tt.script)}
+
+			var v map[string]interface{}
+			_, err := c.runJSON("remote:", []string{"size", "remote:"}, timeout, &v)
+			if err == nil {
+				t.Fatal("runJSON() error = nil, want error")
+			}
+
+			if tt.wantMsg != "" {
+				if !strings.Contains(err.Error(), tt.wantMsg) {
+					t.Errorf("runJSON() error = %q, want it to contain %q", err, tt.wantMsg)
+				}
+				return
+			}
+
+			var cmdErr *CommandError
+			if !errors.As(err, &cmdErr) {
+				t.Fatalf("runJSON() error = %T, want *CommandError", err)
+			}
+			if cmdErr.ExitCode != tt.wantExit {
+				t.Errorf("ExitCode = %d, want %d", cmdErr.ExitCode, tt.wantExit)
+			}
+			if cmdErr.TimedOut != tt.timedOut {
+				t.Errorf("TimedOut = %v, want %v", cmdErr.TimedOut, tt.timedOut)
+			}
+			if cmdErr.Stderr != tt.wantStderr {
+				t.Errorf("Stderr = %q, want %q", cmdErr.Stderr, tt.wantStderr)
+			}
+			if cmdErr.Operation != "size" {
+				t.Errorf("Operation = %q, want %q", cmdErr.Operation, "size")
+			}
+		})
+	}
+}
+
+func TestRunJSONMissingBinary(t *testing.T) {
+	c := &rcloneClient{binaryPath: filepath.Join(t.TempDir(), "missing")}
+
+	var v map[string]interface{}
+	_, err := c.runJSON("remote:", []string{"about", "remote:"}, time.Second, &v)
+
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("runJSON() error = %v, want *CommandError", err)
+	}
+	if cmdErr.ExitCode != -1 || cmdErr.TimedOut {
+		t.Errorf("got ExitCode=%d TimedOut=%v, want -1 and false", cmdErr.ExitCode, cmdErr.TimedOut)
+	}
+}
+
+func TestRunJSONDecodesStdoutOnly(t *testing.T) {
+	c := &rcloneClient{binaryPath: fakeBinary(t, `echo "NOTICE: something" >&2; echo '{"count":2,"bytes":5}'`)}
+
+	var out RcloneSizeOutput
+	if _, err := c.runJSON("remote:", []string{"size", "remote:"}, 5*time.Second, &out); err != nil {
+		t.Fatalf("runJSON() error = %v", err)
+	}
+	if out.Count != 2 || out.Bytes != 5 {
+		t.Errorf("runJSON() decoded %+v, want count 2 and bytes 5", out)
+	}
+}
+
+func TestCommandsClassifyFailuresConsistently(t *testing.T) {
+	path := fakeBinary(t, `echo "Failed to load config" >&2; exit 2`)
+
+	tests := []struct {
+		name      string
+		operation string
+		call      func(c *rcloneClient) error
+	}{
+		{"size", "size", func(c *rcloneClient) error { _, err := c.GetRemoteSize("remote:"); return err }},
+		{"about", "about", func(c *rcloneClient) error { _, err := c.GetRemoteAbout("remote:"); return err }},
+		{"lsd", "lsd", func(c *rcloneClient) error { return c.CheckReachable("remote:") }},
+		{"config dump", "config", func(c *rcloneClient) error { _, err := c.GetRemoteType("remote:"); return err }},
+		{"listremotes", "listremotes", func(c *rcloneClient) error { _, err := c.ListRemotes(); return err }},
+		{"version", "version", func(c *rcloneClient) error { _, err := c.GetVersion(); return err }},
+		{"binary check", "version", func(c *rcloneClient) error { return c.CheckBinaryAvailable(context.Background()) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewRcloneClientWithConfig(path, 5*time.Second).(*rcloneClient)
+
+			err := tt.call(c)
+			var cmdErr *CommandError
+			if !errors.As(err, &cmdErr) {
+				t.Fatalf("error = %v, want a wrapped *CommandError", err)
+			}
+			if cmdErr.Operation != tt.operation {
+				t.Errorf("Operation = %q, want %q", cmdErr.Operation, tt.operation)
+			}
+			if cmdErr.ExitCode != 2 || cmdErr.TimedOut {
+				t.Errorf("got ExitCode=%d TimedOut=%v, want 2 and false", cmdErr.ExitCode, cmdErr.TimedOut)
+			}
+			if cmdErr.Stderr != "Failed to load config" {
+				t.Errorf("Stderr = %q, want %q", cmdErr.Stderr, "Failed to load config")
+			}
+		})
+	}
+}
+
+func TestCheckBinaryAvailableCancelled(t *testing.T) {
+	c := NewRcloneClientWithConfig(fakeBinary(t, "exec sleep 30"), time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	begin := time.Now()
+	err := c.CheckBinaryAvailable(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("CheckBinaryAvailable() error = %v, want context.Canceled", err)
+	}
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) {
+		t.Errorf("error = %v, want a cancellation rather than a rclone failure", err)
+	}
+	if elapsed := time.Since(begin); elapsed > 5*time.Second {
+		t.Errorf("CheckBinaryAvailable() returned after %v, want it to stop on cancellation", elapsed)
+	}
+}
+
+func TestProbeTimeoutShortensRun(t *testing.T) {
+	c := NewRcloneClientWithConfig(fakeBinary(t, "exec sleep 30"), time.Minute)
+
+	begin := time.Now()
+	_, err := c.GetRemoteSizeWithOptions("remote:", ProbeOptions{Timeout: 100 * time.Millisecond})
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) || !cmdErr.TimedOut {
+		t.Fatalf("GetRemoteSizeWithOptions() error = %v, want a timeout", err)
+	}
+	if cmdErr.Timeout != 100*time.Millisecond {
+		t.Errorf("Timeout = %s, want the probe timeout of 100ms", cmdErr.Timeout)
+	}
+	if elapsed := time.Since(begin); elapsed > 5*time.Second {
+		t.Errorf("GetRemoteSizeWithOptions() returned after %v, want it to stop at the probe timeout", elapsed)
+	}
+}
+
+func TestProbeTimeoutNeverExtendsClientTimeout(t *testing.T) {
+	c := &rcloneClient{timeout: time.Second}
+
+	for _, tt := range []struct {
+		probe, want time.Duration
+	}{
+		{0, time.Second},
+		{500 * time.Millisecond, 500 * time.Millisecond},
+		{time.Minute, time.Second},
+	} {
+		if got := c.probeTimeout(ProbeOptions{Timeout: tt.probe}); got != tt.want {
+			t.Errorf("probeTimeout(%s) = %s, want %s", tt.probe, got, tt.want)
+		}
+	}
+}
+
+func TestTypeDetectionObserved(t *testing.T) {
+	path := fakeBinary(t, `sleep 0.1; echo '{"remote":{"type":"s3"}}'`)
+
+	var durations []time.Duration
+	c := NewRcloneClientWithOptions(path, 5*time.Second, Options{
+		ObserveTypeDetection: func(d time.Duration) { durations = append(durations, d) },
+	})
+
+	for i := 0; i < 2; i++ {
+		if remoteType, err := c.GetRemoteType("remote:"); err != nil || remoteType != "s3" {
+			t.Fatalf("GetRemoteType() = %q, %v, want s3", remoteType, err)
+		}
+	}
+
+	if len(durations) != 2 {
+		t.Fatalf("observed %d durations, want 2", len(durations))
+	}
+	if durations[0] < 100*time.Millisecond {
+		t.Errorf("config dump observed as %v, want at least 100ms", durations[0])
+	}
+	if durations[1] > 10*time.Millisecond {
+		t.Errorf("cache hit observed as %v, want near zero", durations[1])
+	}
+}
+
+func TestGetRemoteSizeSuccessExitCodes(t *testing.T) {
+	path := fakeBinary(t, `echo "directory not found" >&2; exit 3`)
+
+	c := NewRcloneClientWithOptions(path, 5*time.Second, Options{SuccessExitCodes: []int{3}})
+	size, err := c.GetRemoteSize("remote:path")
+	if err != nil {
+		t.Fatalf("GetRemoteSize() error = %v, want success for exit code 3", err)
+	}
+	if size.Bytes != 0 || size.Count != 0 {
+		t.Errorf("GetRemoteSize() = %+v, want zero size", size)
+	}
+
+	// Other exit codes still fail
+	c = NewRcloneClientWithOptions(path, 5*time.Second, Options{SuccessExitCodes: []int{9}})
+	if _, err := c.GetRemoteSize("remote:path"); err == nil {
+		t.Error("GetRemoteSize() error = nil, want failure for an unlisted exit code")
+	}
+}
+
+func TestCommandErrorMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		err  CommandError
+		want string
+	}{
+		{
+			name: "timeout",
+			err:  CommandError{Remote: "gdrive:", Command: "rclone size gdrive:", ExitCode: -1, TimedOut: true, Timeout: time.Minute},
+			want: "rclone command timed out after 1m0s for remote 'gdrive:' (command: rclone size gdrive:)",
+		},
+		{
+			name: "exit code",
+			err:  CommandError{Remote: "gdrive:", Command: "rclone size gdrive:", ExitCode: 3, Stderr: "not found"},
+			want: "rclone command failed for remote 'gdrive:' (exit code 3, command: rclone size gdrive:): not found",
+		},
+		{
+			name: "global command",
+			err:  CommandError{Command: "rclone version", ExitCode: 1, Stderr: "boom"},
+			want: "rclone command failed (exit code 1, command: rclone version): boom",
+		},
+		{
+			name: "start failure",
+			err:  CommandError{Command: "rclone version", ExitCode: -1, Err: errors.New("no such file")},
+			want: "failed to run rclone (command: rclone version): no such file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Error(); got != tt.want {
+				t.Errorf("Error() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetVersionIsCached(t *testing.T) {
+	calls := filepath.Join(t.TempDir(), "calls")
+	path := fakeBinary(t, `echo x >> "`+calls+`"; echo "rclone v1.66.0"; echo "- os/version: linux"`)
+	c := NewRcloneClientWithConfig(path, 5*time.Second).(*rcloneClient)
+
+	for i := 0; i < 3; i++ {
+		got, err := c.GetVersion()
+		if err != nil {
+			t.Fatalf("GetVersion() error = %v", err)
+		}
+		if got != "rclone v1.66.0" {
+			t.Errorf("GetVersion() = %q, want %q", got, "rclone v1.66.0")
+		}
+	}
+
+	data, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "x"); n != 1 {
+		t.Errorf("rclone version ran %d times, want 1", n)
+	}
+}
+
+func TestPasswordCommandIsExported(t *testing.T) {
+	path := fakeBinary(t, `echo "$RCLONE_PASSWORD_COMMAND"`)
+	c := NewRcloneClientWithOptions(path, 5*time.Second, Options{PasswordCommand: "vault kv get -field=pass secret/rclone"}).(*rcloneClient)
+
+	result, err := c.run("", []string{"version"}, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(result.stdout)); got != "vault kv get -field=pass secret/rclone" {
+		t.Errorf("RCLONE_PASSWORD_COMMAND = %q", got)
+	}
+
+	// The command stays out of the logged command line
+	if strings.Contains(result.commandLine, "vault") {
+		t.Errorf("command line leaks the password command: %s", result.commandLine)
+	}
+}
+
+func TestRemoteEnvIsScopedToRemote(t *testing.T) {
+	var buf bytes.Buffer
+	oldLogger, oldLevel := log.Logger, zerolog.GlobalLevel()
+	defer func() {
+		log.Logger = oldLogger
+		zerolog.SetGlobalLevel(oldLevel)
+	}()
+	zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	log.Logger = zerolog.New(&buf)
+
+	path := fakeBinary(t, `echo "${RCLONE_SFTP_SSH:-unset}"`)
+	c := NewRcloneClientWithOptions(path, 5*time.Second, Options{
+		RemoteEnv: map[string]map[string]string{"bastion": {"RCLONE_SFTP_SSH": "ssh -J jump.example s3cr3t"}},
+	}).(*rcloneClient)
+
+	tests := []struct {
+		remote string
+		want   string
+	}{
+		{"bastion:", "ssh -J jump.example s3cr3t"},
+		{"bastion:backups/daily", "ssh -J jump.example s3cr3t"},
+		{"bastion", "ssh -J jump.example s3cr3t"}, // Config commands pass the bare name
+		{"other:", "unset"},
+		{"", "unset"},
+	}
+	for _, tt := range tests {
+		result, err := c.run(tt.remote, []string{"size"}, 5*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.TrimSpace(string(result.stdout)); got != tt.want {
+			t.Errorf("%q: RCLONE_SFTP_SSH = %q, want %q", tt.remote, got, tt.want)
+		}
+	}
+
+	// Logs name the injected variables but never their values
+	if !strings.Contains(buf.String(), `"remote_env":["RCLONE_SFTP_SSH"]`) {
+		t.Errorf("logs do not name the injected variable:\n%s", buf.String())
+	}
+	if strings.Contains(buf.String(), "s3cr3t") {
+		t.Errorf("logs leak a per-remote env value:\n%s", buf.String())
+	}
+}
+
+func TestActiveSubprocesses(t *testing.T) {
+	release := filepath.Join(t.TempDir(), "release")
+	path := fakeBinary(t, `while [ ! -e "`+release+`" ]; do sleep 0.01; done`)
+	c := NewRcloneClientWithConfig(path, 5*time.Second).(*rcloneClient)
+
+	before := ActiveSubprocesses()
+	done := make(chan error)
+	go func() {
+		_, err := c.run("", []string{"version"}, 5*time.Second)
+		done <- err
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for ActiveSubprocesses() != before+1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("ActiveSubprocesses() = %d while rclone runs, want %d", ActiveSubprocesses(), before+1)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := os.WriteFile(release, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if got := ActiveSubprocesses(); got != before {
+		t.Errorf("ActiveSubprocesses() = %d after rclone exited, want %d", got, before)
+	}
+}
+
+func TestGetRemoteSizeRetriesOnceOnEmptyOutput(t *testing.T) {
+	dir := t.TempDir()
+	calls := filepath.Join(dir, "calls")
+
+	// Empty output on the first call, valid JSON afterwards
+	script := `echo x >> "` + calls + `"
+if [ "$(wc -l < "` + calls + `")" -eq 1 ]; then exit 0; fi
+echo '{"count":3,"bytes":42}'`
+	c := NewRcloneClientWithConfig(fakeBinary(t, script), 5*time.Second)
+
+	before := EmptyOutputRetries()
+	size, err := c.GetRemoteSize("remote:")
+	if err != nil {
+		t.Fatalf("GetRemoteSize() error = %v", err)
+	}
+	if size.Count != 3 || size.Bytes != 42 {
+		t.Errorf("GetRemoteSize() = %+v, want count 3, bytes 42", size)
+	}
+	if got := EmptyOutputRetries() - before; got != 1 {
+		t.Errorf("empty output retries = %d, want 1", got)
+	}
+
+	// Output that stays empty fails after a single retry
+	os.Remove(calls)
+	c = NewRcloneClientWithConfig(fakeBinary(t, `echo x >> "`+calls+`"`), 5*time.Second)
+	if _, err := c.GetRemoteSize("remote:"); !errors.Is(err, ErrEmptyOutput) {
+		t.Errorf("GetRemoteSize() error = %v, want ErrEmptyOutput", err)
+	}
+	data, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "x"); n != 2 {
+		t.Errorf("rclone size ran %d times, want 2", n)
+	}
+}
+
+func TestConfigParseErrorsCounted(t *testing.T) {
+	before := ConfigParseErrors()
+
+	// A failing rclone is a run error, not a parse error
+	failing := NewRcloneClientWithConfig(fakeBinary(t, `exit 1`), 5*time.Second)
+	if _, err := failing.GetRemoteType("remote"); err == nil {
+		t.Fatal("GetRemoteType() succeeded with failing rclone")
+	}
+	if got := ConfigParseErrors() - before; got != 0 {
+		t.Errorf("parse errors after run failure = %d, want 0", got)
+	}
+
+	garbled := NewRcloneClientWithConfig(fakeBinary(t, `echo "not json"`), 5*time.Second)
+	if _, err := garbled.GetRemoteType("remote"); err == nil {
+		t.Fatal("GetRemoteType() succeeded with invalid config dump")
+	}
+	if _, err := garbled.ListRemotes(); err == nil {
+		t.Fatal("ListRemotes() succeeded with invalid output")
+	}
+	if got := ConfigParseErrors() - before; got != 2 {
+		t.Errorf("parse errors = %d, want 2", got)
+	}
+}
+
+func TestGetRemoteSizeCountsStderrWarnings(t *testing.T) {
+	script := `echo "2024/01/01 12:00:00 NOTICE: 3 files skipped" >&2
+echo "" >&2
+echo "2024/01/01 12:00:01 ERROR : dir: permission denied" >&2
+echo '{"count":1,"bytes":2}'`
+	c := NewRcloneClientWithConfig(fakeBinary(t, script), 5*time.Second)
+
+	size, err := c.GetRemoteSize("remote:")
+	if err != nil {
+		t.Fatalf("GetRemoteSize() error = %v", err)
+	}
+	if size.Count != 1 || size.Bytes != 2 || size.Warnings != 2 {
+		t.Errorf("GetRemoteSize() = %+v, want count 1, bytes 2, 2 warnings", size)
+	}
+	if want := int64(len("2024/01/01 12:00:00 NOTICE: 3 files skipped\n\n2024/01/01 12:00:01 ERROR : dir: permission denied\n")); size.StderrBytes != want {
+		t.Errorf("GetRemoteSize() stderr bytes = %d, want %d", size.StderrBytes, want)
+	}
+}
+
+func TestGetRemoteSizeIgnoresStderrNoise(t *testing.T) {
+	// Before stdout and stderr were split, this stderr output was prepended to the
+	// JSON and made the probe fail
+	script := `echo '2024/01/01 12:00:00 NOTICE: Config file "rclone.conf" not found - using defaults {' >&2
+echo '{"count":7,"bytes":1024}'
+echo 'DEPRECATED: --fast-list will change meaning' >&2`
+	c := NewRcloneClientWithConfig(fakeBinary(t, script), 5*time.Second)
+
+	size, err := c.GetRemoteSize("remote:")
+	if err != nil {
+		t.Fatalf("GetRemoteSize() error = %v", err)
+	}
+	if size.Count != 7 || size.Bytes != 1024 {
+		t.Errorf("GetRemoteSize() = %+v, want count 7, bytes 1024", size)
+	}
+}
+
+func TestTypeDetectionFailuresCounted(t *testing.T) {
+	before := TypeDetectionFailures()
+
+	c := NewRcloneClientWithConfig(fakeBinary(t, `echo '{"known":{"type":"s3"},"untyped":{}}'`), 5*time.Second)
+	if _, err := c.GetRemoteType("known:"); err != nil {
+		t.Fatalf("GetRemoteType(known) error = %v", err)
+	}
+	for _, remote := range []string{"missing:", "untyped:"} {
+		if remoteType, err := c.GetRemoteType(remote); err == nil || remoteType != "unknown" {
+			t.Errorf("GetRemoteType(%s) = %q, %v, want unknown with an error", remote, remoteType, err)
+		}
+	}
+
+	failing := NewRcloneClientWithConfig(fakeBinary(t, `exit 1`), 5*time.Second)
+	if _, err := failing.GetRemoteType("remote:"); err == nil {
+		t.Fatal("GetRemoteType() succeeded with failing rclone")
+	}
+
+	if got := TypeDetectionFailures() - before; got != 3 {
+		t.Errorf("type detection failures = %d, want 3", got)
+	}
+}
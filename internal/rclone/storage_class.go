@@ -0,0 +1,79 @@
+package rclone
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+)
+
+// UnknownStorageClass is reported for objects whose backend returned no storage class
+const UnknownStorageClass = "unknown"
+
+// storageClassArgs builds the arguments for a recursive file-only `rclone lsf` listing
+// that prints only the storage class (tier) of each object, e.g. STANDARD or GLACIER
+func (c *rcloneClient) storageClassArgs(remote string, opts ProbeOptions) []string {
+	args := []string{"lsf", remote, "--files-only", "-R", "--format", "T", c.options.traversalFlag(opts)}
+	if opts.MaxDepth > 0 {
+		args = append(args, "--max-depth", strconv.Itoa(opts.MaxDepth))
+	}
+	return append(args, c.options.args()...)
+}
+
+// storageClassCounter is an io.Writer counting the lines of its input by value, so a
+// listing of millions of objects is never buffered
+type storageClassCounter struct {
+	counts  map[string]int64
+	partial []byte // Start of a line split across writes
+}
+
+// Write implements io.Writer
+func (s *storageClassCounter) Write(p []byte) (int, error) {
+	n := len(p)
+	for {
+		line, rest, found := bytes.Cut(p, []byte{'\n'})
+		if !found {
+			s.partial = append(s.partial, line...)
+			return n, nil
+		}
+		s.partial = append(s.partial, line...)
+		s.count(s.partial)
+		s.partial, p = s.partial[:0], rest
+	}
+}
+
+// count records one storage class line
+func (s *storageClassCounter) count(line []byte) {
+	class := string(bytes.TrimSpace(line))
+	if class == "" {
+		class = UnknownStorageClass
+	}
+	s.counts[class]++
+}
+
+// GetRemoteStorageClasses runs `rclone lsf --files-only -R --format T` and returns the
+// number of objects of each storage class
+func (c *rcloneClient) GetRemoteStorageClasses(remote string, opts ProbeOptions) (map[string]int64, error) {
+	if remote == "" {
+		return nil, fmt.Errorf("remote name cannot be empty")
+	}
+
+	counter := storageClassCounter{counts: make(map[string]int64)}
+	result, err := c.runTo(remote, c.storageClassArgs(remote, opts), c.probeTimeout(opts), &counter)
+	if err != nil {
+		return nil, err
+	}
+	// Count a last line without a trailing newline
+	if len(counter.partial) > 0 {
+		counter.count(counter.partial)
+	}
+
+	log.Debug().
+		Str("remote", remote).
+		Int("storage_classes", len(counter.counts)).
+		Dur("duration", result.duration).
+		Msg("Rclone storage class listing successful")
+
+	return counter.counts, nil
+}
@@ -0,0 +1,43 @@
+package rclone
+
+import (
+	"maps"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStorageClassCounterSplitWrites(t *testing.T) {
+	counter := storageClassCounter{counts: make(map[string]int64)}
+	for _, chunk := range []string{"STAND", "ARD\nGLACIER\n", "\nSTANDARD\nDEEP_", "ARCHIVE\n"} {
+		if _, err := counter.Write([]byte(chunk)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	want := map[string]int64{"STANDARD": 2, "GLACIER": 1, "DEEP_ARCHIVE": 1, UnknownStorageClass: 1}
+	if !maps.Equal(counter.counts, want) || len(counter.partial) != 0 {
+		t.Errorf("counts = %v (partial %q), want %v", counter.counts, counter.partial, want)
+	}
+}
+
+func TestGetRemoteStorageClasses(t *testing.T) {
+	script := `case "$*" in
+*"--format T"*) printf 'STANDARD\nGLACIER\nSTANDARD' ;;
+*) echo "unexpected args: $*" >&2; exit 1 ;;
+esac`
+	c := NewRcloneClientWithConfig(fakeBinary(t, script), 5*time.Second)
+
+	classes, err := c.GetRemoteStorageClasses("s3:bucket", ProbeOptions{MaxDepth: 2})
+	if err != nil {
+		t.Fatalf("GetRemoteStorageClasses() error = %v", err)
+	}
+	if want := map[string]int64{"STANDARD": 2, "GLACIER": 1}; !maps.Equal(classes, want) {
+		t.Errorf("GetRemoteStorageClasses() = %v, want %v", classes, want)
+	}
+
+	args := strings.Join(c.(*rcloneClient).storageClassArgs("s3:bucket", ProbeOptions{MaxDepth: 2}), " ")
+	if want := "lsf s3:bucket --files-only -R --format T --fast-list --max-depth 2"; args != want {
+		t.Errorf("storageClassArgs() = %q, want %q", args, want)
+	}
+}
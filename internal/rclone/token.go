@@ -0,0 +1,38 @@
+package rclone
+
+import (
+	"errors"
+	"strings"
+)
+
+// tokenErrorMarkers are lowercase stderr fragments of rclone and the OAuth backends it
+// talks to when a token is expired, revoked or missing. Retrying does not help these;
+// the remote needs `rclone config reconnect`.
+var tokenErrorMarkers = []string{
+	"couldn't fetch token", // lib/oauthutil, usually with "maybe it has expired?"
+	"config reconnect",     // rclone's own hint to reauthorize the remote
+	"empty token found",    // Remote configured without completing the OAuth flow
+	"invalid_grant",        // OAuth2 refresh token expired or revoked
+	"token has been expired or revoked",
+	"token expired and there's no refresh token",
+	"expired_access_token",       // Dropbox
+	"invalid_access_token",       // Dropbox
+	"invalidauthenticationtoken", // OneDrive / Microsoft Graph
+}
+
+// IsTokenError reports whether err is an rclone failure caused by an invalid OAuth
+// token rather than a transient problem
+func IsTokenError(err error) bool {
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) || cmdErr.TimedOut {
+		return false
+	}
+
+	stderr := strings.ToLower(cmdErr.Stderr)
+	for _, marker := range tokenErrorMarkers {
+		if strings.Contains(stderr, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package rclone
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestIsTokenError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			"expired drive token",
+			&CommandError{ExitCode: 1, Stderr: `ERROR : : error listing: couldn't fetch token: invalid_grant: maybe token is expired? - try refreshing with "rclone config reconnect gdrive:"`},
+			true,
+		},
+		{
+			"revoked google token",
+			&CommandError{ExitCode: 1, Stderr: `oauth2: "invalid_grant" "Token has been expired or revoked."`},
+			true,
+		},
+		{"dropbox", &CommandError{ExitCode: 1, Stderr: "error: expired_access_token/"}, true},
+		{"onedrive", &CommandError{ExitCode: 1, Stderr: "InvalidAuthenticationToken: Access token has expired"}, true},
+		{"wrapped", fmt.Errorf("probe failed: %w", &CommandError{ExitCode: 1, Stderr: "empty token found - please run rclone config reconnect"}), true},
+		{"network error", &CommandError{ExitCode: 1, Stderr: "dial tcp: lookup www.googleapis.com: no such host"}, false},
+		{"directory not found", &CommandError{ExitCode: 3, Stderr: "directory not found"}, false},
+		{"timeout", &CommandError{ExitCode: -1, TimedOut: true, Timeout: time.Minute, Stderr: "couldn't fetch token"}, false},
+		{"not a command error", errors.New("invalid_grant"), false},
+		{"nil", nil, false},
+	}
+	for _, tt := range tests {
+		if got := IsTokenError(tt.err); got != tt.want {
+			t.Errorf("%s: IsTokenError() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
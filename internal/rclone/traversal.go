@@ -0,0 +1,71 @@
+package rclone
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Traversal strategies for recursive listings, selected per probe or via Options.Traversal
+const (
+	// TraversalFastList lists with --fast-list: fewer API calls on bucket-based
+	// backends such as S3, at the cost of holding the whole listing in memory (default)
+	TraversalFastList = "fast-list"
+
+	// TraversalNoTraverse lists with --no-traverse instead, walking directory by
+	// directory. It is kinder to memory and to backends whose fast-list is slow or
+	// unsupported, but needs more API calls.
+	TraversalNoTraverse = "no-traverse"
+)
+
+// traversalFlags are the rclone flags chosen by a traversal strategy, which the size
+// passthrough may not set itself
+var traversalFlags = []string{"--" + TraversalFastList, "--" + TraversalNoTraverse}
+
+// ParseTraversal validates a traversal strategy. Empty selects the default.
+func ParseTraversal(value string) (string, error) {
+	switch value = strings.TrimSpace(value); value {
+	case "", TraversalFastList, TraversalNoTraverse:
+		return value, nil
+	default:
+		return "", fmt.Errorf("unknown traversal %q (want %s or %s)", value, TraversalFastList, TraversalNoTraverse)
+	}
+}
+
+// traversalFlag returns the rclone flag for the probe's traversal, falling back to
+// the client default and then to --fast-list
+func (o Options) traversalFlag(opts ProbeOptions) string {
+	traversal := opts.Traversal
+	if traversal == "" {
+		traversal = o.Traversal
+	}
+	if traversal == "" {
+		traversal = TraversalFastList
+	}
+	return "--" + traversal
+}
+
+// ValidateSizeExtraArgs checks the flags passed through to `rclone size`. Each entry
+// is a single --flag or --flag=value, and flags the exporter sets itself are refused.
+func ValidateSizeExtraArgs(args []string) error {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "--") || len(arg) == 2 {
+			return fmt.Errorf("%q is not a long flag, pass flags as --flag or --flag=value", arg)
+		}
+
+		name, _, _ := strings.Cut(arg, "=")
+		switch {
+		case name == "--json":
+			return fmt.Errorf("%s is always set by the exporter", name)
+		case name == "--max-depth" || name == "--min-size":
+			return fmt.Errorf("%s is set per probe, use the depth or minsize probe parameter", name)
+		case name == "--checkers" || name == "--transfers":
+			return fmt.Errorf("%s is set by Options.Checkers and Options.Transfers", name)
+		}
+		for _, flag := range traversalFlags {
+			if name == flag {
+				return fmt.Errorf("%s is chosen by the traversal setting, not the passthrough", name)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package rclone
+
+import (
+	"fmt"
+	"strings"
+)
+
+// unionModifiers are the policy suffixes rclone allows on union upstreams
+var unionModifiers = []string{":ro", ":nc", ":writeback"}
+
+// Upstream is one member of a union or combine remote
+type Upstream struct {
+	Name   string // Label for the upstream: the combine directory, or the union upstream remote itself
+	Remote string // Remote to probe, e.g. "gdrive:backup"
+}
+
+// GetUpstreams returns the upstream remotes of a union or combine remote.
+// Other remote types have no upstreams and return an empty list.
+func (c *rcloneClient) GetUpstreams(remoteName string) ([]Upstream, error) {
+	remoteName = strings.TrimSuffix(remoteName, ":")
+
+	configs, err := c.configDump(remoteName)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteConfig, exists := configs[remoteName]
+	if !exists {
+		return nil, fmt.Errorf("remote '%s' not found in config", remoteName)
+	}
+
+	remoteType, _ := remoteConfig["type"].(string)
+	upstreams, _ := remoteConfig["upstreams"].(string)
+	return parseUpstreams(remoteType, upstreams), nil
+}
+
+// parseUpstreams parses the space separated upstreams option of a union or combine remote.
+// Union entries may carry a policy suffix such as ":ro"; combine entries are dir=remote pairs.
+func parseUpstreams(remoteType, upstreams string) []Upstream {
+	var result []Upstream
+	for _, field := range strings.Fields(upstreams) {
+		switch remoteType {
+		case "union":
+			for _, modifier := range unionModifiers {
+				field = strings.TrimSuffix(field, modifier)
+			}
+			result = append(result, Upstream{Name: field, Remote: field})
+		case "combine":
+			dir, remote, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			result = append(result, Upstream{Name: dir, Remote: remote})
+		}
+	}
+
+	return result
+}
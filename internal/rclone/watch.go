@@ -0,0 +1,90 @@
+package rclone
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog/log"
+)
+
+// configChangeDebounce groups the burst of events a single save produces into one change
+const configChangeDebounce = 250 * time.Millisecond
+
+// ConfigFile runs `rclone config file` and returns the path of the config file in use.
+func (c *rcloneClient) ConfigFile() (string, error) {
+	result, err := c.run("", []string{"config", "file"}, metadataTimeout)
+	if err != nil {
+		return "", fmt.Errorf("failed to locate rclone config file: %w", err)
+	}
+
+	// The path is printed on the last line, after a "Configuration file is stored at:" header
+	lines := strings.Split(strings.TrimSpace(string(result.stdout)), "\n")
+	path := strings.TrimSpace(lines[len(lines)-1])
+	if path == "" {
+		return "", fmt.Errorf("rclone config file returned no path")
+	}
+	return path, nil
+}
+
+// WatchConfigFile calls onChange whenever the file at path is written, replaced or
+// removed, until ctx is done. The parent directory is watched rather than the file
+// itself, so the watch survives editors that save by renaming a new file into place.
+func WatchConfigFile(ctx context.Context, path string, onChange func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create config watcher: %w", err)
+	}
+
+	path = filepath.Clean(path)
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch %s: %w", filepath.Dir(path), err)
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		var debounce *time.Timer
+		defer func() {
+			if debounce != nil {
+				debounce.Stop()
+			}
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != path || event.Op == fsnotify.Chmod {
+					continue
+				}
+
+				log.Debug().
+					Str("file", event.Name).
+					Str("op", event.Op.String()).
+					Msg("Rclone config file event")
+
+				if debounce == nil {
+					debounce = time.AfterFunc(configChangeDebounce, onChange)
+				} else {
+					debounce.Reset(configChangeDebounce)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Warn().Err(err).Str("file", path).Msg("Rclone config watcher error")
+			}
+		}
+	}()
+
+	return nil
+}
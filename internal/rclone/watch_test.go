@@ -0,0 +1,78 @@
+package rclone
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConfigFile(t *testing.T) {
+	path := fakeBinary(t, `echo "Configuration file is stored at:"; echo "/home/user/.config/rclone/rclone.conf"`)
+	c := NewRcloneClientWithConfig(path, 5*time.Second).(*rcloneClient)
+
+	got, err := c.ConfigFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "/home/user/.config/rclone/rclone.conf" {
+		t.Errorf("ConfigFile() = %q", got)
+	}
+}
+
+func TestWatchConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rclone.conf")
+	if err := os.WriteFile(path, []byte("[a]\ntype = local\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan struct{}, 10)
+	if err := WatchConfigFile(ctx, path, func() { changes <- struct{}{} }); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForChange := func(what string) {
+		t.Helper()
+		select {
+		case <-changes:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no change reported after %s", what)
+		}
+	}
+
+	// Unrelated files in the same directory are ignored
+	if err := os.WriteFile(filepath.Join(dir, "other"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte("[b]\ntype = s3\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	waitForChange("an in-place write")
+
+	// Editors often save by renaming a new file over the old one
+	tmp := filepath.Join(dir, "rclone.conf.tmp")
+	if err := os.WriteFile(tmp, []byte("[c]\ntype = drive\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	waitForChange("a rename over the file")
+
+	if err := os.WriteFile(path, []byte("[d]\ntype = local\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	waitForChange("a write after the rename")
+
+	select {
+	case <-changes:
+		t.Error("one save was reported more than once")
+	case <-time.After(2 * configChangeDebounce):
+	}
+}
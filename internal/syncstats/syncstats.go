@@ -0,0 +1,123 @@
+// Package syncstats reads transfer statistics produced by a separately running rclone sync
+package syncstats
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// logTailBytes bounds how much of the end of a stats log file is scanned
+const logTailBytes = 1 << 20
+
+// Stats holds the counters reported by rclone's core/stats
+type Stats struct {
+	Bytes     int64 `json:"bytes"`     // Bytes transferred
+	Errors    int64 `json:"errors"`    // Number of errors
+	Transfers int64 `json:"transfers"` // Number of completed transfers
+	Checks    int64 `json:"checks"`    // Number of completed checks
+}
+
+// Source provides the latest rclone sync statistics
+type Source interface {
+	Stats(ctx context.Context) (*Stats, error)
+}
+
+// RCSource reads statistics from the core/stats call of a running rclone rc server
+type RCSource struct {
+	URL      string // Base URL of the rc server, e.g. http://localhost:5572
+	User     string // Optional basic auth user
+	Password string // Optional basic auth password
+	Client   *http.Client
+}
+
+// Stats implements Source
+func (s *RCSource) Stats(ctx context.Context) (*Stats, error) {
+	url := strings.TrimSuffix(s.URL, "/") + "/core/stats"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader("{}"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rc request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.User != "" {
+		req.SetBasicAuth(s.User, s.Password)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("rc request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("rc returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var stats Stats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, fmt.Errorf("invalid core/stats response: %w", err)
+	}
+	return &stats, nil
+}
+
+// LogFileSource reads the most recent statistics from an rclone stats log written
+// with --stats-log-file and --use-json-log
+type LogFileSource struct {
+	Path string
+}
+
+// logLine is the part of a JSON log line carrying statistics
+type logLine struct {
+	Stats *Stats `json:"stats"`
+}
+
+// Stats implements Source
+func (s *LogFileSource) Stats(_ context.Context) (*Stats, error) {
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stats log: %w", err)
+	}
+	defer f.Close()
+
+	// Only the tail matters, stats lines are appended periodically
+	if info, err := f.Stat(); err == nil && info.Size() > logTailBytes {
+		if _, err := f.Seek(-logTailBytes, io.SeekEnd); err != nil {
+			return nil, fmt.Errorf("failed to seek stats log: %w", err)
+		}
+	}
+
+	var latest *Stats
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), logTailBytes)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+
+		var parsed logLine
+		if err := json.Unmarshal(line, &parsed); err == nil && parsed.Stats != nil {
+			latest = parsed.Stats
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stats log: %w", err)
+	}
+
+	if latest == nil {
+		return nil, fmt.Errorf("no stats found in %s (is rclone running with --use-json-log?)", s.Path)
+	}
+	return latest, nil
+}
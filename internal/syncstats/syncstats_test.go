@@ -0,0 +1,77 @@
+package syncstats
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRCSourceStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/core/stats" {
+			http.Error(w, "unexpected request", http.StatusNotFound)
+			return
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"bytes":2048,"errors":1,"transfers":3,"checks":7,"speed":12.5}`))
+	}))
+	defer server.Close()
+
+	source := &RCSource{URL: server.URL + "/", User: "admin", Password: "secret"}
+	stats, err := source.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+
+	want := Stats{Bytes: 2048, Errors: 1, Transfers: 3, Checks: 7}
+	if *stats != want {
+		t.Errorf("Stats() = %+v, want %+v", *stats, want)
+	}
+
+	source.Password = "wrong"
+	if _, err := source.Stats(context.Background()); err == nil {
+		t.Error("Stats() with wrong password error = nil, want error")
+	}
+}
+
+func TestLogFileSourceUsesLatestStats(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.log")
+	log := `{"level":"info","msg":"starting","time":"2024-01-01T00:00:00Z"}
+not json at all
+{"level":"info","msg":"stats","stats":{"bytes":10,"errors":0,"transfers":1,"checks":2}}
+{"level":"info","msg":"stats","stats":{"bytes":20,"errors":1,"transfers":2,"checks":4}}
+`
+	if err := os.WriteFile(path, []byte(log), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := (&LogFileSource{Path: path}).Stats(context.Background())
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+
+	want := Stats{Bytes: 20, Errors: 1, Transfers: 2, Checks: 4}
+	if *stats != want {
+		t.Errorf("Stats() = %+v, want %+v", *stats, want)
+	}
+}
+
+func TestLogFileSourceWithoutStats(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.log")
+	if err := os.WriteFile(path, []byte("plain text log\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := (&LogFileSource{Path: path}).Stats(context.Background()); err == nil {
+		t.Error("Stats() error = nil, want error")
+	}
+	if _, err := (&LogFileSource{Path: path + ".missing"}).Stats(context.Background()); err == nil {
+		t.Error("Stats() on missing file error = nil, want error")
+	}
+}